@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var accountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "Manage accounts (checking, credit cards, cash, ...)",
+}
+
+var accountCreateCmd = &cobra.Command{
+	Use:     "create [name]",
+	Short:   "Create a new account",
+	Example: "finance account create \"Visa Gold\" --type credit",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		accType, _ := cmd.Flags().GetString("type")
+		currency, _ := cmd.Flags().GetString("currency")
+
+		a := &models.Account{Name: args[0], Type: strings.ToLower(accType), Currency: currency}
+		if err := models.CreateAccount(database, a); err != nil {
+			return fmt.Errorf("failed to create account: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Account '%s' (%s) created (ID: %d)\n", a.Name, a.Type, a.ID)
+		return nil
+	},
+}
+
+var accountListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List accounts with their current balances",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+
+		conv, err := newConverter()
+		if err != nil {
+			return err
+		}
+
+		balances, err := models.GetAccountBalances(database, all, conv)
+		if err != nil {
+			return fmt.Errorf("failed to list accounts: %w", err)
+		}
+
+		if len(balances) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No accounts found.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tTYPE\tSTATUS\tBALANCE")
+
+		// The total is expressed in the base currency, at today's rates
+		var total models.Money
+		var totalErr error
+		for _, b := range balances {
+			status := "open"
+			if b.Account.Closed {
+				status = "closed"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", b.Account.ID, b.Account.Name, b.Account.Type, status,
+				formatAmount(b.Balance, b.Account.Currency))
+
+			converted, err := conv.Convert(b.Balance, b.Account.Currency, time.Now())
+			if err != nil && totalErr == nil {
+				totalErr = err
+			}
+			total += converted
+		}
+		if totalErr == nil {
+			fmt.Fprintf(w, "\tTOTAL\t\t\t%s\n", formatAmount(total, conv.Base()))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if totalErr != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Total not available: %v\n", totalErr)
+		}
+		return nil
+	},
+}
+
+var accountRenameCmd = &cobra.Command{
+	Use:   "rename [old-name] [new-name]",
+	Short: "Rename an account (its transactions follow)",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := models.RenameAccount(database, args[0], args[1]); err != nil {
+			return fmt.Errorf("failed to rename account: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Account '%s' renamed to '%s'.\n", args[0], args[1])
+		return nil
+	},
+}
+
+var accountCloseCmd = &cobra.Command{
+	Use:   "close [name]",
+	Short: "Close an account; its history is kept but no new transactions are accepted",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := models.CloseAccount(database, args[0]); err != nil {
+			return fmt.Errorf("failed to close account: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Account '%s' closed.\n", args[0])
+		return nil
+	},
+}
+
+// accountFilter turns an --account flag value into the canonical account name.
+// Closed accounts are still valid filters, since their history is kept.
+func accountFilter(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	a, err := models.GetAccountByName(database, name)
+	if err != nil {
+		return "", err
+	}
+	return a.Name, nil
+}
+
+func init() {
+	RootCmd.AddCommand(accountCmd)
+	accountCmd.AddCommand(accountCreateCmd)
+	accountCmd.AddCommand(accountListCmd)
+	accountCmd.AddCommand(accountRenameCmd)
+	accountCmd.AddCommand(accountCloseCmd)
+
+	accountCreateCmd.Flags().StringP("type", "T", "checking", "Account type ("+strings.Join(models.AccountTypes, ", ")+")")
+	accountCreateCmd.Flags().String("currency", "", "Currency of the account, e.g. RON (defaults to the base currency)")
+	accountListCmd.Flags().Bool("all", false, "Include closed accounts")
+	accountListCmd.Flags().String("base", "", "Currency of the total (default $FINANCE_BASE_CURRENCY or "+models.DefaultBaseCurrency+")")
+}
@@ -13,34 +13,52 @@ var addCmd = &cobra.Command{
 	Short: "Add a new transaction manually",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// 1. Get flag values DIRECTLY inside the function
-		amount, _ := cmd.Flags().GetFloat64("amount")
+		amountStr, _ := cmd.Flags().GetString("amount")
 		desc, _ := cmd.Flags().GetString("desc")
 		catRaw, _ := cmd.Flags().GetString("category")
 		dateStr, _ := cmd.Flags().GetString("date")
+		accountRaw := accountFlagOrDefault(cmd)
+		currencyRaw, _ := cmd.Flags().GetString("currency")
+		tagsRaw, _ := cmd.Flags().GetStringSlice("tag")
 
-		// --- AUTO-CATEGORIZATION LOGIC ---
-		// If the user didn't provide a category (it's "Uncategorized"), check the rules.
-		if catRaw == "Uncategorized" || catRaw == "" {
-			rules, err := models.ListRules(database)
+		amount, err := models.ParseMoney(amountStr)
+		if err != nil {
+			return err
+		}
+
+		tags := make(map[string]bool, len(tagsRaw))
+		for _, raw := range tagsRaw {
+			tag, err := models.NormalizeTag(raw)
 			if err != nil {
-				return fmt.Errorf("failed to load rules: %w", err)
-			}
-			// Try to match the description against regex rules
-			if match := models.MatchCategory(rules, desc); match != "" {
-				catRaw = match
-				fmt.Fprintf(cmd.OutOrStdout(), "Auto-categorized as: %s\n", catRaw)
+				return err
 			}
+			tags[tag] = true
 		}
 
-		category := models.NormalizeCategory(catRaw)
+		account, err := models.ResolveOpenAccount(database, accountRaw)
+		if err != nil {
+			return err
+		}
+
+		currency, err := models.NormalizeCurrency(currencyRaw)
+		if err != nil {
+			return err
+		}
+		accountName := ""
+		if account != nil {
+			accountName = account.Name
+			if currency == "" {
+				currency = account.Currency
+			}
+		}
 
 		// 2. Parse Date
 		date := time.Now()
 		if dateStr != "" {
 			var err error
-			date, err = time.Parse("2006-01-02", dateStr)
+			date, err = parseDate(dateStr)
 			if err != nil {
-				return fmt.Errorf("invalid date format (use YYYY-MM-DD): %w", err)
+				return err
 			}
 		}
 
@@ -48,7 +66,37 @@ var addCmd = &cobra.Command{
 			Date:        date,
 			Description: desc,
 			Amount:      amount,
-			Category:    category,
+			Category:    catRaw,
+			Account:     accountName,
+			Currency:    currency,
+		}
+
+		// --- RULES ---
+		// The rules may rename, tag or categorize it; a category given by the user
+		// is only replaced by rules made for it (see models.RuleSet.Apply).
+		rules, err := models.LoadRules(database)
+		if err != nil {
+			return fmt.Errorf("failed to load rules: %w", err)
+		}
+		result := rules.Apply(tr)
+		tr.Category = models.NormalizeCategory(tr.Category)
+		if tr.Category != models.NormalizeCategory(catRaw) {
+			fmt.Fprintf(cmd.OutOrStdout(), "Auto-categorized as: %s\n", tr.Category)
+		}
+
+		// What no rule categorizes may be categorized from history
+		if tr.Category == "Uncategorized" {
+			classifier, err := loadClassifier()
+			if err != nil {
+				return err
+			}
+			if p := classifier.Predict(tr.Description, tr.Amount); p.Category != "" && p.Confidence >= settings.Confidence {
+				tr.Category = p.Category
+				fmt.Fprintf(cmd.OutOrStdout(), "Auto-categorized as: %s (learned, %s)\n", tr.Category, formatConfidence(p.Confidence))
+			}
+		}
+		for _, tag := range result.Tags {
+			tags[tag] = true
 		}
 
 		// 3. Save Transaction
@@ -56,23 +104,36 @@ var addCmd = &cobra.Command{
 			return fmt.Errorf("failed to save transaction: %w", err)
 		}
 
+		if len(tagsRaw) > 0 {
+			if err := models.AddTags(database, tr.ID, tagsRaw...); err != nil {
+				return fmt.Errorf("failed to tag transaction: %w", err)
+			}
+		}
+		if err := models.ApplyRuleResult(database, tr, result); err != nil {
+			return fmt.Errorf("failed to apply the rules: %w", err)
+		}
+
 		fmt.Fprintf(cmd.OutOrStdout(), "Successfully added transaction (ID: %d)\n", tr.ID)
 
 		// 4. Budget Alert Logic
 		// Only check if it's an expense (negative amount)
 		if amount < 0 {
+			conv, err := newConverter()
+			if err != nil {
+				return err
+			}
 			budgets, _ := models.ListBudgets(database)
 			for _, b := range budgets {
-				if b.Category == category {
-					spent, _ := models.GetSpendingTotal(database, category, date.Month(), date.Year())
+				// The budgets of the category and its parents, and those of the transaction's tags are affected
+				if (b.Tag == "" && models.IsSubcategory(tr.Category, b.Category)) || tags[b.Tag] {
+					spent, _ := models.GetBudgetSpending(database, b, date.Month(), date.Year(), conv)
 
 					if spent > b.Amount {
-						fmt.Fprintf(cmd.OutOrStdout(), "\n⚠️  ALERT: You have exceeded your budget for '%s'!\n", category)
-						fmt.Fprintf(cmd.OutOrStdout(), "   Limit: %.2f | Spent: %.2f\n", b.Amount, spent)
-					} else if spent > (b.Amount * 0.9) {
-						fmt.Fprintf(cmd.OutOrStdout(), "\n⚠️  WARNING: You are close to your budget for '%s' (%.0f%% used).\n", category, (spent/b.Amount)*100)
+						fmt.Fprintf(cmd.OutOrStdout(), "\n⚠️  ALERT: You have exceeded your budget for '%s'!\n", b.Target())
+						fmt.Fprintf(cmd.OutOrStdout(), "   Limit: %s | Spent: %s\n", b.Amount, spent)
+					} else if spent*10 > b.Amount*9 { // more than 90% used
+						fmt.Fprintf(cmd.OutOrStdout(), "\n⚠️  WARNING: You are close to your budget for '%s' (%.0f%% used).\n", b.Target(), float64(spent)/float64(b.Amount)*100)
 					}
-					break
 				}
 			}
 		}
@@ -84,10 +145,14 @@ var addCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(addCmd)
 
-	addCmd.Flags().Float64P("amount", "a", 0, "Amount (positive for income, negative for expense)")
+	addCmd.Flags().StringP("amount", "a", "", "Amount (positive for income, negative for expense)")
 	addCmd.Flags().StringP("desc", "d", "", "Transaction description")
 	addCmd.Flags().StringP("category", "c", "Uncategorized", "Transaction category")
-	addCmd.Flags().StringP("date", "t", "", "Date (YYYY-MM-DD), defaults to today")
+	addCmd.Flags().StringP("date", "t", "", "Date (YYYY-MM-DD or the configured date_format), defaults to today")
+	addCmd.Flags().String("account", "", "Account the transaction belongs to (defaults to the configured default_account)")
+	addCmd.Flags().String("currency", "", "Currency code (defaults to the account's currency)")
+	addCmd.Flags().StringSlice("tag", nil, "Tag the transaction (repeatable, e.g. --tag vacation-2026)")
+	addCmd.Flags().String("confidence", "", "How likely a category learned from history must be to be taken, e.g. 0.8, or off (defaults to the classifier_confidence setting)")
 
 	addCmd.MarkFlagRequired("amount")
 	addCmd.MarkFlagRequired("desc")
@@ -18,18 +18,32 @@ var budgetCmd = &cobra.Command{
 
 var budgetAddCmd = &cobra.Command{
 	Use:     "add",
-	Short:   "Set or update a budget for a category",
-	Example: "finance budget add --category Food --amount 500",
+	Short:   "Set or update a budget for a category or a tag",
+	Example: "finance budget add --category Food --amount 500\nfinance budget add --tag vacation-2026 --amount 2000",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get values locally
 		catRaw, _ := cmd.Flags().GetString("category") // Rename to catRaw
-		amount, _ := cmd.Flags().GetFloat64("amount")
-		category := models.NormalizeCategory(catRaw)
+		tagRaw, _ := cmd.Flags().GetString("tag")
+		amountStr, _ := cmd.Flags().GetString("amount")
+
+		amount, err := models.ParseMoney(amountStr)
+		if err != nil {
+			return err
+		}
 
 		b := &models.Budget{
-			Category: category,
-			Amount:   amount,
-			Period:   "monthly",
+			Amount: amount,
+			Period: "monthly",
+		}
+		switch {
+		case (catRaw == "") == (tagRaw == ""):
+			return fmt.Errorf("specify either --category or --tag")
+		case tagRaw != "":
+			if b.Tag, err = models.NormalizeTag(tagRaw); err != nil {
+				return err
+			}
+		default:
+			b.Category = models.NormalizeCategory(catRaw)
 		}
 
 		// This handles Insert OR Update
@@ -38,7 +52,7 @@ var budgetAddCmd = &cobra.Command{
 		}
 
 		// Updated success message
-		fmt.Fprintf(cmd.OutOrStdout(), "Budget set for '%s': %.2f/month\n", category, amount)
+		fmt.Fprintf(cmd.OutOrStdout(), "Budget set for '%s': %s/month\n", b.Target(), amount)
 		return nil
 	},
 }
@@ -57,22 +71,28 @@ var budgetListCmd = &cobra.Command{
 			return nil
 		}
 
+		conv, err := newConverter()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Amounts in %s\n", conv.Base())
 		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tCATEGORY\tLIMIT\tSPENT\tREMAINING\tSTATUS")
+		fmt.Fprintln(w, "ID\tTARGET\tLIMIT\tSPENT\tREMAINING\tSTATUS")
 
 		now := time.Now()
 
 		for _, b := range budgets {
-			spent, err := models.GetSpendingTotal(database, b.Category, now.Month(), now.Year())
+			spent, err := models.GetBudgetSpending(database, b, now.Month(), now.Year(), conv)
 			if err != nil {
-				spent = 0
+				return fmt.Errorf("failed to compute spending for '%s': %w", b.Target(), err)
 			}
 
 			remaining := b.Amount - spent
 			status := getProgressBar(spent, b.Amount)
 
-			fmt.Fprintf(w, "%d\t%s\t%.2f\t%.2f\t%.2f\t%s\n",
-				b.ID, b.Category, b.Amount, spent, remaining, status)
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+				b.ID, b.Target(), b.Amount, spent, remaining, status)
 		}
 		return w.Flush()
 	},
@@ -97,11 +117,11 @@ var budgetRemoveCmd = &cobra.Command{
 	},
 }
 
-func getProgressBar(spent, limit float64) string {
+func getProgressBar(spent, limit models.Money) string {
 	if limit == 0 {
 		return "[???]"
 	}
-	percent := spent / limit
+	percent := float64(spent) / float64(limit)
 	if percent > 1.0 {
 		return "[!! OVER BUDGET !!]"
 	}
@@ -121,7 +141,8 @@ func init() {
 
 	// Define flags locally
 	budgetAddCmd.Flags().StringP("category", "c", "", "Category for the budget")
-	budgetAddCmd.Flags().Float64P("amount", "a", 0, "Spending limit amount")
-	budgetAddCmd.MarkFlagRequired("category")
+	budgetAddCmd.Flags().String("tag", "", "Tag for the budget (instead of a category)")
+	budgetAddCmd.Flags().StringP("amount", "a", "", "Spending limit amount")
 	budgetAddCmd.MarkFlagRequired("amount")
+	budgetListCmd.Flags().String("base", "", "Currency to convert spending into (default $FINANCE_BASE_CURRENCY or "+models.DefaultBaseCurrency+")")
 }
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var categorizeCmd = &cobra.Command{
+	Use:   "categorize",
+	Short: "Categorize uncategorized transactions by what was learned from the categorized ones",
+	Long: `Learns from every categorized transaction which words of a description and which
+sizes of amount go with which category, and predicts a category for the uncategorized
+ones, with how likely it is. If the classifier_confidence setting (or --confidence on
+'add' and 'import') asks for it, new transactions that no rule categorizes get the
+learned category by themselves when it is at least that likely; this command is the
+explicit way, for everything left uncategorized.
+
+--suggest lists the predictions, most likely first; --min leaves out the less likely
+ones. Review the list, then run again with --accept to store all of them, or name the
+transaction IDs to accept only those.`,
+	Example: "finance categorize --suggest\nfinance categorize --suggest --min 0.6 --accept\nfinance categorize --accept 12 15",
+	Args:    cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		suggest, _ := cmd.Flags().GetBool("suggest")
+		accept, _ := cmd.Flags().GetBool("accept")
+		minimum, _ := cmd.Flags().GetFloat64("min")
+		if !suggest && !accept {
+			return fmt.Errorf("use --suggest to list the predicted categories, or --accept to store them")
+		}
+		if len(args) > 0 && !accept {
+			return fmt.Errorf("transaction IDs only select the suggestions to --accept")
+		}
+		only := map[int64]bool{}
+		for _, arg := range args {
+			id, err := parseID(arg)
+			if err != nil {
+				return err
+			}
+			only[id] = true
+		}
+
+		classifier, err := models.TrainClassifier(database)
+		if err != nil {
+			return fmt.Errorf("failed to learn from the categorized transactions: %w", err)
+		}
+		all, err := models.SuggestCategories(database, classifier)
+		if err != nil {
+			return fmt.Errorf("failed to predict categories: %w", err)
+		}
+		var list []models.Suggestion
+		for _, s := range all {
+			if s.Confidence >= minimum && (len(only) == 0 || only[s.Transaction.ID]) {
+				list = append(list, s)
+			}
+		}
+		if len(list) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No category to suggest.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tDATE\tDESCRIPTION\tAMOUNT\tCATEGORY\tCONFIDENCE")
+		for _, s := range list {
+			t := s.Transaction
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", t.ID, formatDate(t.Date), t.Description,
+				formatAmount(t.Amount, t.Currency), s.Category, formatConfidence(s.Confidence))
+		}
+		w.Flush()
+
+		if !accept {
+			fmt.Fprintf(cmd.OutOrStdout(), "%d suggestion(s). Run again with --accept to store them.\n", len(list))
+			return nil
+		}
+		if err := models.AcceptSuggestions(database, list); err != nil {
+			return fmt.Errorf("failed to store the categories: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Categorized %d transaction(s).\n", len(list))
+		return nil
+	},
+}
+
+// loadClassifier learns from the categorized transactions unless the classifier_confidence
+// setting turns learning off, in which case it returns nil.
+func loadClassifier() (*models.Classifier, error) {
+	if settings.Confidence <= 0 {
+		return nil, nil
+	}
+	c, err := models.TrainClassifier(database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to learn from the categorized transactions: %w", err)
+	}
+	return c, nil
+}
+
+// formatConfidence shows a probability as a whole percentage.
+func formatConfidence(c float64) string {
+	return fmt.Sprintf("%.0f%%", c*100)
+}
+
+func init() {
+	RootCmd.AddCommand(categorizeCmd)
+
+	categorizeCmd.Flags().Bool("suggest", false, "List the uncategorized transactions with the category predicted for them")
+	categorizeCmd.Flags().Bool("accept", false, "Store the listed suggestions")
+	categorizeCmd.Flags().Float64("min", 0, "Leave out suggestions less likely than this, e.g. 0.6")
+}
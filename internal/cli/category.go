@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var categoryCmd = &cobra.Command{
+	Use:   "category",
+	Short: "Manage the category tree",
+	Long: `Categories form a tree. A path such as "Food:Groceries" names Groceries below Food;
+reports roll subcategories up into their parents, and a budget on a parent covers
+all of its children. Categories used by 'add', 'import' or rules are created
+automatically.`,
+}
+
+var categoryAddCmd = &cobra.Command{
+	Use:     "add [path]",
+	Short:   "Create a category (and its missing parents)",
+	Example: "finance category add Food:Groceries",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := models.EnsureCategory(database, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Category '%s' is ready.\n", c.Path)
+		return nil
+	},
+}
+
+var categoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show the category tree",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		categories, err := models.ListCategories(database)
+		if err != nil {
+			return fmt.Errorf("failed to list categories: %w", err)
+		}
+		if len(categories) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No categories found.")
+			return nil
+		}
+
+		// Parents come before their children, so the depth is all that's needed to indent
+		for _, c := range categories {
+			depth := strings.Count(c.Path, models.CategorySeparator)
+			fmt.Fprintf(cmd.OutOrStdout(), "%s%s\n", strings.Repeat("  ", depth), c.Name)
+		}
+		return nil
+	},
+}
+
+var categoryRenameCmd = &cobra.Command{
+	Use:     "rename [path] [new-name]",
+	Short:   "Rename a category; subcategories, transactions, budgets and rules follow",
+	Example: "finance category rename Food:Eating-Out Restaurants",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := models.RenameCategory(database, args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Category '%s' renamed to '%s'.\n", args[0], models.NormalizeCategory(args[1]))
+		return nil
+	},
+}
+
+var categoryMoveCmd = &cobra.Command{
+	Use:     "move [path] [new-parent]",
+	Short:   "Move a category below another one (omit the parent to make it top-level)",
+	Example: "finance category move Groceries Food",
+	Args:    cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		parent := ""
+		if len(args) == 2 {
+			parent = args[1]
+		}
+		if err := models.MoveCategory(database, args[0], parent); err != nil {
+			return err
+		}
+		if parent == "" {
+			fmt.Fprintf(cmd.OutOrStdout(), "Category '%s' is now top-level.\n", args[0])
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "Category '%s' moved below '%s'.\n", args[0], parent)
+		}
+		return nil
+	},
+}
+
+var categoryRemoveCmd = &cobra.Command{
+	Use:   "remove [path]",
+	Short: "Remove a category that is no longer used",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := models.DeleteCategory(database, args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Category '%s' removed.\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(categoryCmd)
+	categoryCmd.AddCommand(categoryAddCmd)
+	categoryCmd.AddCommand(categoryListCmd)
+	categoryCmd.AddCommand(categoryRenameCmd)
+	categoryCmd.AddCommand(categoryMoveCmd)
+	categoryCmd.AddCommand(categoryRemoveCmd)
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/config"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change the defaults stored in the config file",
+	// Changing the config must work even when the configured database is unusable
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSettings(cmd)
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the settings in effect and where each one comes from",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		fmt.Fprintf(w, "db\t%s\t%s\n", settings.DBPath, settings.Sources["db"])
+		fmt.Fprintf(w, "base_currency\t%s\t%s\n", settings.BaseCurrency, settings.Sources["base_currency"])
+		fmt.Fprintf(w, "date_format\t%s\t%s\n", settings.DateFormat, settings.Sources["date_format"])
+		fmt.Fprintf(w, "default_account\t%s\t%s\n", settings.DefaultAccount, settings.Sources["default_account"])
+		fmt.Fprintf(w, "fuzzy_dedup\t%s\t%s\n", settings.FuzzyDedup, settings.Sources["fuzzy_dedup"])
+		fmt.Fprintf(w, "classifier_confidence\t%s\t%s\n", models.FormatConfidence(settings.Confidence), settings.Sources["classifier_confidence"])
+		return w.Flush()
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the location of the config file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.Path()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), path)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:     "set [key] [value]",
+	Short:   "Set a config key (db, base_currency, date_format, default_account, fuzzy_dedup, classifier_confidence); an empty value unsets it",
+	Example: "finance config set date_format DD.MM.YYYY\nfinance config set fuzzy_dedup days=5,amount=0.50,similarity=0.6",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, value := args[0], args[1]
+
+		// Reject values that would make every later command fail
+		switch key {
+		case "base_currency":
+			normalized, err := models.NormalizeCurrency(value)
+			if err != nil {
+				return err
+			}
+			value = normalized
+		case "date_format":
+			if _, err := config.DateLayout(value); err != nil {
+				return err
+			}
+		case "fuzzy_dedup":
+			if value != "" {
+				m, err := models.ParseFuzzyMatch(value)
+				if err != nil {
+					return err
+				}
+				value = m.String()
+			}
+		case "classifier_confidence":
+			if value != "" {
+				c, err := models.ParseConfidence(value)
+				if err != nil {
+					return err
+				}
+				value = models.FormatConfidence(c)
+			}
+		}
+
+		path, err := config.Path()
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+		if err := cfg.Set(key, value); err != nil {
+			return err
+		}
+		if err := cfg.Save(path); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		if value == "" {
+			fmt.Fprintf(cmd.OutOrStdout(), "Unset %s\n", key)
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "Set %s = %s\n", key, value)
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configSetCmd)
+}
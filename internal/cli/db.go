@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Inspect and upgrade the database schema",
+	// Unlike every other command, 'db' must not migrate automatically,
+	// otherwise 'db status' could never show a pending migration.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadSettings(cmd); err != nil {
+			return err
+		}
+		if database == nil {
+			var err error
+			database, err = db.Open(settings.DBPath)
+			if err != nil {
+				return fmt.Errorf("could not connect to database: %w", err)
+			}
+		}
+		return nil
+	},
+}
+
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which schema migrations have been applied",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		states, err := db.Status(database)
+		if err != nil {
+			return fmt.Errorf("failed to read migration status: %w", err)
+		}
+
+		pending := 0
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT")
+		for _, s := range states {
+			if s.Applied {
+				fmt.Fprintf(w, "%s\tapplied\t%s\n", s.Version, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Fprintf(w, "%s\tpending\t\n", s.Version)
+				pending++
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%d pending migration(s).\n", pending)
+		return nil
+	},
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply all pending schema migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		applied, err := db.Migrate(database)
+		for _, v := range applied {
+			fmt.Fprintf(cmd.OutOrStdout(), "Applied %s\n", v)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "Database is up to date.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbStatusCmd)
+	dbCmd.AddCommand(dbMigrateCmd)
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var duplicatesCmd = &cobra.Command{
+	Use:   "duplicates",
+	Short: "Find transactions stored twice, e.g. by imports from two sources, and merge them",
+	Long: `Lists pairs of transactions in the same account that are likely the same one: booked
+by different sources (two import formats, or an import and 'add'), a few days apart, with
+nearly the same amount and a similar description, e.g. "STARBUCKS 054" from a CSV export
+and "Starbucks - Coffee" from an OFX statement. --fuzzy or the fuzzy_dedup setting tunes
+how close they must be; while fuzzy_dedup is off, the defaults of "on" are used.
+
+Review the list, then run again with --merge to merge all of them, or use
+'duplicates merge' for single pairs. Merging keeps the transaction with the bank's
+reference (else the older one) and moves the other one's tags, category, split lines
+and transfer over to it where it has none.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		merge, _ := cmd.Flags().GetBool("merge")
+		fuzzy := settings.FuzzyDedup
+		if !fuzzy.Enabled() && !cmd.Flags().Changed("fuzzy") {
+			fuzzy = models.DefaultFuzzyMatch
+		}
+		if !fuzzy.Enabled() {
+			return fmt.Errorf("fuzzy matching is off; use --fuzzy to set how close duplicates must be")
+		}
+
+		pairs, err := models.FindDuplicates(database, fuzzy)
+		if err != nil {
+			return fmt.Errorf("failed to find duplicates: %w", err)
+		}
+		if len(pairs) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No likely duplicates found.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEEP\tDATE\tDESCRIPTION\tDUPLICATE\tDATE\tDESCRIPTION\tAMOUNT\tSIMILARITY")
+		for _, p := range pairs {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\t%.0f%%\n",
+				p.Keep.ID, formatDate(p.Keep.Date), p.Keep.Description,
+				p.Duplicate.ID, formatDate(p.Duplicate.Date), p.Duplicate.Description,
+				formatAmount(p.Keep.Amount, p.Keep.Currency), p.Similarity*100)
+		}
+		w.Flush()
+
+		if !merge {
+			fmt.Fprintf(cmd.OutOrStdout(), "%d likely duplicate(s). Run again with --merge to merge them.\n", len(pairs))
+			return nil
+		}
+		for _, p := range pairs {
+			if err := models.MergeDuplicates(database, p.Keep.ID, p.Duplicate.ID); err != nil {
+				return fmt.Errorf("failed to merge %d into %d: %w", p.Duplicate.ID, p.Keep.ID, err)
+			}
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Merged %d duplicate(s).\n", len(pairs))
+		return nil
+	},
+}
+
+var duplicatesMergeCmd = &cobra.Command{
+	Use:   "merge [keep-id] [duplicate-id]",
+	Short: "Merge a transaction into the one it duplicates and delete it",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keep, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		dup, err := parseID(args[1])
+		if err != nil {
+			return err
+		}
+		if err := models.MergeDuplicates(database, keep, dup); err != nil {
+			return fmt.Errorf("failed to merge: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Transaction %d merged into %d.\n", dup, keep)
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(duplicatesCmd)
+	duplicatesCmd.AddCommand(duplicatesMergeCmd)
+
+	duplicatesCmd.Flags().Bool("merge", false, "Merge every pair found")
+	duplicatesCmd.Flags().String("fuzzy", "", "How close duplicates must be, e.g. days=3,amount=0.50,similarity=0.5 (defaults to the fuzzy_dedup setting, or on)")
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/importer"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export transactions as CSV or QIF",
+	Long: `Writes transactions, oldest first, to standard output or a file.
+
+csv  Date,Description,Amount,Category,Currency,Account,Transfer, the last being the
+     account of the other leg of a transfer; 'finance import' reads it back.
+qif  One !Type section per account, with categories, split lines and transfers
+     ("[Account]"), for Quicken, GnuCash, HomeBank and similar programs.`,
+	Example: "finance export --format qif --account Checking --output checking.qif",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		accountRaw, _ := cmd.Flags().GetString("account")
+		account, err := accountFilter(accountRaw)
+		if err != nil {
+			return err
+		}
+
+		var write func(io.Writer, []models.Transaction) error
+		switch strings.ToLower(format) {
+		case "csv":
+			write = exportCSV
+		case "qif":
+			write = exportQIF
+		default:
+			return fmt.Errorf("unknown export format %q (use csv or qif)", format)
+		}
+
+		transactions, err := models.FindTransactions(database, models.TransactionFilter{Account: account})
+		if err != nil {
+			return fmt.Errorf("failed to load transactions: %w", err)
+		}
+		// Grouped by account, oldest first
+		sort.SliceStable(transactions, func(i, j int) bool {
+			if transactions[i].Account != transactions[j].Account {
+				return transactions[i].Account < transactions[j].Account
+			}
+			return transactions[i].Date.Before(transactions[j].Date)
+		})
+
+		if output == "" {
+			return write(cmd.OutOrStdout(), transactions)
+		}
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		defer file.Close()
+		if err := write(file, transactions); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%d transactions exported to %s\n", len(transactions), output)
+		return file.Close()
+	},
+}
+
+func exportCSV(w io.Writer, transactions []models.Transaction) error {
+	otherLeg, err := otherLegs()
+	if err != nil {
+		return err
+	}
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"Date", "Description", "Amount", "Category", "Currency", "Account", "Transfer"})
+	for _, t := range transactions {
+		out.Write([]string{t.Date.Format("2006-01-02"), t.Description, t.Amount.String(), t.Category, t.Currency, t.Account, otherLeg[t.ID]})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func exportQIF(w io.Writer, transactions []models.Transaction) error {
+	accounts, err := models.ListAccounts(database, true)
+	if err != nil {
+		return err
+	}
+	qifType := map[string]string{}
+	for _, a := range accounts {
+		switch a.Type {
+		case "credit":
+			qifType[a.Name] = "CREDITCARD"
+		case "cash":
+			qifType[a.Name] = "CASH"
+		default:
+			qifType[a.Name] = "BANK"
+		}
+	}
+
+	// A transfer leg is written with the other leg's account as its category
+	otherLeg, err := otherLegs()
+	if err != nil {
+		return err
+	}
+
+	var statements []importer.Statement
+	for _, t := range transactions {
+		if len(statements) == 0 || statements[len(statements)-1].AccountID != t.Account {
+			statements = append(statements, importer.Statement{AccountID: t.Account, AccountType: qifType[t.Account]})
+		}
+
+		rec := importer.Record{Date: t.Date, Description: t.Description, Amount: t.Amount}
+		transfer := otherLeg[t.ID]
+		if t.Category != "Uncategorized" {
+			rec.Category = t.Category
+		}
+
+		splits, err := models.ListSplits(database, t.ID)
+		if err != nil {
+			return err
+		}
+		if len(splits) > 0 {
+			// QIF split lines add up to the amount, so the remainder is written as one too.
+			// Of a split transfer, the Transfer line is the part that went to the other leg.
+			lines, err := models.GetSplitLines(database, &t)
+			if err != nil {
+				return err
+			}
+			for _, l := range lines {
+				sp := importer.Split{Amount: l.Amount, Category: l.Category, Memo: l.Memo}
+				if l.Category == models.TransferCategory && transfer != "" {
+					sp.Category, sp.Transfer, transfer = "", transfer, ""
+				}
+				rec.Splits = append(rec.Splits, sp)
+			}
+		}
+		if transfer != "" {
+			rec.Category, rec.Transfer = "", transfer
+		}
+
+		s := &statements[len(statements)-1]
+		s.Records = append(s.Records, rec)
+	}
+	return importer.WriteQIF(w, statements)
+}
+
+// otherLegs maps each leg of a transfer to the account of the other leg.
+func otherLegs() (map[int64]string, error) {
+	transfers, err := models.ListTransfers(database)
+	if err != nil {
+		return nil, err
+	}
+	otherLeg := map[int64]string{}
+	for _, p := range transfers {
+		otherLeg[p.Out.ID] = p.In.Account
+		otherLeg[p.In.ID] = p.Out.Account
+	}
+	return otherLeg, nil
+}
+
+func init() {
+	RootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringP("format", "f", "csv", "Output format: csv or qif")
+	exportCmd.Flags().StringP("output", "o", "", "File to write; standard output if omitted")
+	exportCmd.Flags().String("account", "", "Only export transactions of this account")
+}
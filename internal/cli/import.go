@@ -1,228 +1,420 @@
 package cli
 
 import (
-	"encoding/csv"
-	"encoding/xml"
+	"bufio"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
-	"time"
+	"text/tabwriter"
 
+	"github.com/SebiGabor/personal-finance-cli/internal/importer"
 	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/SebiGabor/personal-finance-cli/internal/tui"
 	"github.com/spf13/cobra"
 )
 
 var importCmd = &cobra.Command{
 	Use:   "import [file]",
-	Short: "Import transactions from a CSV or OFX file",
-	Long:  `Imports transactions from a file. Supports .csv and .ofx formats.`,
-	Args:  cobra.ExactArgs(1),
+	Short: "Import transactions from a CSV, OFX, QIF, camt or MT940 file",
+	Long: `Imports transactions from a file. Supports CSV, OFX/QFX (OFX 1.x SGML and 2.x XML), QIF,
+ISO 20022 camt.053/052/054 XML and SWIFT MT940 statements. The format is recognized from the
+file's content; files without a marker are read as CSV if they end in .csv or .txt.
+
+--dry-run lists what would happen to every record without writing anything; --review opens
+a screen to accept, reject or recategorize the records before they are stored.
+
+Every import is recorded as a batch: 'import list' shows them, 'import show' the
+transactions of one and 'import undo' deletes them again. Importing the very same file
+twice is refused unless --force is given.
+
+Only exact duplicates are skipped, unless --fuzzy or the fuzzy_dedup setting turns on
+fuzzy matching: then records that closely match a transaction from another source (e.g.
+the card payment a CSV export brings that an OFX statement already did, with another
+description and a later date) are skipped too, and listed.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		filePath := args[0]
-		ext := strings.ToLower(filepath.Ext(filePath))
+
+		accountRaw := accountFlagOrDefault(cmd)
+		account, err := models.ResolveOpenAccount(database, accountRaw)
+		if err != nil {
+			return err
+		}
 
 		// Load rules for auto-categorization
-		rules, err := models.ListRules(database)
+		rules, err := models.LoadRules(database)
 		if err != nil {
 			return fmt.Errorf("failed to load categorization rules: %w", err)
 		}
+		classifier, err := loadClassifier()
+		if err != nil {
+			return err
+		}
+		accounts, err := models.ListAccounts(database, false)
+		if err != nil {
+			return fmt.Errorf("failed to load accounts: %w", err)
+		}
 
-		fmt.Fprintf(cmd.OutOrStdout(), "Importing file: %s\n", filePath)
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		review, _ := cmd.Flags().GetBool("review")
+		force, _ := cmd.Flags().GetBool("force")
+		if dryRun && review {
+			return fmt.Errorf("--dry-run and --review can't be combined")
+		}
 
-		switch ext {
-		case ".csv":
-			return importCSV(cmd, filePath, rules)
-		case ".ofx":
-			return importOFX(cmd, filePath, rules)
-		default:
-			return fmt.Errorf("unsupported file format '%s'. Please use .csv or .ofx", ext)
+		// The file is read twice, never as a whole: once for its hash, once to import it
+		f, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer f.Close()
+		hash, err := models.HashFile(f)
+		if err == nil {
+			_, err = f.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
 		}
-	},
-}
 
-// --- CSV Logic ---
-func importCSV(cmd *cobra.Command, filePath string, rules []models.CategoryRule) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
+		// A byte-identical file has been imported before
+		previous, err := models.FindImportBatchByHash(database, hash)
+		if err != nil {
+			return fmt.Errorf("failed to look up earlier imports: %w", err)
+		}
+		if previous != nil && !force && !dryRun {
+			return fmt.Errorf("%s was already imported as batch %d on %s; use --force to import it again, or 'finance import undo %d' first",
+				filePath, previous.ID, formatDate(previous.CreatedAt), previous.ID)
+		}
 
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = -1 // Allow variable fields
+		in := bufio.NewReaderSize(f, importer.SniffLen)
+		head, err := in.Peek(importer.SniffLen)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
 
-	records, err := reader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("failed to read CSV data: %w", err)
-	}
+		var imp importer.Importer
+		if format, _ := cmd.Flags().GetString("format"); format != "" {
+			imp, err = importer.Lookup(format)
+		} else {
+			imp, err = importer.Detect(filePath, head)
+		}
+		if err != nil {
+			return err
+		}
 
-	importedCount := 0
-	skippedCount := 0
-	duplicateCount := 0 // Track duplicates
+		fmt.Fprintf(cmd.OutOrStdout(), "Importing file: %s\n", filePath)
+		if previous != nil && !force {
+			fmt.Fprintf(cmd.OutOrStdout(), "⚠️  %s was already imported as batch %d on %s.\n", filePath, previous.ID, formatDate(previous.CreatedAt))
+		}
 
-	for i, record := range records {
-		if len(record) < 3 {
-			continue
+		// CSV files need a profile describing their columns
+		if _, ok := imp.(importer.CSV); ok {
+			profileName, _ := cmd.Flags().GetString("profile")
+			profile, err := csvProfile(cmd, head, profileName)
+			if err != nil {
+				return err
+			}
+			imp = importer.CSV{Profile: profile}
 		}
 
-		// 1. Date
-		dateStr := strings.TrimSpace(record[0])
-		date, err := time.Parse("2006-01-02", dateStr)
-		if err != nil {
-			if i == 0 {
-				continue
-			} // Skip header
-			fmt.Fprintf(cmd.OutOrStdout(), "Row %d skipped: invalid date\n", i+1)
-			skippedCount++
-			continue
-		}
-
-		// 2. Description & Amount
-		description := strings.TrimSpace(record[1])
-		amountStr := strings.TrimSpace(record[2])
-		amount, err := strconv.ParseFloat(amountStr, 64)
-		if err != nil {
-			fmt.Fprintf(cmd.OutOrStdout(), "Row %d skipped: invalid amount\n", i+1)
-			skippedCount++
-			continue
+		name := strings.ToUpper(imp.Name())
+		pipeline := &importer.Pipeline{DB: database, Account: account, Rules: rules, Fuzzy: settings.FuzzyDedup, Source: imp.Name(),
+			Accounts: accounts, Classifier: classifier, Confidence: settings.Confidence}
+		batch := &models.ImportBatch{FileName: filepath.Base(filePath), FileHash: hash, Format: imp.Name()}
+		if account != nil {
+			batch.Account = account.Name
 		}
+		report := &importReport{cmd: cmd, batch: batch}
 
-		// 3. Category
-		category := "Uncategorized"
-		if len(record) > 3 && strings.TrimSpace(record[3]) != "" {
-			category = strings.TrimSpace(record[3])
-		} else {
-			if match := models.MatchCategory(rules, description); match != "" {
-				category = match
+		// Files that can be streamed go into the database record by record
+		if streamer, ok := imp.(importer.RecordStreamer); ok && !dryRun && !review {
+			if err := pipeline.Begin(batch); err != nil {
+				return err
 			}
+			defer pipeline.Abort()
+
+			err := streamer.Stream(in, func(rec importer.Record) error {
+				o, err := pipeline.ImportRecord(importer.Statement{}, rec)
+				if err != nil {
+					return err
+				}
+				report.add(o)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to import %s, nothing was written: %w", name, err)
+			}
+			if err := pipeline.End(batch); err != nil {
+				return err
+			}
+			report.finish()
+			return hintTransfers(cmd, report.imported)
 		}
 
-		category = models.NormalizeCategory(category)
-		tr := &models.Transaction{Date: date, Description: description, Amount: amount, Category: category}
-
-		exists, err := models.TransactionExists(database, tr)
+		statements, err := imp.Parse(in)
 		if err != nil {
-			return fmt.Errorf("failed to check duplicate: %w", err)
+			return fmt.Errorf("failed to parse %s: %w", name, err)
 		}
-		if exists {
-			duplicateCount++
-			continue // Skip this transaction
+
+		// Decide what happens to every record before anything is written
+		plans := make([][]importer.Outcome, len(statements))
+		for i, s := range statements {
+			if plans[i], err = pipeline.Check(s); err != nil {
+				return err
+			}
 		}
 
-		if err := models.CreateTransaction(database, tr); err != nil {
-			skippedCount++
-		} else {
-			importedCount++
+		if dryRun {
+			printImportPlan(cmd, name, statements, plans)
+			return nil
+		}
+		if review {
+			ok, err := reviewImport(plans)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Fprintln(cmd.OutOrStdout(), "Import cancelled, nothing was written.")
+				return nil
+			}
 		}
-	}
 
-	fmt.Fprintf(cmd.OutOrStdout(), "CSV Import complete. %d imported, %d duplicates skipped, %d errors.\n", importedCount, duplicateCount, skippedCount)
-	return nil
-}
+		if err := pipeline.Begin(batch); err != nil {
+			return err
+		}
+		defer pipeline.Abort()
+		for i, s := range statements {
+			printStatementHeader(cmd, s)
+			if err := pipeline.Commit(plans[i]); err != nil {
+				return fmt.Errorf("failed to import %s, nothing was written: %w", name, err)
+			}
+			for _, o := range plans[i] {
+				report.add(o)
+			}
+			warnDifference(cmd, s)
+		}
+		if err := pipeline.End(batch); err != nil {
+			return err
+		}
 
-// --- OFX Logic ---
-
-// XML Structures for parsing OFX
-type OFX struct {
-	BankMsgs struct {
-		StmtTrn struct {
-			StmtRs struct {
-				BankTranList struct {
-					Transactions []OFXTransaction `xml:"STMTTRN"`
-				} `xml:"BANKTRANLIST"`
-			} `xml:"STMTRS"`
-		} `xml:"STMTTRNRS"`
-	} `xml:"BANKMSGSRSV1"`
+		// Compared once the import is stored, as the account's balance includes it
+		sources := map[string]map[string]bool{} // bank accounts of each account's currency
+		for _, s := range statements {
+			if a := pipeline.AccountOf(s); a != nil && (s.Currency == "" || s.Currency == a.Currency) {
+				if sources[a.Name] == nil {
+					sources[a.Name] = map[string]bool{}
+				}
+				sources[a.Name][s.BankAccount()] = true
+			}
+		}
+		for _, s := range statements {
+			if s.Balance != nil {
+				a := pipeline.AccountOf(s)
+				if err := checkBalance(cmd, a, s, a != nil && len(sources[a.Name]) > 1); err != nil {
+					return err
+				}
+			}
+		}
+		report.finish()
+		return hintTransfers(cmd, report.imported)
+	},
 }
 
-type OFXTransaction struct {
-	TrnType  string `xml:"TRNTYPE"`
-	DtPosted string `xml:"DTPOSTED"`
-	TrnAmt   string `xml:"TRNAMT"`
-	Name     string `xml:"NAME"`
-	Memo     string `xml:"MEMO"`
+// importReport counts the outcomes of an import into its batch and prints what the user
+// needs to know about single records.
+type importReport struct {
+	cmd      *cobra.Command
+	batch    *models.ImportBatch
+	imported []int64 // IDs of the new transactions
+	rejected int
+	learned  int // new transactions categorized by the classifier
 }
 
-func importOFX(cmd *cobra.Command, filePath string, rules []models.CategoryRule) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+func (r *importReport) add(o importer.Outcome) {
+	switch o.Status {
+	case importer.StatusImported:
+		r.batch.Imported++
+		r.imported = append(r.imported, o.Transaction.ID)
+		if o.Learned > 0 {
+			r.learned++
+		}
+	case importer.StatusDuplicate:
+		r.batch.Duplicates++
+		if m := o.Match; m != nil {
+			t := o.Transaction
+			fmt.Fprintf(r.cmd.OutOrStdout(), "Skipped %q of %s as likely the same as #%d %q of %s (import again with --fuzzy off --force to keep it).\n",
+				t.Description, formatDate(t.Date), m.ID, m.Description, formatDate(m.Date))
+		}
+	case importer.StatusRejected:
+		r.rejected++
+	case importer.StatusInvalid:
+		if o.Record.Line > 0 {
+			fmt.Fprintf(r.cmd.OutOrStdout(), "Line %d skipped: %v\n", o.Record.Line, o.Record.Err)
+		}
+		r.batch.Errors++
 	}
-	defer file.Close()
-
-	// Parse XML
-	byteValue, _ := io.ReadAll(file)
-	var ofx OFX
-	if err := xml.Unmarshal(byteValue, &ofx); err != nil {
-		return fmt.Errorf("failed to parse OFX XML: %w", err)
+	for _, w := range o.Warnings {
+		fmt.Fprintf(r.cmd.OutOrStdout(), "⚠️  %s\n", w)
 	}
+}
 
-	importedCount := 0
-	skippedCount := 0
-	duplicateCount := 0 // Track duplicates
+// finish prints the summary of a stored import.
+func (r *importReport) finish() {
+	out := r.cmd.OutOrStdout()
+	fmt.Fprintf(out, "%s Import complete. %d imported, %d duplicates skipped, %d errors.\n",
+		strings.ToUpper(r.batch.Format), r.batch.Imported, r.batch.Duplicates, r.batch.Errors)
+	if r.rejected > 0 {
+		fmt.Fprintf(out, "%d rejected during the review.\n", r.rejected)
+	}
+	if r.learned > 0 {
+		fmt.Fprintf(out, "%d categorized from history; 'finance categorize --suggest' lists what is left uncategorized.\n", r.learned)
+	}
+	if r.batch.Imported > 0 {
+		fmt.Fprintf(out, "Recorded as import batch %d; 'finance import undo %d' removes it again.\n", r.batch.ID, r.batch.ID)
+	}
+}
 
-	for _, t := range ofx.BankMsgs.StmtTrn.StmtRs.BankTranList.Transactions {
-		// 1. Parse Date (OFX dates look like "20231025120000" or "20231025")
-		date, err := parseOFXDate(t.DtPosted)
-		if err != nil {
-			skippedCount++
-			continue
+// printImportPlan shows what an import would do with every record, and why.
+func printImportPlan(cmd *cobra.Command, format string, statements []importer.Statement, plans [][]importer.Outcome) {
+	counts := map[importer.Status]int{}
+	for i, s := range statements {
+		printStatementHeader(cmd, s)
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STATUS\tDATE\tDESCRIPTION\tCATEGORY\tAMOUNT\tREASON")
+		for _, o := range plans[i] {
+			counts[o.Status]++
+			status := string(o.Status)
+			if o.Status == importer.StatusNew {
+				status = "import"
+			}
+			if t := o.Transaction; t != nil {
+				category := t.Category
+				if o.Learned > 0 {
+					category = fmt.Sprintf("%s (learned, %s)", category, formatConfidence(o.Learned))
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", status, formatDate(t.Date), t.Description, category,
+					formatAmount(t.Amount, t.Currency), o.Reason)
+			} else {
+				fmt.Fprintf(w, "%s\t\t\t\t\t%s\n", status, o.Reason)
+			}
 		}
+		w.Flush()
 
-		// 2. Parse Amount
-		amount, err := strconv.ParseFloat(t.TrnAmt, 64)
-		if err != nil {
-			skippedCount++
-			continue
-		}
+		warnDifference(cmd, s)
+	}
 
-		// 3. Description (Name + Memo)
-		description := strings.TrimSpace(t.Name)
-		if t.Memo != "" {
-			description += " - " + strings.TrimSpace(t.Memo)
-		}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s dry run: %d would be imported, %d duplicates, %d errors. Nothing was written.\n",
+		format, counts[importer.StatusNew], counts[importer.StatusDuplicate], counts[importer.StatusInvalid])
+}
 
-		// 4. Auto-Categorize
-		category := "Uncategorized"
-		if match := models.MatchCategory(rules, description); match != "" {
-			category = match
+// reviewImport opens the review screen on all records of the file.
+func reviewImport(plans [][]importer.Outcome) (bool, error) {
+	var all []*importer.Outcome
+	for i := range plans {
+		for j := range plans[i] {
+			all = append(all, &plans[i][j])
 		}
+	}
 
-		category = models.NormalizeCategory(category)
-		tr := &models.Transaction{Date: date, Description: description, Amount: amount, Category: category}
+	list, err := models.ListCategories(database)
+	if err != nil {
+		return false, fmt.Errorf("failed to load categories: %w", err)
+	}
+	categories := make([]string, len(list))
+	for i, c := range list {
+		categories[i] = c.Path
+	}
+	return tui.ReviewImport(all, categories, settings.DateFormat)
+}
 
-		exists, err := models.TransactionExists(database, tr)
-		if err != nil {
-			return fmt.Errorf("failed to check duplicate: %w", err)
-		}
-		if exists {
-			duplicateCount++
-			continue // Skip
-		}
+func printStatementHeader(cmd *cobra.Command, s importer.Statement) {
+	switch {
+	case s.AccountID != "" && s.AccountType != "":
+		fmt.Fprintf(cmd.OutOrStdout(), "Statement for account %s (%s)\n", s.AccountID, strings.ToLower(s.AccountType))
+	case s.AccountID != "":
+		fmt.Fprintf(cmd.OutOrStdout(), "Statement for account %s\n", s.AccountID)
+	}
+}
 
-		if err := models.CreateTransaction(database, tr); err != nil {
-			skippedCount++
-		} else {
-			importedCount++
-		}
+// warnDifference checks that the statement explains the move from its opening to its
+// closing balance.
+func warnDifference(cmd *cobra.Command, s importer.Statement) {
+	if diff, ok := s.Difference(); ok && diff != 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "⚠️  Opening balance %s plus the transactions doesn't give the closing balance %s (difference %s). The file may be incomplete.\n",
+			formatAmount(s.Opening.Amount, s.Currency), formatAmount(s.Balance.Amount, s.Currency), formatAmount(diff, s.Currency))
 	}
+}
 
-	fmt.Fprintf(cmd.OutOrStdout(), "OFX Import complete. %d imported, %d duplicates skipped, %d errors.\n", importedCount, duplicateCount, skippedCount)
+// hintTransfers points out imported transactions that mirror one in another account.
+func hintTransfers(cmd *cobra.Command, imported []int64) error {
+	if len(imported) == 0 {
+		return nil
+	}
+	pairs, err := models.DetectTransfers(database, models.DefaultTransferWindow, imported)
+	if err != nil {
+		return fmt.Errorf("failed to look for transfers: %w", err)
+	}
+	if len(pairs) > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "%d possible transfer(s) found; run 'finance transfer detect' to review and link them.\n", len(pairs))
+	}
 	return nil
 }
 
-func parseOFXDate(dateStr string) (time.Time, error) {
-	// Usually YYYYMMDD or YYYYMMDDHHMMSS...
-	layout := "20060102"
-	if len(dateStr) >= 8 {
-		return time.Parse(layout, dateStr[:8])
+// csvProfile returns the named import profile or, without a name, the one whose header
+// matches the file. Files that match none are read with the generic layout.
+func csvProfile(cmd *cobra.Command, data []byte, name string) (*models.ImportProfile, error) {
+	if name != "" {
+		return models.GetImportProfile(database, name)
 	}
-	return time.Time{}, fmt.Errorf("invalid date length")
+
+	profiles, err := models.ListImportProfiles(database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load import profiles: %w", err)
+	}
+	if p, ok := importer.DetectProfile(data, profiles); ok {
+		fmt.Fprintf(cmd.OutOrStdout(), "Detected import profile: %s\n", p.Name)
+		return p, nil
+	}
+	return models.GetImportProfile(database, models.DefaultProfile)
+}
+
+// checkBalance compares the balance a bank reported for a statement with the balance of the
+// account it was booked in, on the same day. Without an account, or if the currencies
+// differ, the bank's balance is only shown. If other bank accounts of the file went into
+// the same account (shared), their sum can't be compared with one of them, which is said.
+func checkBalance(cmd *cobra.Command, account *models.Account, s importer.Statement, shared bool) error {
+	bal, currency := *s.Balance, s.Currency
+	fmt.Fprintf(cmd.OutOrStdout(), "Bank balance on %s: %s\n", formatDate(bal.Date), formatAmount(bal.Amount, currency))
+	if account == nil || (currency != "" && currency != account.Currency) {
+		return nil
+	}
+	if shared {
+		fmt.Fprintf(cmd.OutOrStdout(), "   Not compared with %s, which this file's other bank accounts were booked in too.\n", account.Name)
+		return nil
+	}
+
+	own, err := models.GetAccountBalanceOn(database, account.Name, bal.Date)
+	if err != nil {
+		return fmt.Errorf("failed to compute the balance of %s: %w", account.Name, err)
+	}
+	if own != bal.Amount {
+		fmt.Fprintf(cmd.OutOrStdout(), "⚠️  %s has %s on that day (difference %s). Transactions may be missing.\n",
+			account.Name, formatAmount(own, currency), formatAmount(bal.Amount-own, currency))
+	}
+	return nil
 }
 
 func init() {
 	RootCmd.AddCommand(importCmd)
+	importCmd.Flags().String("account", "", "Account the imported transactions belong to (defaults to the configured default_account)")
+	importCmd.Flags().String("profile", "", "CSV layout to read the file with (see 'finance profile list'); detected from the header if omitted")
+	importCmd.Flags().Bool("dry-run", false, "Show what would be imported, and why records are skipped, without writing anything")
+	importCmd.Flags().Bool("review", false, "Accept, reject or recategorize every record in a terminal UI before importing")
+	importCmd.Flags().Bool("force", false, "Import the file even if the same file was imported before")
+	importCmd.Flags().String("format", "", "Read the file in this format (csv, ofx, qif, camt, mt940) instead of detecting it")
+	importCmd.Flags().String("fuzzy", "", "How to match duplicates from other sources: on, e.g. days=3,amount=0.50,similarity=0.5, or off (defaults to the fuzzy_dedup setting)")
+	importCmd.Flags().String("confidence", "", "How likely a category learned from history must be to be taken, e.g. 0.8, or off (defaults to the classifier_confidence setting)")
 }
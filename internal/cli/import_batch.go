@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var importListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List past imports",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		batches, err := models.ListImportBatches(database)
+		if err != nil {
+			return fmt.Errorf("failed to list imports: %w", err)
+		}
+		if len(batches) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No imports found.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tIMPORTED AT\tFILE\tFORMAT\tACCOUNT\tNEW\tDUPLICATES\tERRORS")
+		for _, b := range batches {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%d\t%d\n", b.ID, formatBatchTime(b), b.FileName, b.Format, b.Account,
+				b.Imported, b.Duplicates, b.Errors)
+		}
+		return w.Flush()
+	},
+}
+
+var importShowCmd = &cobra.Command{
+	Use:   "show [batch]",
+	Short: "Show an import and the transactions it added",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		b, err := models.GetImportBatch(database, id)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "Import batch %d\n", b.ID)
+		fmt.Fprintf(out, "File:     %s (%s)\n", b.FileName, b.Format)
+		fmt.Fprintf(out, "SHA-256:  %s\n", b.FileHash)
+		fmt.Fprintf(out, "Imported: %s\n", formatBatchTime(*b))
+		if b.Account != "" {
+			fmt.Fprintf(out, "Account:  %s\n", b.Account)
+		}
+		fmt.Fprintf(out, "Result:   %d imported, %d duplicates skipped, %d errors\n", b.Imported, b.Duplicates, b.Errors)
+
+		transactions, err := models.FindTransactions(database, models.TransactionFilter{Batch: b.ID})
+		if err != nil {
+			return fmt.Errorf("failed to list transactions: %w", err)
+		}
+		if len(transactions) == 0 {
+			fmt.Fprintln(out, "\nNo transactions left from this import.")
+			return nil
+		}
+
+		fmt.Fprintln(out)
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tLINE\tDATE\tAMOUNT\tCATEGORY\tDESCRIPTION")
+		for _, t := range transactions {
+			line := ""
+			if t.SourceLine > 0 {
+				line = strconv.Itoa(t.SourceLine)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", t.ID, line, formatDate(t.Date),
+				formatAmount(t.Amount, t.Currency), t.Category, t.Description)
+		}
+		return w.Flush()
+	},
+}
+
+var importUndoCmd = &cobra.Command{
+	Use:   "undo [batch]",
+	Short: "Delete the transactions an import added",
+	Long: `Deletes every transaction added by the import, with its split lines and tags, and
+forgets the import, so the file can be imported again. Transfers with a transaction of
+the import are unlinked; the other leg is kept.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+
+		n, err := models.UndoImportBatch(database, id)
+		if err != nil {
+			return fmt.Errorf("failed to undo import: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Import batch %d undone, %d transaction(s) deleted.\n", id, n)
+		return nil
+	},
+}
+
+// formatBatchTime shows when an import ran, in local time.
+func formatBatchTime(b models.ImportBatch) string {
+	t := b.CreatedAt.Local()
+	return formatDate(t) + " " + t.Format("15:04")
+}
+
+func init() {
+	importCmd.AddCommand(importListCmd, importShowCmd, importUndoCmd)
+}
@@ -12,7 +12,18 @@ var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all transactions",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		transactions, err := models.ListTransactions(database)
+		accountRaw, _ := cmd.Flags().GetString("account")
+		account, err := accountFilter(accountRaw)
+		if err != nil {
+			return err
+		}
+		tagRaw, _ := cmd.Flags().GetString("tag")
+		tag, err := tagFilter(tagRaw)
+		if err != nil {
+			return err
+		}
+
+		transactions, err := models.FindTransactions(database, models.TransactionFilter{Account: account, Tag: tag})
 		if err != nil {
 			return fmt.Errorf("failed to list transactions: %w", err)
 		}
@@ -23,14 +34,16 @@ var listCmd = &cobra.Command{
 		}
 
 		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tDATE\tAMOUNT\tCATEGORY\tDESCRIPTION")
+		fmt.Fprintln(w, "ID\tDATE\tAMOUNT\tCATEGORY\tACCOUNT\tPAYEE\tDESCRIPTION")
 
 		for _, t := range transactions {
-			fmt.Fprintf(w, "%d\t%s\t%.2f\t%s\t%s\n",
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
 				t.ID,
-				t.Date.Format("2006-01-02"),
-				t.Amount,
+				formatDate(t.Date),
+				formatAmount(t.Amount, t.Currency),
 				t.Category,
+				t.Account,
+				t.Payee,
 				t.Description,
 			)
 		}
@@ -40,4 +53,6 @@ var listCmd = &cobra.Command{
 
 func init() {
 	RootCmd.AddCommand(listCmd)
+	listCmd.Flags().String("account", "", "Only show transactions of this account")
+	listCmd.Flags().String("tag", "", "Only show transactions with this tag")
 }
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/config"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage the CSV layouts ('import profiles') used by import",
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the built-in and your own import profiles",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := models.ListImportProfiles(database)
+		if err != nil {
+			return fmt.Errorf("failed to list import profiles: %w", err)
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tDELIMITER\tDATE FORMAT\tDECIMAL\tSIGN\t")
+		for _, p := range profiles {
+			builtIn := ""
+			if p.BuiltIn {
+				builtIn = "(built-in)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Name, delimiterName(p.Delimiter), p.DateLayout, p.Decimal, p.Sign, builtIn)
+		}
+		return w.Flush()
+	},
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show which columns a profile reads",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := models.GetImportProfile(database, args[0])
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Name:\t%s\n", p.Name)
+		fmt.Fprintf(w, "Delimiter:\t%s\n", delimiterName(p.Delimiter))
+		fmt.Fprintf(w, "Header row:\t%t\n", p.Header)
+		if p.SkipRows > 0 {
+			fmt.Fprintf(w, "Skipped rows:\t%d\n", p.SkipRows)
+		}
+		fmt.Fprintf(w, "Date:\t%s (%s)\n", p.Date, p.DateLayout)
+		fmt.Fprintf(w, "Description:\t%s\n", strings.Join(p.Description, " + "))
+		switch p.Sign {
+		case models.SignDebitCredit:
+			fmt.Fprintf(w, "Debit:\t%s\n", p.Debit)
+			fmt.Fprintf(w, "Credit:\t%s\n", p.Credit)
+		default:
+			fmt.Fprintf(w, "Amount:\t%s (%s)\n", p.Amount, p.Sign)
+		}
+		fmt.Fprintf(w, "Decimal separator:\t%s\n", p.Decimal)
+		if p.Category != "" {
+			fmt.Fprintf(w, "Category:\t%s\n", p.Category)
+		}
+		if p.Currency != "" {
+			fmt.Fprintf(w, "Currency:\t%s\n", p.Currency)
+		}
+		if p.Account != "" {
+			fmt.Fprintf(w, "Account:\t%s\n", p.Account)
+		}
+		if p.Transfer != "" {
+			fmt.Fprintf(w, "Transfer:\t%s\n", p.Transfer)
+		}
+		return w.Flush()
+	},
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add [name]",
+	Short: "Save the CSV layout of your bank's export (replaces a profile with the same name)",
+	Example: `finance profile add mybank --delimiter ";" --date-column Buchungsdatum --description-column Empfaenger \
+    --description-column Verwendungszweck --amount-column Betrag --date-format DD.MM.YYYY --decimal ,`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		delimiter, _ := cmd.Flags().GetString("delimiter")
+		header, _ := cmd.Flags().GetBool("header")
+		skip, _ := cmd.Flags().GetInt("skip-rows")
+		dateCol, _ := cmd.Flags().GetString("date-column")
+		descCols, _ := cmd.Flags().GetStringArray("description-column")
+		amountCol, _ := cmd.Flags().GetString("amount-column")
+		debitCol, _ := cmd.Flags().GetString("debit-column")
+		creditCol, _ := cmd.Flags().GetString("credit-column")
+		categoryCol, _ := cmd.Flags().GetString("category-column")
+		currencyCol, _ := cmd.Flags().GetString("currency-column")
+		accountCol, _ := cmd.Flags().GetString("account-column")
+		transferCol, _ := cmd.Flags().GetString("transfer-column")
+		dateFormat, _ := cmd.Flags().GetString("date-format")
+		decimal, _ := cmd.Flags().GetString("decimal")
+		sign, _ := cmd.Flags().GetString("sign")
+
+		layout, err := config.DateLayout(dateFormat)
+		if err != nil {
+			return err
+		}
+		// Separate debit and credit columns imply the matching convention
+		if !cmd.Flags().Changed("sign") && (debitCol != "" || creditCol != "") {
+			sign = models.SignDebitCredit
+		}
+		if delimiter == "tab" || delimiter == `\t` {
+			delimiter = "\t"
+		}
+
+		p := &models.ImportProfile{
+			Name: args[0], Delimiter: delimiter, Header: header, SkipRows: skip,
+			Date: dateCol, Description: descCols, Amount: amountCol, Debit: debitCol, Credit: creditCol,
+			Category: categoryCol, Currency: currencyCol, Account: accountCol, Transfer: transferCol, DateLayout: layout, Decimal: decimal, Sign: strings.ToLower(sign),
+		}
+		if err := models.SaveImportProfile(database, p); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Import profile '%s' saved. Use it with 'finance import <file> --profile %s'.\n", p.Name, p.Name)
+		return nil
+	},
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Remove one of your import profiles",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := models.DeleteImportProfile(database, args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Import profile '%s' removed.\n", args[0])
+		return nil
+	},
+}
+
+// delimiterName makes invisible delimiters readable in tables.
+func delimiterName(d string) string {
+	if d == "\t" {
+		return "tab"
+	}
+	return d
+}
+
+func init() {
+	RootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd, profileShowCmd, profileAddCmd, profileRemoveCmd)
+
+	profileAddCmd.Flags().String("delimiter", ",", "Field separator, a single character or 'tab'")
+	profileAddCmd.Flags().Bool("header", true, "The file starts with a row of column names (use --header=false if not)")
+	profileAddCmd.Flags().Int("skip-rows", 0, "Lines to skip before the header or the first transaction")
+	profileAddCmd.Flags().String("date-column", "", "Column of the booking date: its header text or 1-based position")
+	profileAddCmd.Flags().StringArray("description-column", nil, "Column of the description; repeat to join several columns")
+	profileAddCmd.Flags().String("amount-column", "", "Column of the amount")
+	profileAddCmd.Flags().String("debit-column", "", "Column of money going out (implies --sign debit-credit)")
+	profileAddCmd.Flags().String("credit-column", "", "Column of money coming in (implies --sign debit-credit)")
+	profileAddCmd.Flags().String("category-column", "", "Column of the category, if the export has one")
+	profileAddCmd.Flags().String("currency-column", "", "Column of the currency, if the export has one")
+	profileAddCmd.Flags().String("account-column", "", "Column naming the account of each row, for exports of several accounts")
+	profileAddCmd.Flags().String("transfer-column", "", "Column naming the other account of a transfer")
+	profileAddCmd.Flags().String("date-format", "YYYY-MM-DD", "Date format, e.g. DD.MM.YYYY, MM/DD/YYYY or a Go layout")
+	profileAddCmd.Flags().String("decimal", ".", "Decimal separator: '.' or ','")
+	profileAddCmd.Flags().String("sign", models.SignSigned, "Amount convention: "+strings.Join(models.SignConventions, ", "))
+	profileAddCmd.MarkFlagRequired("date-column")
+	profileAddCmd.MarkFlagRequired("description-column")
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var ratesCmd = &cobra.Command{
+	Use:   "rates",
+	Short: "Manage currency exchange rates",
+}
+
+var ratesSetCmd = &cobra.Command{
+	Use:     "set [base] [quote] [rate]",
+	Short:   "Record that 1 unit of base was worth rate units of quote",
+	Example: "finance rates set EUR RON 4.9765 --date 2024-05-03",
+	Args:    cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dateStr, _ := cmd.Flags().GetString("date")
+
+		date := time.Now()
+		if dateStr != "" {
+			var err error
+			date, err = time.Parse("2006-01-02", dateStr)
+			if err != nil {
+				return fmt.Errorf("invalid date format (use YYYY-MM-DD): %w", err)
+			}
+		}
+
+		r := &models.ExchangeRate{Date: date, Base: args[0], Quote: args[1], Rate: args[2]}
+		if err := models.SetRate(database, r); err != nil {
+			return fmt.Errorf("failed to set rate: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Rate set: 1 %s = %s %s on %s\n", r.Base, r.Rate, r.Quote, r.Date.Format("2006-01-02"))
+		return nil
+	},
+}
+
+var ratesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded exchange rates (newest first)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		currencyRaw, _ := cmd.Flags().GetString("currency")
+		currency, err := models.NormalizeCurrency(currencyRaw)
+		if err != nil {
+			return err
+		}
+
+		rates, err := models.ListRates(database, currency)
+		if err != nil {
+			return fmt.Errorf("failed to list rates: %w", err)
+		}
+
+		if len(rates) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No exchange rates found.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tDATE\tBASE\tQUOTE\tRATE")
+		for _, r := range rates {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.ID, r.Date.Format("2006-01-02"), r.Base, r.Quote, r.Rate)
+		}
+		return w.Flush()
+	},
+}
+
+var ratesRemoveCmd = &cobra.Command{
+	Use:   "remove [id]",
+	Short: "Remove an exchange rate by ID",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ID: %s", args[0])
+		}
+
+		if err := models.DeleteRate(database, id); err != nil {
+			return fmt.Errorf("failed to remove rate: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Rate %d removed successfully.\n", id)
+		return nil
+	},
+}
+
+var ratesImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import exchange rates from a CSV or ECB XML file",
+	Long: `Imports exchange rates from a file.
+
+CSV files have the columns Date,Base,Quote,Rate (a header row is optional), e.g.
+  2024-05-03,EUR,RON,4.9765
+
+XML files use the format of the ECB euro reference rates
+(eurofxref-daily.xml / eurofxref-hist.xml); all of their rates are EUR-based.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath := args[0]
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+
+		var rates []models.ExchangeRate
+		switch ext := strings.ToLower(filepath.Ext(filePath)); ext {
+		case ".csv":
+			rates, err = parseRatesCSV(file)
+		case ".xml":
+			rates, err = parseECBRates(file)
+		default:
+			return fmt.Errorf("unsupported file format '%s'. Please use .csv or .xml", ext)
+		}
+		if err != nil {
+			return err
+		}
+
+		for i := range rates {
+			if err := models.SetRate(database, &rates[i]); err != nil {
+				return fmt.Errorf("rate %d (%s/%s): %w", i+1, rates[i].Base, rates[i].Quote, err)
+			}
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Rates import complete. %d rates stored.\n", len(rates))
+		return nil
+	},
+}
+
+func parseRatesCSV(r io.Reader) ([]models.ExchangeRate, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV data: %w", err)
+	}
+
+	var rates []models.ExchangeRate
+	for i, record := range records {
+		if len(record) < 4 {
+			return nil, fmt.Errorf("row %d: expected Date,Base,Quote,Rate", i+1)
+		}
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			if i == 0 {
+				continue
+			} // Skip header
+			return nil, fmt.Errorf("row %d: invalid date", i+1)
+		}
+		rates = append(rates, models.ExchangeRate{
+			Date:  date,
+			Base:  record[1],
+			Quote: record[2],
+			Rate:  record[3],
+		})
+	}
+	return rates, nil
+}
+
+// ECBEnvelope mirrors the ECB reference rate XML:
+// <Cube><Cube time="2024-05-03"><Cube currency="USD" rate="1.0765"/>...</Cube></Cube>
+type ECBEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+func parseECBRates(r io.Reader) ([]models.ExchangeRate, error) {
+	var env ECBEnvelope
+	if err := xml.NewDecoder(r).Decode(&env); err != nil {
+		return nil, fmt.Errorf("failed to parse ECB XML: %w", err)
+	}
+
+	var rates []models.ExchangeRate
+	for _, day := range env.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ECB date %q", day.Time)
+		}
+		for _, c := range day.Rates {
+			rates = append(rates, models.ExchangeRate{Date: date, Base: "EUR", Quote: c.Currency, Rate: c.Rate})
+		}
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("no rates found in ECB XML")
+	}
+	return rates, nil
+}
+
+func init() {
+	RootCmd.AddCommand(ratesCmd)
+	ratesCmd.AddCommand(ratesSetCmd)
+	ratesCmd.AddCommand(ratesListCmd)
+	ratesCmd.AddCommand(ratesRemoveCmd)
+	ratesCmd.AddCommand(ratesImportCmd)
+
+	ratesSetCmd.Flags().StringP("date", "t", "", "Date the rate applies from (YYYY-MM-DD), defaults to today")
+	ratesListCmd.Flags().String("currency", "", "Only show rates involving this currency")
+}
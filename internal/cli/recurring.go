@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var recurringCmd = &cobra.Command{
+	Use:   "recurring",
+	Short: "Manage transactions that repeat on a schedule",
+	Long: `Recurring templates describe transactions such as rent, salary or subscriptions.
+'recurring run' books every occurrence that is due; run it regularly (e.g. from cron).`,
+}
+
+var recurringAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Create a recurring template",
+	Example: `finance recurring add --desc Rent --amount -950 --category Housing --day 1
+finance recurring add --desc "Gym" --amount -30 --frequency weekly --interval 2 --start 2024-05-06
+finance recurring add --desc "Car insurance" --amount -480 --frequency yearly --start 2024-03-15 --end 2027-03-15`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		desc, _ := cmd.Flags().GetString("desc")
+		amountStr, _ := cmd.Flags().GetString("amount")
+		catRaw, _ := cmd.Flags().GetString("category")
+		currencyRaw, _ := cmd.Flags().GetString("currency")
+		frequency, _ := cmd.Flags().GetString("frequency")
+		interval, _ := cmd.Flags().GetInt("interval")
+		day, _ := cmd.Flags().GetInt("day")
+		startStr, _ := cmd.Flags().GetString("start")
+		endStr, _ := cmd.Flags().GetString("end")
+
+		amount, err := models.ParseMoney(amountStr)
+		if err != nil {
+			return err
+		}
+
+		account, err := models.ResolveOpenAccount(database, accountFlagOrDefault(cmd))
+		if err != nil {
+			return err
+		}
+		currency, err := models.NormalizeCurrency(currencyRaw)
+		if err != nil {
+			return err
+		}
+
+		r := &models.Recurring{
+			Description: desc,
+			Amount:      amount,
+			Category:    models.NormalizeCategory(catRaw),
+			Currency:    currency,
+			Frequency:   strings.ToLower(frequency),
+			Interval:    interval,
+			Day:         day,
+			Start:       time.Now(),
+		}
+		if account != nil {
+			r.Account = account.Name
+			if r.Currency == "" {
+				r.Currency = account.Currency
+			}
+		}
+		if startStr != "" {
+			if r.Start, err = parseDate(startStr); err != nil {
+				return err
+			}
+		}
+		if endStr != "" {
+			if r.End, err = parseDate(endStr); err != nil {
+				return err
+			}
+		}
+
+		if err := models.CreateRecurring(database, r); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Recurring transaction '%s' created (ID: %d), %s.\n", r.Description, r.ID, r.Schedule())
+		return nil
+	},
+}
+
+var recurringListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recurring templates and their next occurrence",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		templates, err := models.ListRecurring(database)
+		if err != nil {
+			return fmt.Errorf("failed to list recurring transactions: %w", err)
+		}
+		if len(templates) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No recurring transactions.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tDESCRIPTION\tAMOUNT\tCATEGORY\tACCOUNT\tSCHEDULE\tNEXT")
+		for _, r := range templates {
+			next := "-"
+			if dates := r.Occurrences(nextAfter(r), time.Now().AddDate(10, 0, 0)); len(dates) > 0 {
+				next = formatDate(dates[0])
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.ID, r.Description, formatAmount(r.Amount, r.Currency), r.Category, r.Account, schedule(r), next)
+		}
+		return w.Flush()
+	},
+}
+
+var recurringRemoveCmd = &cobra.Command{
+	Use:   "remove [id]",
+	Short: "Remove a recurring template (booked transactions stay)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if err := models.DeleteRecurring(database, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Recurring transaction %d removed.\n", id)
+		return nil
+	},
+}
+
+var recurringRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Book every recurring transaction that is due",
+	Long: `Creates the transactions of all occurrences up to today (or --until). Occurrences
+that already exist, e.g. because they were added by hand, are skipped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		untilStr, _ := cmd.Flags().GetString("until")
+
+		until := time.Now()
+		if untilStr != "" {
+			var err error
+			if until, err = parseDate(untilStr); err != nil {
+				return err
+			}
+		}
+
+		run, err := models.RunRecurring(database, until)
+		if err != nil {
+			return fmt.Errorf("failed to run recurring transactions: %w", err)
+		}
+		for _, t := range run.Created {
+			fmt.Fprintf(cmd.OutOrStdout(), "Added %s  %-25s %s\n", formatDate(t.Date), t.Description, formatAmount(t.Amount, t.Currency))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%d recurring transaction(s) added, %d already existed.\n", len(run.Created), run.Skipped)
+		return nil
+	},
+}
+
+var recurringUpcomingCmd = &cobra.Command{
+	Use:   "upcoming",
+	Short: "Show the occurrences of the next days",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		days, _ := cmd.Flags().GetInt("days")
+
+		now := time.Now()
+		upcoming, err := models.UpcomingOccurrences(database, now, now.AddDate(0, 0, days))
+		if err != nil {
+			return fmt.Errorf("failed to compute upcoming transactions: %w", err)
+		}
+		if len(upcoming) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "Nothing scheduled in the next %d days.\n", days)
+			return nil
+		}
+		return printOccurrences(cmd, upcoming)
+	},
+}
+
+// printOccurrences lists scheduled occurrences with their total.
+func printOccurrences(cmd *cobra.Command, occurrences []models.Occurrence) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATE\tDESCRIPTION\tAMOUNT\tCATEGORY\tACCOUNT")
+	for _, o := range occurrences {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			formatDate(o.Date), o.Recurring.Description, formatAmount(o.Recurring.Amount, o.Recurring.Currency),
+			o.Recurring.Category, o.Recurring.Account)
+	}
+	return w.Flush()
+}
+
+// schedule describes a template's rule including its end date.
+func schedule(r models.Recurring) string {
+	if r.End.IsZero() {
+		return r.Schedule()
+	}
+	return r.Schedule() + " until " + formatDate(r.End)
+}
+
+// nextAfter is the first date a run hasn't booked yet.
+func nextAfter(r models.Recurring) time.Time {
+	if r.LastRun.IsZero() {
+		return r.Start
+	}
+	return r.LastRun.AddDate(0, 0, 1)
+}
+
+func init() {
+	RootCmd.AddCommand(recurringCmd)
+	recurringCmd.AddCommand(recurringAddCmd)
+	recurringCmd.AddCommand(recurringListCmd)
+	recurringCmd.AddCommand(recurringRemoveCmd)
+	recurringCmd.AddCommand(recurringRunCmd)
+	recurringCmd.AddCommand(recurringUpcomingCmd)
+
+	recurringAddCmd.Flags().StringP("desc", "d", "", "Description of the booked transactions")
+	recurringAddCmd.Flags().StringP("amount", "a", "", "Amount (positive for income, negative for expense)")
+	recurringAddCmd.Flags().StringP("category", "c", "Uncategorized", "Category of the booked transactions")
+	recurringAddCmd.Flags().String("account", "", "Account (defaults to the configured default_account)")
+	recurringAddCmd.Flags().String("currency", "", "Currency code (defaults to the account's currency)")
+	recurringAddCmd.Flags().StringP("frequency", "f", models.Monthly, "How often: "+strings.Join(models.Frequencies, ", "))
+	recurringAddCmd.Flags().Int("interval", 1, "Repeat every N days/weeks/months/years")
+	recurringAddCmd.Flags().Int("day", 0, "Day of the month for monthly schedules (default the start date's day)")
+	recurringAddCmd.Flags().String("start", "", "First possible date (default today)")
+	recurringAddCmd.Flags().String("end", "", "Last possible date (default none)")
+	recurringAddCmd.MarkFlagRequired("desc")
+	recurringAddCmd.MarkFlagRequired("amount")
+
+	recurringRunCmd.Flags().String("until", "", "Book occurrences up to this date (default today)")
+	recurringUpcomingCmd.Flags().Int("days", 30, "How many days ahead to look")
+}
@@ -10,8 +10,12 @@ import (
 )
 
 var (
-	reportYear  int
-	reportMonth int
+	reportYear     int
+	reportMonth    int
+	reportAccount  string
+	reportTag      string
+	reportDepth    int
+	reportUpcoming bool
 )
 
 var reportCmd = &cobra.Command{
@@ -27,52 +31,122 @@ var reportCmd = &cobra.Command{
 			reportMonth = int(now.Month())
 		}
 
-		breakdown, income, expense, err := models.GetMonthlyReport(database, reportYear, reportMonth)
+		account, err := accountFilter(reportAccount)
+		if err != nil {
+			return err
+		}
+		tag, err := tagFilter(reportTag)
+		if err != nil {
+			return err
+		}
+
+		conv, err := newConverter()
+		if err != nil {
+			return err
+		}
+
+		breakdown, income, expense, err := models.GetMonthlyReport(database, reportYear, reportMonth, models.TransactionFilter{Account: account, Tag: tag}, conv)
 		if err != nil {
 			return fmt.Errorf("failed to generate report: %w", err)
 		}
 
-		fmt.Fprintf(cmd.OutOrStdout(), "\n=== Report for %04d-%02d ===\n\n", reportYear, reportMonth)
-		fmt.Fprintf(cmd.OutOrStdout(), "Total Income:   %10.2f\n", income)
-		fmt.Fprintf(cmd.OutOrStdout(), "Total Expenses: %10.2f\n", expense)
-		fmt.Fprintf(cmd.OutOrStdout(), "Net Savings:    %10.2f\n", income+expense)
+		fmt.Fprintf(cmd.OutOrStdout(), "\n=== Report for %04d-%02d ===\n", reportYear, reportMonth)
+		if account != "" {
+			fmt.Fprintf(cmd.OutOrStdout(), "Account: %s\n", account)
+		}
+		if tag != "" {
+			fmt.Fprintf(cmd.OutOrStdout(), "Tag: #%s\n", tag)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Amounts in %s\n", conv.Base())
+		fmt.Fprintln(cmd.OutOrStdout())
+		fmt.Fprintf(cmd.OutOrStdout(), "Total Income:   %10s\n", income)
+		fmt.Fprintf(cmd.OutOrStdout(), "Total Expenses: %10s\n", expense)
+		fmt.Fprintf(cmd.OutOrStdout(), "Net Savings:    %10s\n", income+expense)
 		fmt.Fprintln(cmd.OutOrStdout(), "\n--- Category Breakdown ---")
 
 		if len(breakdown) == 0 {
 			fmt.Fprintln(cmd.OutOrStdout(), "No transactions found for this month.")
-			return nil
-		}
+		} else {
+			// Subcategories roll up into their parents
+			tree := models.BuildCategoryTree(breakdown)
 
-		// Calculate max absolute amount for scaling the bar chart
-		var maxAmount float64
-		for _, b := range breakdown {
-			if abs(b.Amount) > maxAmount {
-				maxAmount = abs(b.Amount)
+			// Calculate max absolute amount for scaling the bar chart
+			var maxAmount models.Money
+			for _, n := range tree {
+				if n.Total.Abs() > maxAmount {
+					maxAmount = n.Total.Abs()
+				}
 			}
+
+			printCategoryTree(cmd, tree, 0, reportDepth, maxAmount)
+		}
+
+		if reportUpcoming {
+			return printUpcomingInMonth(cmd, reportYear, time.Month(reportMonth), account)
 		}
+		return nil
+	},
+}
 
-		// Draw simple ASCII bars
-		for _, b := range breakdown {
-			barLength := int((abs(b.Amount) / maxAmount) * 20) // Scale to max 20 chars
-			bar := strings.Repeat("█", barLength)
+// printUpcomingInMonth lists the recurring transactions of the month that haven't been booked yet.
+func printUpcomingInMonth(cmd *cobra.Command, year int, month time.Month, account string) error {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	upcoming, err := models.UpcomingOccurrences(database, first, first.AddDate(0, 1, -1))
+	if err != nil {
+		return fmt.Errorf("failed to compute upcoming transactions: %w", err)
+	}
 
-			// Adjust spacing for alignment
-			fmt.Fprintf(cmd.OutOrStdout(), "%-20s [%-20s] %10.2f\n", b.Category, bar, b.Amount)
+	var list []models.Occurrence
+	for _, o := range upcoming {
+		if account == "" || o.Recurring.Account == account {
+			list = append(list, o)
 		}
+	}
 
+	fmt.Fprintln(cmd.OutOrStdout(), "\n--- Upcoming Recurring ---")
+	if len(list) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Nothing else scheduled this month.")
 		return nil
-	},
+	}
+	return printOccurrences(cmd, list)
 }
 
-func abs(x float64) float64 {
-	if x < 0 {
-		return -x
+// printCategoryTree draws one ASCII bar per category, children indented below their parent.
+// Levels deeper than maxDepth (0 = unlimited) are collapsed into their parent's total.
+func printCategoryTree(cmd *cobra.Command, nodes []*models.CategoryNode, level, maxDepth int, maxAmount models.Money) {
+	for _, n := range nodes {
+		barLength := 0
+		if maxAmount > 0 {
+			barLength = int(n.Total.Abs() * 20 / maxAmount) // Scale to max 20 chars
+		}
+		bar := strings.Repeat("█", barLength)
+
+		name := strings.Repeat("  ", level) + n.Name
+		collapsed := len(n.Children) > 0 && maxDepth > 0 && level+1 >= maxDepth
+		if collapsed {
+			name += " (+)"
+		}
+
+		// Adjust spacing for alignment
+		fmt.Fprintf(cmd.OutOrStdout(), "%-20s [%-20s] %10s\n", name, bar, n.Total)
+
+		if len(n.Children) > 0 && !collapsed {
+			// Show what the parent itself was charged with, so the children add up
+			if n.Own != 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "%-20s [%-20s] %10s\n", strings.Repeat("  ", level+1)+"(other)", "", n.Own)
+			}
+			printCategoryTree(cmd, n.Children, level+1, maxDepth, maxAmount)
+		}
 	}
-	return x
 }
 
 func init() {
 	RootCmd.AddCommand(reportCmd)
 	reportCmd.Flags().IntVarP(&reportYear, "year", "y", 0, "Year of report (default current year)")
 	reportCmd.Flags().IntVarP(&reportMonth, "month", "m", 0, "Month of report (default current month)")
+	reportCmd.Flags().StringVar(&reportAccount, "account", "", "Only report on this account")
+	reportCmd.Flags().StringVar(&reportTag, "tag", "", "Only report on transactions with this tag")
+	reportCmd.Flags().BoolVar(&reportUpcoming, "upcoming", false, "Also list the recurring transactions of the month not booked yet")
+	reportCmd.Flags().IntVar(&reportDepth, "depth", 0, "Collapse subcategories below this level into their parents (0 shows the whole tree)")
+	reportCmd.Flags().String("base", "", "Currency to convert amounts into (default $FINANCE_BASE_CURRENCY or "+models.DefaultBaseCurrency+")")
 }
@@ -2,25 +2,63 @@ package cli
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/SebiGabor/personal-finance-cli/internal/config"
 	"github.com/SebiGabor/personal-finance-cli/internal/db"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
 	"github.com/spf13/cobra"
 )
 
 var database *sql.DB
 
+// Settings are the defaults every command works with, resolved once per run by loadSettings.
+type Settings struct {
+	DBPath         string
+	BaseCurrency   string
+	DateFormat     string // Go layout
+	DefaultAccount string
+	FuzzyDedup     models.FuzzyMatch // how imports and 'duplicates' match across sources
+	Confidence     float64           // a learned category needs this probability; 0 (the default) turns learning off
+
+	// Sources records where each value came from ("flag", "env", "config" or "default").
+	Sources map[string]string
+}
+
+var settings = Settings{DateFormat: "2006-01-02", Sources: map[string]string{}}
+
+// legacyDBPath is where versions without configuration kept their database.
+const legacyDBPath = "finance.db"
+
 // RootCmd is the base command for the application
 var RootCmd = &cobra.Command{
 	Use:   "finance",
 	Short: "Personal Finance CLI Manager",
-	Long:  `A command-line tool for tracking personal income and expenses.`,
+	Long: `A command-line tool for tracking personal income and expenses.
+
+Settings are resolved in this order, the first one found wins:
+  1. command-line flags (--db, --base, --account, --fuzzy, --confidence)
+  2. environment variables (FINANCE_DB, FINANCE_BASE_CURRENCY,
+     FINANCE_DATE_FORMAT, FINANCE_ACCOUNT, FINANCE_FUZZY_DEDUP,
+     FINANCE_CLASSIFIER_CONFIDENCE)
+  3. the config file (see 'finance config path'; override with FINANCE_CONFIG)
+  4. built-in defaults: the database lives in $XDG_DATA_HOME/finance/finance.db
+     (~/.local/share/finance/finance.db), the base currency is EUR and dates
+     are shown as YYYY-MM-DD. Imports only skip exact duplicates (fuzzy_dedup
+     off), and only rules categorize new transactions (classifier_confidence
+     off).`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadSettings(cmd); err != nil {
+			return err
+		}
 		// If the database isn't already set (e.g. by a test), connect to the production DB
 		if database == nil {
 			var err error
-			database, err = db.Connect()
+			database, err = db.Connect(settings.DBPath)
 			if err != nil {
 				return fmt.Errorf("could not connect to database: %w", err)
 			}
@@ -29,6 +67,141 @@ var RootCmd = &cobra.Command{
 	},
 }
 
+// loadSettings resolves Settings from flags, environment, config file and defaults.
+func loadSettings(cmd *cobra.Command) error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	s := Settings{Sources: map[string]string{}}
+	pick := func(key, flagValue, envName, configValue, defaultValue string) string {
+		switch {
+		case flagValue != "":
+			s.Sources[key] = "flag"
+			return flagValue
+		case os.Getenv(envName) != "":
+			s.Sources[key] = "env " + envName
+			return os.Getenv(envName)
+		case configValue != "":
+			s.Sources[key] = "config"
+			return configValue
+		default:
+			s.Sources[key] = "default"
+			return defaultValue
+		}
+	}
+
+	dbFlag, _ := cmd.Flags().GetString("db")
+	defaultDB, err := defaultDBPath()
+	if err != nil {
+		return err
+	}
+	s.DBPath = pick("db", dbFlag, "FINANCE_DB", cfg.DB, defaultDB)
+
+	s.BaseCurrency = pick("base_currency", changedFlag(cmd, "base"), "FINANCE_BASE_CURRENCY", cfg.BaseCurrency, models.DefaultBaseCurrency)
+	if s.BaseCurrency, err = models.NormalizeCurrency(s.BaseCurrency); err != nil {
+		return fmt.Errorf("base currency (%s): %w", s.Sources["base_currency"], err)
+	}
+
+	format := pick("date_format", "", "FINANCE_DATE_FORMAT", cfg.DateFormat, "YYYY-MM-DD")
+	if s.DateFormat, err = config.DateLayout(format); err != nil {
+		return fmt.Errorf("date format (%s): %w", s.Sources["date_format"], err)
+	}
+
+	// Only commands that record transactions have an --account flag that overrides the default
+	s.DefaultAccount = pick("default_account", "", "FINANCE_ACCOUNT", cfg.DefaultAccount, "")
+
+	fuzzy := pick("fuzzy_dedup", changedFlag(cmd, "fuzzy"), "FINANCE_FUZZY_DEDUP", cfg.FuzzyDedup, "off")
+	if s.FuzzyDedup, err = models.ParseFuzzyMatch(fuzzy); err != nil {
+		return fmt.Errorf("fuzzy duplicate matching (%s): %w", s.Sources["fuzzy_dedup"], err)
+	}
+
+	confidence := pick("classifier_confidence", changedFlag(cmd, "confidence"), "FINANCE_CLASSIFIER_CONFIDENCE",
+		cfg.ClassifierConfidence, "off")
+	if s.Confidence, err = models.ParseConfidence(confidence); err != nil {
+		return fmt.Errorf("classifier confidence (%s): %w", s.Sources["classifier_confidence"], err)
+	}
+
+	settings = s
+	return nil
+}
+
+// defaultDBPath prefers the XDG data directory. A finance.db in the current directory,
+// where older versions created it, is still used as long as the new location is empty.
+func defaultDBPath() (string, error) {
+	path, err := config.DefaultDBPath()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if _, err := os.Stat(legacyDBPath); err == nil {
+			return legacyDBPath, nil
+		}
+	}
+	return path, nil
+}
+
+// changedFlag returns the value of a flag the user explicitly set on cmd, or "".
+func changedFlag(cmd *cobra.Command, name string) string {
+	if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+		return f.Value.String()
+	}
+	return ""
+}
+
+// accountFlagOrDefault returns the --account flag, falling back to the configured default account.
+func accountFlagOrDefault(cmd *cobra.Command) string {
+	if account := changedFlag(cmd, "account"); account != "" {
+		return account
+	}
+	return settings.DefaultAccount
+}
+
+// newConverter builds the currency converter for commands that show totals,
+// using the resolved base currency.
+func newConverter() (*models.Converter, error) {
+	return models.NewConverter(database, settings.BaseCurrency)
+}
+
+// formatAmount prints an amount followed by its currency code, if it has one.
+func formatAmount(amount models.Money, currency string) string {
+	if currency == "" {
+		return amount.String()
+	}
+	return amount.String() + " " + currency
+}
+
+// formatDate prints a date in the configured date format.
+func formatDate(t time.Time) string {
+	return t.Format(settings.DateFormat)
+}
+
+// parseDate reads a date given on the command line, in the configured format or as YYYY-MM-DD.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(settings.DateFormat, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use %s or YYYY-MM-DD)", s, time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC).Format(settings.DateFormat))
+	}
+	return t, nil
+}
+
+// parseID reads a numeric ID argument.
+func parseID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID: %s", s)
+	}
+	return id, nil
+}
+
 // SetDatabase allows external packages (like tests) to inject a database connection
 func SetDatabase(db *sql.DB) {
 	database = db
@@ -41,3 +214,7 @@ func Execute() {
 		os.Exit(1)
 	}
 }
+
+func init() {
+	RootCmd.PersistentFlags().String("db", "", "Path to the SQLite database file (overrides FINANCE_DB and the config file)")
+}
@@ -2,45 +2,125 @@ package cli
 
 import (
 	"fmt"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/SebiGabor/personal-finance-cli/internal/models"
 	"github.com/spf13/cobra"
 )
 
-var (
-	rulePattern  string
-	ruleCategory string
-)
-
 // rulesCmd represents the base command for rule management
 var rulesCmd = &cobra.Command{
 	Use:   "rules",
-	Short: "Manage auto-categorization rules",
+	Short: "Manage the rules applied to new transactions",
+	Long: `Rules change transactions as they are imported or added. A rule has conditions
+(description pattern, amount range, income or expense, account, dates, current category)
+and actions (category, description, payee, tags, transfer). Rules are applied in the
+order of 'rules list'; every matching rule applies its actions, but the first rule to set
+the category, description or payee wins, and tags add up. A rule only replaces a category
+the transaction already has if it names that category with --if-category.`,
 }
 
 var rulesAddCmd = &cobra.Command{
 	Use:   "add",
-	Short: "Add a new categorization rule",
+	Short: "Add a new rule",
+	Example: `finance rules add --pattern '(?i)netflix' --category Entertainment:Streaming
+finance rules add --pattern '(?i)^card payment (.+?) \d+$' --set-description '$1' --sign expense
+finance rules add --pattern '(?i)savings' --account Checking --transfer
+finance rules add --if-category Food --min-amount 100 --category Food:Catering --tag party`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		normalizedCategory := models.NormalizeCategory(ruleCategory)
-		rule := &models.CategoryRule{
-			Pattern:  rulePattern,
-			Category: normalizedCategory,
+		rule := &models.CategoryRule{}
+		if err := ruleFromFlags(cmd, rule); err != nil {
+			return err
 		}
-
 		if err := models.CreateRule(database, rule); err != nil {
 			return fmt.Errorf("failed to create rule: %w", err)
 		}
 
-		fmt.Fprintf(cmd.OutOrStdout(), "Rule added: matches '%s' -> '%s'\n", rulePattern, normalizedCategory)
+		fmt.Fprintf(cmd.OutOrStdout(), "Rule %d added at position %d: %s -> %s\n",
+			rule.ID, rulePosition(rule.ID), ruleConditions(rule), ruleActions(rule))
+		return nil
+	},
+}
+
+var rulesEditCmd = &cobra.Command{
+	Use:   "edit [id]",
+	Short: "Change the conditions or actions of a rule",
+	Long: `Only the given flags change; pass an empty value (or 0) to drop a condition
+or an action, e.g. --account "" or --max-amount 0.`,
+	Example: `finance rules edit 3 --category Food:Groceries
+finance rules edit 3 --pattern '(?i)lidl|aldi' --account ""`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		rule, err := models.GetRule(database, id)
+		if err != nil {
+			return err
+		}
+		if err := ruleFromFlags(cmd, rule); err != nil {
+			return err
+		}
+		if err := models.UpdateRule(database, rule); err != nil {
+			return fmt.Errorf("failed to update rule: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Rule %d updated: %s -> %s\n", rule.ID, ruleConditions(rule), ruleActions(rule))
 		return nil
 	},
 }
 
+var rulesMoveCmd = &cobra.Command{
+	Use:   "move [id] [position]",
+	Short: "Change the order in which rules are applied (position 1 is applied first)",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		position, err := parseID(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid position %q", args[1])
+		}
+		if err := models.MoveRule(database, id, int(position)); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Rule %d moved to position %d.\n", id, position)
+		return nil
+	},
+}
+
+// ruleSwitchCmd builds 'rules enable' and 'rules disable'.
+func ruleSwitchCmd(enable bool) *cobra.Command {
+	verb, short := "enable", "Apply a disabled rule again"
+	if !enable {
+		verb, short = "disable", "Stop applying a rule without removing it"
+	}
+	return &cobra.Command{
+		Use:   verb + " [id]",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			if err := models.SetRuleEnabled(database, id, enable); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Rule %d %sd.\n", id, verb)
+			return nil
+		},
+	}
+}
+
 var rulesListCmd = &cobra.Command{
 	Use:   "list",
-	Short: "List all categorization rules",
+	Short: "List all rules in the order they are applied",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		rules, err := models.ListRules(database)
 		if err != nil {
@@ -53,22 +133,376 @@ var rulesListCmd = &cobra.Command{
 		}
 
 		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tPATTERN\tCATEGORY")
-		for _, r := range rules {
-			fmt.Fprintf(w, "%d\t%s\t%s\n", r.ID, r.Pattern, r.Category)
+		fmt.Fprintln(w, "#\tID\tSTATUS\tCONDITIONS\tACTIONS")
+		for i, r := range rules {
+			status := "enabled"
+			if r.Disabled {
+				status = "disabled"
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", i+1, r.ID, status, ruleConditions(&r), ruleActions(&r))
 		}
 		return w.Flush()
 	},
 }
 
+var rulesRemoveCmd = &cobra.Command{
+	Use:   "remove [id]",
+	Short: "Remove a rule (transactions it changed stay as they are)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if err := models.DeleteRule(database, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Rule %d removed.\n", id)
+		return nil
+	},
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test [description]",
+	Short: "Show which rules would fire for a transaction, without storing anything",
+	Example: `finance rules test "CARD PAYMENT LIDL 0042"
+finance rules test "Transfer to savings" --amount -200 --account Checking`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		amountStr, _ := cmd.Flags().GetString("amount")
+		accountName, _ := cmd.Flags().GetString("account")
+		dateStr, _ := cmd.Flags().GetString("date")
+		category, _ := cmd.Flags().GetString("category")
+
+		tr := &models.Transaction{Description: args[0], Account: accountName, Category: category, Date: time.Now()}
+		var err error
+		if amountStr != "" {
+			if tr.Amount, err = models.ParseMoney(amountStr); err != nil {
+				return err
+			}
+		}
+		if dateStr != "" {
+			if tr.Date, err = parseDate(dateStr); err != nil {
+				return err
+			}
+		}
+
+		rules, err := models.LoadRules(database)
+		if err != nil {
+			return err
+		}
+		before := *tr
+		result := rules.Apply(tr)
+		if len(result.Rules) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No rule matches.")
+			return nil
+		}
+
+		byID := map[int64]*models.CategoryRule{}
+		for _, r := range rules.Rules() {
+			byID[r.ID] = &r
+		}
+		for _, id := range result.Rules {
+			r := byID[id]
+			fmt.Fprintf(cmd.OutOrStdout(), "Rule %d matches: %s -> %s\n", id, ruleConditions(r), ruleActions(r))
+		}
+
+		tr.Category = models.NormalizeCategory(tr.Category)
+		before.Category = models.NormalizeCategory(before.Category)
+		fmt.Fprintln(cmd.OutOrStdout(), "Result:")
+		printRuleChange(cmd, &models.RuleChange{Before: before, After: *tr, Tags: result.Tags, Link: result.Transfer})
+		return nil
+	},
+}
+
+var rulesApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Run the rules over the transactions already stored",
+	Long: `Applies the rules to stored transactions as if they were imported again and shows
+every change. Unlike on import, rules replace the category transactions already have,
+unless --only-uncategorized is given. Legs of linked transfers are left alone.`,
+	Example: `finance rules apply --dry-run
+finance rules apply --since 2024-01-01 --only-uncategorized`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sinceStr, _ := cmd.Flags().GetString("since")
+		onlyUncategorized, _ := cmd.Flags().GetBool("only-uncategorized")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		scope := models.RuleScope{OnlyUncategorized: onlyUncategorized}
+		if sinceStr != "" {
+			var err error
+			if scope.Since, err = parseDate(sinceStr); err != nil {
+				return err
+			}
+		}
+
+		rules, err := models.LoadRules(database)
+		if err != nil {
+			return err
+		}
+		changes, err := models.RuleChanges(database, rules, scope)
+		if err != nil {
+			return fmt.Errorf("failed to apply the rules: %w", err)
+		}
+		if len(changes) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No transaction would change.")
+			return nil
+		}
+
+		for i := range changes {
+			c := &changes[i]
+			fmt.Fprintf(cmd.OutOrStdout(), "#%d %s %q\n", c.Before.ID, formatDate(c.Before.Date), c.Before.Description)
+			printRuleChange(cmd, c)
+		}
+
+		if dryRun {
+			fmt.Fprintf(cmd.OutOrStdout(), "%d transaction(s) would change (dry run, nothing stored).\n", len(changes))
+			return nil
+		}
+		linked, err := models.ApplyRuleChanges(database, changes)
+		if err != nil {
+			return fmt.Errorf("failed to apply the rules: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Updated %d transaction(s)", len(changes))
+		if linked > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), ", linked %d transfer(s)", linked)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), ".")
+		return nil
+	},
+}
+
+// printRuleChange prints what the rules change on a transaction, one field per line.
+func printRuleChange(cmd *cobra.Command, c *models.RuleChange) {
+	out := cmd.OutOrStdout()
+	if c.Before.Category != c.After.Category {
+		fmt.Fprintf(out, "  category:    %s -> %s\n", c.Before.Category, c.After.Category)
+	}
+	if c.Before.Description != c.After.Description {
+		fmt.Fprintf(out, "  description: %q -> %q\n", c.Before.Description, c.After.Description)
+	}
+	if c.Before.Payee != c.After.Payee {
+		fmt.Fprintf(out, "  payee:       %q -> %q\n", c.Before.Payee, c.After.Payee)
+	}
+	if len(c.Tags) > 0 {
+		fmt.Fprintf(out, "  tags:        +#%s\n", strings.Join(c.Tags, " +#"))
+	}
+	if c.Link {
+		fmt.Fprintln(out, "  transfer:    link with the other leg, if booked")
+	}
+}
+
+// ruleFlags adds the flags describing a rule to 'rules add' and 'rules edit'.
+func ruleFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("pattern", "p", "", "Regex the description must match (e.g., '(?i)netflix')")
+	cmd.Flags().String("min-amount", "", "Only amounts of at least this much, income or expense")
+	cmd.Flags().String("max-amount", "", "Only amounts of at most this much, income or expense")
+	cmd.Flags().String("sign", "", "Only 'expense' or 'income' (default: both)")
+	cmd.Flags().String("account", "", "Only transactions of this account")
+	cmd.Flags().String("from", "", "Only transactions booked on or after this date")
+	cmd.Flags().String("to", "", "Only transactions booked on or before this date")
+	cmd.Flags().String("if-category", "", "Only transactions in this category (or below it), and replace it")
+
+	cmd.Flags().StringP("category", "c", "", "Category to assign")
+	cmd.Flags().String("set-description", "", "New description; $1, $2, ... insert the pattern's groups")
+	cmd.Flags().String("payee", "", "Payee to set")
+	cmd.Flags().StringSlice("tag", nil, "Tag to add (repeatable)")
+	cmd.Flags().Bool("transfer", false, "Mark as a transfer between own accounts and link the other leg")
+	cmd.Flags().Bool("disabled", false, "Store the rule without applying it")
+	cmd.Flags().Int("priority", 0, "Priority; lower numbers are applied first (default: after all other rules)")
+}
+
+// ruleFromFlags sets the fields of the rule whose flags were given.
+func ruleFromFlags(cmd *cobra.Command, r *models.CategoryRule) error {
+	flags := cmd.Flags()
+	var err error
+
+	if flags.Changed("pattern") {
+		r.Pattern, _ = flags.GetString("pattern")
+	}
+	for _, f := range []struct {
+		name  string
+		field *models.Money
+	}{{"min-amount", &r.MinAmount}, {"max-amount", &r.MaxAmount}} {
+		if !flags.Changed(f.name) {
+			continue
+		}
+		s, _ := flags.GetString(f.name)
+		*f.field = 0
+		if s != "" {
+			amount, err := models.ParseMoney(s)
+			if err != nil {
+				return fmt.Errorf("invalid --%s: %w", f.name, err)
+			}
+			*f.field = amount.Abs()
+		}
+	}
+	if flags.Changed("sign") {
+		s, _ := flags.GetString("sign")
+		switch strings.ToLower(s) {
+		case "expense", "expenses", "-":
+			r.Sign = -1
+		case "income", "+":
+			r.Sign = 1
+		case "", "any", "both":
+			r.Sign = 0
+		default:
+			return fmt.Errorf("invalid --sign %q (use expense, income or any)", s)
+		}
+	}
+	if flags.Changed("account") {
+		name, _ := flags.GetString("account")
+		r.Account = ""
+		if name != "" {
+			account, err := models.GetAccountByName(database, name)
+			if err != nil {
+				return err
+			}
+			r.Account = account.Name
+		}
+	}
+	for _, f := range []struct {
+		name  string
+		field *time.Time
+	}{{"from", &r.From}, {"to", &r.To}} {
+		if !flags.Changed(f.name) {
+			continue
+		}
+		s, _ := flags.GetString(f.name)
+		*f.field = time.Time{}
+		if s != "" {
+			if *f.field, err = parseDate(s); err != nil {
+				return err
+			}
+		}
+	}
+	if flags.Changed("if-category") {
+		r.IfCategory, _ = flags.GetString("if-category")
+		if r.IfCategory != "" {
+			r.IfCategory = models.NormalizeCategory(r.IfCategory)
+		}
+	}
+
+	if flags.Changed("category") {
+		r.Category, _ = flags.GetString("category")
+		if r.Category != "" {
+			r.Category = models.NormalizeCategory(r.Category)
+		}
+	}
+	if flags.Changed("set-description") {
+		r.Description, _ = flags.GetString("set-description")
+	}
+	if flags.Changed("payee") {
+		r.Payee, _ = flags.GetString("payee")
+	}
+	if flags.Changed("tag") {
+		r.Tags, _ = flags.GetStringSlice("tag")
+	}
+	if flags.Changed("transfer") {
+		r.Transfer, _ = flags.GetBool("transfer")
+	}
+	if flags.Changed("disabled") {
+		r.Disabled, _ = flags.GetBool("disabled")
+	}
+	if flags.Changed("priority") {
+		r.Priority, _ = flags.GetInt("priority")
+	}
+	return nil
+}
+
+// ruleConditions describes when a rule applies, e.g. `/(?i)netflix/, expenses, Checking`.
+func ruleConditions(r *models.CategoryRule) string {
+	var parts []string
+	if r.Pattern != "" {
+		parts = append(parts, "/"+r.Pattern+"/")
+	}
+	switch {
+	case r.MinAmount != 0 && r.MaxAmount != 0:
+		parts = append(parts, fmt.Sprintf("amount %s-%s", r.MinAmount, r.MaxAmount))
+	case r.MinAmount != 0:
+		parts = append(parts, fmt.Sprintf("amount >= %s", r.MinAmount))
+	case r.MaxAmount != 0:
+		parts = append(parts, fmt.Sprintf("amount <= %s", r.MaxAmount))
+	}
+	switch r.Sign {
+	case -1:
+		parts = append(parts, "expenses")
+	case 1:
+		parts = append(parts, "income")
+	}
+	if r.Account != "" {
+		parts = append(parts, "account "+r.Account)
+	}
+	switch {
+	case !r.From.IsZero() && !r.To.IsZero():
+		parts = append(parts, formatDate(r.From)+".."+formatDate(r.To))
+	case !r.From.IsZero():
+		parts = append(parts, "from "+formatDate(r.From))
+	case !r.To.IsZero():
+		parts = append(parts, "until "+formatDate(r.To))
+	}
+	if r.IfCategory != "" {
+		parts = append(parts, "in "+r.IfCategory)
+	}
+	if len(parts) == 0 {
+		return "always"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ruleActions describes what a rule does, e.g. `category Food, payee Lidl, #groceries`.
+func ruleActions(r *models.CategoryRule) string {
+	var parts []string
+	if r.Category != "" {
+		parts = append(parts, "category "+r.Category)
+	}
+	if r.Description != "" {
+		parts = append(parts, fmt.Sprintf("description %q", r.Description))
+	}
+	if r.Payee != "" {
+		parts = append(parts, "payee "+r.Payee)
+	}
+	for _, tag := range r.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	if r.Transfer {
+		parts = append(parts, "transfer")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// rulePosition returns where a rule stands in the order of 'rules list', or 0.
+func rulePosition(id int64) int {
+	rules, _ := models.ListRules(database)
+	for i, r := range rules {
+		if r.ID == id {
+			return i + 1
+		}
+	}
+	return 0
+}
+
 func init() {
 	RootCmd.AddCommand(rulesCmd)
 	rulesCmd.AddCommand(rulesAddCmd)
+	rulesCmd.AddCommand(rulesEditCmd)
 	rulesCmd.AddCommand(rulesListCmd)
+	rulesCmd.AddCommand(rulesMoveCmd)
+	rulesCmd.AddCommand(ruleSwitchCmd(true))
+	rulesCmd.AddCommand(ruleSwitchCmd(false))
+	rulesCmd.AddCommand(rulesRemoveCmd)
+	rulesCmd.AddCommand(rulesTestCmd)
+	rulesCmd.AddCommand(rulesApplyCmd)
+
+	ruleFlags(rulesAddCmd)
+	ruleFlags(rulesEditCmd)
+
+	rulesTestCmd.Flags().String("amount", "", "Amount of the transaction (negative for an expense)")
+	rulesTestCmd.Flags().String("account", "", "Account of the transaction")
+	rulesTestCmd.Flags().String("date", "", "Date of the transaction (defaults to today)")
+	rulesTestCmd.Flags().String("category", "", "Category the transaction already has")
 
-	// Flags for 'rules add'
-	rulesAddCmd.Flags().StringVarP(&rulePattern, "pattern", "p", "", "Regex pattern to match (e.g., '(?i)netflix')")
-	rulesAddCmd.Flags().StringVarP(&ruleCategory, "category", "c", "", "Category to assign")
-	rulesAddCmd.MarkFlagRequired("pattern")
-	rulesAddCmd.MarkFlagRequired("category")
+	rulesApplyCmd.Flags().String("since", "", "Only transactions booked on or after this date")
+	rulesApplyCmd.Flags().Bool("only-uncategorized", false, "Only transactions without a category")
+	rulesApplyCmd.Flags().Bool("dry-run", false, "Show the changes without storing them")
 }
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var rulesSuggestCmd = &cobra.Command{
+	Use:   "suggest",
+	Short: "Propose rules for the merchants uncategorized transactions go to",
+	Long: `Groups the uncategorized transactions by merchant, their description without
+numbers and words like "card payment" ("STARBUCKS 054" and "Starbucks 112" are both
+starbucks), and proposes a rule for each merchant with at least --min-count of them:
+a pattern matching all of them, how many uncategorized transactions it catches, and the
+category learned from history, if any. Transactions the current rules already
+categorize are left out.
+
+For every suggestion, type the category to accept it with (Enter takes the proposed
+one), s to skip it or q to stop. Accepted rules are added at the end of 'rules list'
+and apply to new transactions; 'rules apply --only-uncategorized' applies them to the
+stored ones. --list only prints the suggestions.`,
+	Example: "finance rules suggest\nfinance rules suggest --min-count 5 --list",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		minCount, _ := cmd.Flags().GetInt("min-count")
+		list, _ := cmd.Flags().GetBool("list")
+		if minCount < 1 {
+			return fmt.Errorf("--min-count must be at least 1")
+		}
+
+		rules, err := models.LoadRules(database)
+		if err != nil {
+			return fmt.Errorf("failed to load rules: %w", err)
+		}
+		classifier, err := models.TrainClassifier(database)
+		if err != nil {
+			return fmt.Errorf("failed to learn from the categorized transactions: %w", err)
+		}
+		suggestions, err := models.SuggestRules(database, rules, classifier, minCount)
+		if err != nil {
+			return fmt.Errorf("failed to suggest rules: %w", err)
+		}
+		if len(suggestions) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No rule to suggest.")
+			return nil
+		}
+
+		if list {
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PATTERN\tCOUNT\tCATEGORY\tEXAMPLES")
+			for _, s := range suggestions {
+				fmt.Fprintf(w, "/%s/\t%d\t%s\t%s\n", s.Pattern, s.Count, s.Category, strings.Join(s.Examples, ", "))
+			}
+			w.Flush()
+			fmt.Fprintf(cmd.OutOrStdout(), "%d suggestion(s). Run again without --list to accept them.\n", len(suggestions))
+			return nil
+		}
+
+		in := bufio.NewScanner(cmd.InOrStdin())
+		added := 0
+	prompts:
+		for i, s := range suggestions {
+			fmt.Fprintf(cmd.OutOrStdout(), "\n[%d/%d] /%s/ catches %d transaction(s), e.g. %s\n",
+				i+1, len(suggestions), s.Pattern, s.Count, strings.Join(s.Examples, ", "))
+			for {
+				if s.Category != "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "Category [%s], s to skip, q to quit: ", s.Category)
+				} else {
+					fmt.Fprint(cmd.OutOrStdout(), "Category, s to skip, q to quit: ")
+				}
+				if !in.Scan() {
+					fmt.Fprintln(cmd.OutOrStdout())
+					break prompts
+				}
+
+				answer := strings.TrimSpace(in.Text())
+				switch {
+				case answer == "q":
+					break prompts
+				case answer == "s", answer == "" && s.Category == "":
+					continue prompts
+				case answer == "":
+					answer = s.Category
+				}
+
+				rule := &models.CategoryRule{Pattern: s.Pattern, Category: answer}
+				if err := models.CreateRule(database, rule); err != nil {
+					fmt.Fprintf(cmd.OutOrStdout(), "Failed to create rule: %v\n", err)
+					continue
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Rule %d added at position %d: %s -> %s\n",
+					rule.ID, rulePosition(rule.ID), ruleConditions(rule), ruleActions(rule))
+				added++
+				continue prompts
+			}
+		}
+		if err := in.Err(); err != nil {
+			return err
+		}
+
+		if added == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No rule added.")
+			return nil
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Added %d rule(s); run 'finance rules apply --only-uncategorized' to categorize the transactions they catch.\n", added)
+		return nil
+	},
+}
+
+func init() {
+	rulesCmd.AddCommand(rulesSuggestCmd)
+
+	rulesSuggestCmd.Flags().Int("min-count", 2, "Only merchants with at least this many uncategorized transactions")
+	rulesSuggestCmd.Flags().Bool("list", false, "Only print the suggestions")
+}
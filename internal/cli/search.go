@@ -14,7 +14,18 @@ var searchCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		query := args[0]
-		transactions, err := models.SearchTransactions(database, query)
+		accountRaw, _ := cmd.Flags().GetString("account")
+		account, err := accountFilter(accountRaw)
+		if err != nil {
+			return err
+		}
+		tagRaw, _ := cmd.Flags().GetString("tag")
+		tag, err := tagFilter(tagRaw)
+		if err != nil {
+			return err
+		}
+
+		transactions, err := models.FindTransactions(database, models.TransactionFilter{Query: query, Account: account, Tag: tag})
 		if err != nil {
 			return fmt.Errorf("search failed: %w", err)
 		}
@@ -25,10 +36,10 @@ var searchCmd = &cobra.Command{
 		}
 
 		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tDATE\tAMOUNT\tCATEGORY\tDESCRIPTION")
+		fmt.Fprintln(w, "ID\tDATE\tAMOUNT\tCATEGORY\tACCOUNT\tPAYEE\tDESCRIPTION")
 		for _, t := range transactions {
-			fmt.Fprintf(w, "%d\t%s\t%.2f\t%s\t%s\n",
-				t.ID, t.Date.Format("2006-01-02"), t.Amount, t.Category, t.Description)
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				t.ID, formatDate(t.Date), formatAmount(t.Amount, t.Currency), t.Category, t.Account, t.Payee, t.Description)
 		}
 		return w.Flush()
 	},
@@ -36,4 +47,6 @@ var searchCmd = &cobra.Command{
 
 func init() {
 	RootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().String("account", "", "Only search transactions of this account")
+	searchCmd.Flags().String("tag", "", "Only search transactions with this tag")
 }
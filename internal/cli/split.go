@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var splitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "Split a transaction across several categories",
+	Long: `Split lines assign part of a transaction to another category, e.g. the household
+items on a supermarket receipt. Whatever the splits don't cover stays in the
+transaction's own category. Reports and budgets count the split lines.`,
+}
+
+var splitAddCmd = &cobra.Command{
+	Use:     "add [transaction-id]",
+	Short:   "Add a split line to a transaction",
+	Example: "finance split add 42 --amount 12.50 --category Household --memo \"detergent\"",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		amountStr, _ := cmd.Flags().GetString("amount")
+		category, _ := cmd.Flags().GetString("category")
+		memo, _ := cmd.Flags().GetString("memo")
+
+		amount, err := models.ParseMoney(amountStr)
+		if err != nil {
+			return err
+		}
+
+		s := &models.Split{TransactionID: id, Amount: amount, Category: category, Memo: memo}
+		if err := models.AddSplit(database, s); err != nil {
+			return fmt.Errorf("failed to add split: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Split %d added: %s -> %s\n", s.ID, s.Amount, s.Category)
+		return printSplitLines(cmd, id)
+	},
+}
+
+var splitListCmd = &cobra.Command{
+	Use:   "list [transaction-id]",
+	Short: "Show how a transaction is split",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		return printSplitLines(cmd, id)
+	},
+}
+
+var splitRemoveCmd = &cobra.Command{
+	Use:   "remove [split-id]",
+	Short: "Remove a split line; its amount goes back to the transaction's category",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if err := models.DeleteSplit(database, id); err != nil {
+			return fmt.Errorf("failed to remove split: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Split %d removed.\n", id)
+		return nil
+	},
+}
+
+var splitClearCmd = &cobra.Command{
+	Use:   "clear [transaction-id]",
+	Short: "Remove all split lines of a transaction",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if err := models.ClearSplits(database, id); err != nil {
+			return fmt.Errorf("failed to clear splits: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Transaction %d is no longer split.\n", id)
+		return nil
+	},
+}
+
+// printSplitLines prints the breakdown of a transaction, remainder included.
+func printSplitLines(cmd *cobra.Command, transactionID int64) error {
+	t, err := models.GetTransaction(database, transactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("transaction %d not found", transactionID)
+	}
+	if err != nil {
+		return err
+	}
+	lines, err := models.GetSplitLines(database, t)
+	if err != nil {
+		return fmt.Errorf("failed to list splits: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "%s  %s  %s\n", formatDate(t.Date), t.Description, formatAmount(t.Amount, t.Currency))
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SPLIT\tAMOUNT\tCATEGORY\tMEMO")
+	for _, l := range lines {
+		id := strconv.FormatInt(l.ID, 10)
+		memo := l.Memo
+		if l.Remainder {
+			id, memo = "-", "(not split)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", id, l.Amount, l.Category, memo)
+	}
+	return w.Flush()
+}
+
+func init() {
+	RootCmd.AddCommand(splitCmd)
+	splitCmd.AddCommand(splitAddCmd)
+	splitCmd.AddCommand(splitListCmd)
+	splitCmd.AddCommand(splitRemoveCmd)
+	splitCmd.AddCommand(splitClearCmd)
+
+	splitAddCmd.Flags().String("amount", "", "Part of the transaction amount (takes the transaction's sign)")
+	splitAddCmd.Flags().String("category", "", "Category of this part")
+	splitAddCmd.Flags().String("memo", "", "Optional note, e.g. what was bought")
+	splitAddCmd.MarkFlagRequired("amount")
+	splitAddCmd.MarkFlagRequired("category")
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Tag transactions, e.g. by trip or project",
+	Long: `Tags group transactions across categories, e.g. everything spent on
+#vacation-2026 or #renovation. A transaction can carry any number of tags.`,
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:     "add [transaction-id] [tag...]",
+	Short:   "Add tags to a transaction",
+	Example: "finance tag add 42 vacation-2026 '#family'",
+	Args:    cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if err := models.AddTags(database, id, args[1:]...); err != nil {
+			return fmt.Errorf("failed to add tags: %w", err)
+		}
+		return printTags(cmd, id)
+	},
+}
+
+var tagRemoveCmd = &cobra.Command{
+	Use:   "remove [transaction-id] [tag...]",
+	Short: "Remove tags from a transaction",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if err := models.RemoveTags(database, id, args[1:]...); err != nil {
+			return fmt.Errorf("failed to remove tags: %w", err)
+		}
+		return printTags(cmd, id)
+	},
+}
+
+var tagListCmd = &cobra.Command{
+	Use:   "list [transaction-id]",
+	Short: "List all tags, or the tags of one transaction",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			return printTags(cmd, id)
+		}
+
+		tags, err := models.ListTags(database)
+		if err != nil {
+			return fmt.Errorf("failed to list tags: %w", err)
+		}
+		if len(tags) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No tags found.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TAG\tTRANSACTIONS")
+		for _, t := range tags {
+			fmt.Fprintf(w, "#%s\t%d\n", t.Name, t.Count)
+		}
+		return w.Flush()
+	},
+}
+
+// printTags prints the tags of one transaction.
+func printTags(cmd *cobra.Command, transactionID int64) error {
+	tags, err := models.GetTags(database, transactionID)
+	if err != nil {
+		return fmt.Errorf("failed to list tags: %w", err)
+	}
+	if len(tags) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Transaction %d has no tags.\n", transactionID)
+		return nil
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Transaction %d: #%s\n", transactionID, strings.Join(tags, " #"))
+	return nil
+}
+
+// tagFilter normalizes the value of a --tag flag; an empty value means no filter.
+func tagFilter(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	return models.NormalizeTag(raw)
+}
+
+func init() {
+	RootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+	tagCmd.AddCommand(tagListCmd)
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var transferCmd = &cobra.Command{
+	Use:   "transfer",
+	Short: "Move money between your own accounts",
+	Long: `Records a transfer between two of your accounts, e.g. paying off a credit card
+from checking. Both legs are linked: account balances change, but reports and
+budgets don't count a transfer as income or expense.`,
+	Example: "finance transfer --from Checking --to Visa --amount 500\nfinance transfer --from Checking --to Savings-USD --amount 500 --to-amount 542.10",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		amountStr, _ := cmd.Flags().GetString("amount")
+		toAmountStr, _ := cmd.Flags().GetString("to-amount")
+		desc, _ := cmd.Flags().GetString("desc")
+		dateStr, _ := cmd.Flags().GetString("date")
+
+		amount, err := models.ParseMoney(amountStr)
+		if err != nil {
+			return err
+		}
+		var toAmount models.Money
+		if toAmountStr != "" {
+			if toAmount, err = models.ParseMoney(toAmountStr); err != nil {
+				return err
+			}
+		}
+
+		date := time.Now()
+		if dateStr != "" {
+			if date, err = parseDate(dateStr); err != nil {
+				return err
+			}
+		}
+
+		out, in, err := models.CreateTransfer(database, models.Transfer{
+			Date: date, Description: desc, From: from, To: to, Amount: amount.Abs(), ToAmount: toAmount.Abs(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record transfer: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Transferred %s from %s to %s (IDs: %d, %d)\n",
+			formatAmount(out.Amount.Abs(), out.Currency), out.Account, in.Account, out.ID, in.ID)
+		return nil
+	},
+}
+
+var transferListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List linked transfers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pairs, err := models.ListTransfers(database)
+		if err != nil {
+			return fmt.Errorf("failed to list transfers: %w", err)
+		}
+		if len(pairs) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No transfers found.")
+			return nil
+		}
+		return printTransferPairs(cmd, pairs)
+	},
+}
+
+var transferLinkCmd = &cobra.Command{
+	Use:   "link [transaction-id] [transaction-id]",
+	Short: "Mark two existing transactions as one transfer",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		b, err := parseID(args[1])
+		if err != nil {
+			return err
+		}
+		if _, err := models.LinkTransfer(database, a, b); err != nil {
+			return fmt.Errorf("failed to link transfer: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Transactions %d and %d are now a transfer.\n", a, b)
+		return nil
+	},
+}
+
+var transferUnlinkCmd = &cobra.Command{
+	Use:   "unlink [transaction-id]",
+	Short: "Turn both legs of a transfer back into ordinary transactions",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if err := models.UnlinkTransfer(database, id); err != nil {
+			return fmt.Errorf("failed to unlink transfer: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Transfer of transaction %d unlinked.\n", id)
+		return nil
+	},
+}
+
+var transferDetectCmd = &cobra.Command{
+	Use:   "detect",
+	Short: "Find imported transactions that look like transfers between your accounts",
+	Long: `Pairs unlinked transactions in two different accounts with opposite amounts in
+the same currency, booked at most --days apart. Review the list, then run again
+with --link to link all of them (or use 'transfer link' for single pairs).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		days, _ := cmd.Flags().GetInt("days")
+		link, _ := cmd.Flags().GetBool("link")
+
+		pairs, err := models.DetectTransfers(database, days, nil)
+		if err != nil {
+			return fmt.Errorf("failed to detect transfers: %w", err)
+		}
+		if len(pairs) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No possible transfers found.")
+			return nil
+		}
+		if err := printTransferPairs(cmd, pairs); err != nil {
+			return err
+		}
+
+		if !link {
+			fmt.Fprintf(cmd.OutOrStdout(), "%d possible transfer(s). Run again with --link to link them.\n", len(pairs))
+			return nil
+		}
+		for _, p := range pairs {
+			if _, err := models.LinkTransfer(database, p.Out.ID, p.In.ID); err != nil {
+				return fmt.Errorf("failed to link %d and %d: %w", p.Out.ID, p.In.ID, err)
+			}
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Linked %d transfer(s).\n", len(pairs))
+		return nil
+	},
+}
+
+func printTransferPairs(cmd *cobra.Command, pairs []models.TransferPair) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OUT ID\tDATE\tFROM\tIN ID\tDATE\tTO\tAMOUNT")
+	for _, p := range pairs {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			p.Out.ID, formatDate(p.Out.Date), p.Out.Account,
+			p.In.ID, formatDate(p.In.Date), p.In.Account,
+			formatAmount(p.Out.Amount.Abs(), p.Out.Currency))
+	}
+	return w.Flush()
+}
+
+func init() {
+	RootCmd.AddCommand(transferCmd)
+	transferCmd.AddCommand(transferListCmd)
+	transferCmd.AddCommand(transferLinkCmd)
+	transferCmd.AddCommand(transferUnlinkCmd)
+	transferCmd.AddCommand(transferDetectCmd)
+
+	transferCmd.Flags().String("from", "", "Account the money leaves")
+	transferCmd.Flags().String("to", "", "Account the money arrives in")
+	transferCmd.Flags().StringP("amount", "a", "", "Amount leaving the source account")
+	transferCmd.Flags().String("to-amount", "", "Amount arriving, when the accounts use different currencies")
+	transferCmd.Flags().StringP("desc", "d", "", "Description (default \"Transfer <from> -> <to>\")")
+	transferCmd.Flags().StringP("date", "t", "", "Date (YYYY-MM-DD or the configured date_format), defaults to today")
+	transferCmd.MarkFlagRequired("from")
+	transferCmd.MarkFlagRequired("to")
+	transferCmd.MarkFlagRequired("amount")
+
+	transferDetectCmd.Flags().Int("days", models.DefaultTransferWindow, "How many days apart the two legs may be booked")
+	transferDetectCmd.Flags().Bool("link", false, "Link every pair found")
+}
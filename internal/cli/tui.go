@@ -10,7 +10,7 @@ var tuiCmd = &cobra.Command{
 	Short: "Launch the interactive terminal UI",
 	Long:  "Opens an interactive table to browse and scroll through transactions.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return tui.StartTUI(database)
+		return tui.StartTUI(database, settings.DateFormat)
 	},
 }
 
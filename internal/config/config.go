@@ -0,0 +1,159 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AppName is the directory name used under the XDG config and data directories.
+const AppName = "finance"
+
+// Config holds user defaults read from the config file. Empty fields are unset.
+type Config struct {
+	DB                   string `json:"db,omitempty"`
+	BaseCurrency         string `json:"base_currency,omitempty"`
+	DateFormat           string `json:"date_format,omitempty"`
+	DefaultAccount       string `json:"default_account,omitempty"`
+	FuzzyDedup           string `json:"fuzzy_dedup,omitempty"`
+	ClassifierConfidence string `json:"classifier_confidence,omitempty"`
+}
+
+// keys maps the names used by 'finance config set' to the fields they change.
+var keys = map[string]func(c *Config) *string{
+	"db":                    func(c *Config) *string { return &c.DB },
+	"base_currency":         func(c *Config) *string { return &c.BaseCurrency },
+	"date_format":           func(c *Config) *string { return &c.DateFormat },
+	"default_account":       func(c *Config) *string { return &c.DefaultAccount },
+	"fuzzy_dedup":           func(c *Config) *string { return &c.FuzzyDedup },
+	"classifier_confidence": func(c *Config) *string { return &c.ClassifierConfidence },
+}
+
+// Keys returns the settable config keys in alphabetical order.
+func Keys() []string {
+	list := make([]string, 0, len(keys))
+	for k := range keys {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Get returns the value of a config key.
+func (c *Config) Get(key string) (string, error) {
+	field, ok := keys[key]
+	if !ok {
+		return "", fmt.Errorf("unknown config key %q (use one of: %s)", key, strings.Join(Keys(), ", "))
+	}
+	return *field(c), nil
+}
+
+// Set changes the value of a config key; an empty value unsets it.
+func (c *Config) Set(key, value string) error {
+	field, ok := keys[key]
+	if !ok {
+		return fmt.Errorf("unknown config key %q (use one of: %s)", key, strings.Join(Keys(), ", "))
+	}
+	*field(c) = strings.TrimSpace(value)
+	return nil
+}
+
+// Path returns the location of the config file: $FINANCE_CONFIG if set,
+// otherwise config.json in the user's config directory ($XDG_CONFIG_HOME/finance on Linux).
+func Path() (string, error) {
+	if p := os.Getenv("FINANCE_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine config directory: %w", err)
+	}
+	return filepath.Join(dir, AppName, "config.json"), nil
+}
+
+// Load reads the config file at path. A missing file is not an error and yields an empty Config.
+func Load(path string) (*Config, error) {
+	var c Config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the config file, creating its directory if needed.
+func (c *Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// DefaultDBPath is where the database lives when nothing else is configured:
+// $XDG_DATA_HOME/finance/finance.db, falling back to ~/.local/share on Unix
+// and the user config directory on Windows.
+func DefaultDBPath() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			var err error
+			if dir, err = os.UserConfigDir(); err != nil {
+				return "", err
+			}
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("cannot determine home directory: %w", err)
+			}
+			dir = filepath.Join(home, ".local", "share")
+		}
+	}
+	return filepath.Join(dir, AppName, "finance.db"), nil
+}
+
+// dateTokens translates human friendly date patterns into Go layouts, longest first.
+var dateTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MM", "01"},
+	{"DD", "02"},
+}
+
+// DateLayout turns a date format such as "DD.MM.YYYY" into a Go time layout.
+// Formats that already are Go layouts (containing "2006") are returned unchanged.
+func DateLayout(format string) (string, error) {
+	if format == "" {
+		return "2006-01-02", nil
+	}
+	if strings.Contains(format, "2006") {
+		return format, nil
+	}
+
+	layout := format
+	for _, t := range dateTokens {
+		layout = strings.ReplaceAll(layout, t.token, t.layout)
+	}
+
+	// The layout must round-trip a date, otherwise a token was not recognized
+	probe := time.Date(2024, 11, 23, 0, 0, 0, 0, time.UTC)
+	if parsed, err := time.Parse(layout, probe.Format(layout)); err != nil || !parsed.Equal(probe) {
+		return "", fmt.Errorf("invalid date format %q (use e.g. YYYY-MM-DD or DD.MM.YYYY)", format)
+	}
+	return layout, nil
+}
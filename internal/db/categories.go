@@ -0,0 +1,137 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// categoryTables are the tables whose free-form category column becomes a category_id.
+var categoryTables = []string{"transactions", "transaction_splits", "budgets", "category_rules"}
+
+// migrateCategoryTree moves categories from free-form strings into a categories tree.
+// A stored "Food:Groceries" becomes Groceries below Food; every table then refers to the
+// category by id and the old text columns are dropped.
+func migrateCategoryTree(tx *sql.Tx) error {
+	// The view reads the text columns that are about to be dropped
+	if _, err := tx.Exec(`DROP VIEW IF EXISTS ledger_lines`); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS categories (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name TEXT NOT NULL,
+            parent_id INTEGER REFERENCES categories(id)
+        );
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_sibling_name
+            ON categories(COALESCE(parent_id, 0), name COLLATE NOCASE);
+        CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);
+    `); err != nil {
+		return err
+	}
+
+	for _, table := range categoryTables {
+		if err := addMissingColumn(tx, table, "category_id", "INTEGER REFERENCES categories(id)"); err != nil {
+			return err
+		}
+
+		names, err := distinctCategories(tx, table)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			id, err := categoryPathID(tx, name)
+			if err != nil {
+				return fmt.Errorf("category %q: %w", name, err)
+			}
+			if _, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET category_id = ? WHERE category = ?`, table), id, name); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s DROP COLUMN category`, table)); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(`
+        CREATE INDEX IF NOT EXISTS idx_transactions_category ON transactions(category_id);
+
+        -- Full "Parent:Child" path of every category
+        CREATE VIEW category_paths AS
+        WITH RECURSIVE paths(id, path) AS (
+            SELECT id, name FROM categories WHERE parent_id IS NULL
+            UNION ALL
+            SELECT c.id, paths.path || ':' || c.name FROM categories c JOIN paths ON c.parent_id = paths.id
+        )
+        SELECT id, path FROM paths;
+
+        -- Same lines as before (see 006_transaction_splits.sql), now by category id
+        CREATE VIEW ledger_lines AS
+        SELECT t.id AS transaction_id, t.date, t.description, t.account, t.currency, t.category_id,
+               COALESCE(cp.path, 'Uncategorized') AS category,
+               t.amount - COALESCE(s.total, 0) AS amount, '' AS memo
+        FROM transactions t
+                 LEFT JOIN (SELECT transaction_id, SUM(amount) AS total FROM transaction_splits GROUP BY transaction_id) s
+                           ON s.transaction_id = t.id
+                 LEFT JOIN category_paths cp ON cp.id = t.category_id
+        WHERE s.total IS NULL OR t.amount != s.total
+        UNION ALL
+        SELECT t.id, t.date, t.description, t.account, t.currency, sp.category_id,
+               COALESCE(cp.path, 'Uncategorized'), sp.amount, sp.memo
+        FROM transaction_splits sp
+                 JOIN transactions t ON t.id = sp.transaction_id
+                 LEFT JOIN category_paths cp ON cp.id = sp.category_id;
+    `)
+	return err
+}
+
+func distinctCategories(tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.Query(fmt.Sprintf(`SELECT DISTINCT category FROM %s WHERE COALESCE(category, '') != ''`, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// categoryPathID returns the id of a "Parent:Child" category path, creating missing levels.
+func categoryPathID(tx *sql.Tx, path string) (int64, error) {
+	var parent sql.NullInt64
+	for _, name := range strings.Split(path, ":") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		var id int64
+		err := tx.QueryRow(`SELECT id FROM categories WHERE COALESCE(parent_id, 0) = ? AND name = ? COLLATE NOCASE`, parent.Int64, name).Scan(&id)
+		if err == sql.ErrNoRows {
+			res, err := tx.Exec(`INSERT INTO categories (name, parent_id) VALUES (?, ?)`, name, parent)
+			if err != nil {
+				return 0, err
+			}
+			id, err = res.LastInsertId()
+			if err != nil {
+				return 0, err
+			}
+		} else if err != nil {
+			return 0, err
+		}
+		parent = sql.NullInt64{Int64: id, Valid: true}
+	}
+	if !parent.Valid {
+		return 0, fmt.Errorf("empty category path")
+	}
+	return parent.Int64, nil
+}
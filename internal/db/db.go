@@ -6,6 +6,11 @@ import (
 	"fmt"
 	"io/fs"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -13,45 +18,190 @@ import (
 //go:embed migrations/*.sql
 var migrationFiles embed.FS
 
-// Connect opens (or creates) the SQLite database file and runs migrations.
-func Connect() (*sql.DB, error) {
-	db, err := sql.Open("sqlite", "finance.db")
+// Migration is one versioned schema change. Versions sort in the order they are applied.
+type Migration struct {
+	Version string
+	up      func(tx *sql.Tx) error
+}
+
+// MigrationState tells whether a migration has been applied, and when.
+type MigrationState struct {
+	Version   string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Connect opens (or creates) the SQLite database file at path and runs pending migrations.
+func Connect(path string) (*sql.DB, error) {
+	db, err := Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	if err := runMigrations(db); err != nil {
+	if _, err := Migrate(db); err != nil {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
 	return db, nil
 }
 
-// runMigrations executes all .sql files in the migrations folder.
-func runMigrations(db *sql.DB) error {
-	entries, err := fs.ReadDir(migrationFiles, "migrations")
+// Open opens (or creates) the SQLite database file at path without touching its schema.
+// The file's directory is created if needed.
+func Open(path string) (*sql.DB, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
+
+// Migrate applies every migration that is not yet recorded in schema_migrations,
+// each exactly once and inside its own transaction. It returns the applied versions.
+func Migrate(db *sql.DB) ([]string, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
+	migrations, err := allMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []string
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
 			continue
 		}
 
-		migrationName := entry.Name()
-		content, err := migrationFiles.ReadFile("migrations/" + migrationName)
-		if err != nil {
-			return err
+		log.Printf("Running migration: %s", m.Version)
+		if err := apply(db, m); err != nil {
+			return ran, fmt.Errorf("migration %s failed: %w", m.Version, err)
 		}
+		ran = append(ran, m.Version)
+	}
+
+	return ran, nil
+}
 
-		log.Printf("Running migration: %s", migrationName)
+// Status lists all known migrations and whether they have been applied.
+func Status(db *sql.DB) ([]MigrationState, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	migrations, err := allMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, m := range migrations {
+		at, ok := applied[m.Version]
+		states[i] = MigrationState{Version: m.Version, Applied: ok, AppliedAt: at}
+	}
+	return states, nil
+}
+
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version TEXT PRIMARY KEY,
+            applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        );
+    `)
+	return err
+}
+
+func appliedVersions(db *sql.DB) (map[string]time.Time, error) {
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[string]time.Time{}
+	for rows.Next() {
+		var version string
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// apply runs a migration and records it in the same transaction, so a failure
+// leaves neither a half-applied schema nor a false record behind.
+func apply(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Another process may have applied it since we looked
+	var done int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, m.Version).Scan(&done); err != nil {
+		return err
+	}
+	if done > 0 {
+		return nil
+	}
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, m.Version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// allMigrations merges the embedded .sql files with the migrations written in Go,
+// ordered by version. The version of a .sql file is its name without the extension.
+func allMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
 
-		_, err = db.Exec(string(content))
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
 		if err != nil {
-			return fmt.Errorf("migration %s failed: %w", migrationName, err)
+			return nil, err
 		}
+		migrations = append(migrations, Migration{
+			Version: strings.TrimSuffix(entry.Name(), ".sql"),
+			up: func(tx *sql.Tx) error {
+				_, err := tx.Exec(string(content))
+				return err
+			},
+		})
 	}
+	migrations = append(migrations, goMigrations...)
 
-	return nil
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
 }
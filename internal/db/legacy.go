@@ -0,0 +1,123 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// goMigrations are schema changes that plain SQL cannot express.
+var goMigrations = []Migration{
+	// Databases created before migrations were tracked ran 001-004 on every start and
+	// were patched in place by inspecting the live schema. This step performs those
+	// patches once; on databases created from the current .sql files it changes nothing.
+	{Version: "005_upgrade_legacy_schema", up: upgradeLegacySchema},
+	// Category strings are parsed into a tree, which SQL alone cannot do.
+	{Version: "008_category_tree", up: migrateCategoryTree},
+}
+
+func upgradeLegacySchema(tx *sql.Tx) error {
+	for _, table := range []string{"transactions", "budgets"} {
+		if err := convertAmountToMinorUnits(tx, table); err != nil {
+			return fmt.Errorf("upgrading %s amounts failed: %w", table, err)
+		}
+	}
+
+	for _, c := range []struct{ table, column, decl string }{
+		{"transactions", "currency", "TEXT"},
+		{"accounts", "currency", "TEXT"},
+	} {
+		if err := addMissingColumn(tx, c.table, c.column, c.decl); err != nil {
+			return fmt.Errorf("adding %s.%s failed: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+// addMissingColumn adds a column that newer versions declare in their CREATE TABLE
+// statements to a table that was created before the column existed.
+func addMissingColumn(tx *sql.Tx, table, column, decl string) error {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	log.Printf("Adding column %s.%s", table, column)
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	return err
+}
+
+var realAmountColumn = regexp.MustCompile(`(?i)\bamount\s+REAL\b`)
+
+// convertAmountToMinorUnits rebuilds a table whose amount column is still a REAL
+// (as created by versions before exact money support) into an INTEGER column holding cents.
+// SQLite cannot change a column type in place, so the table is recreated and copied.
+func convertAmountToMinorUnits(tx *sql.Tx, table string) error {
+	var colType string
+	err := tx.QueryRow(`SELECT type FROM pragma_table_info(?) WHERE name = 'amount'`, table).Scan(&colType)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(colType, "REAL") {
+		return nil
+	}
+
+	var createSQL string
+	if err := tx.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&createSQL); err != nil {
+		return err
+	}
+
+	columns, err := columnNames(tx, table)
+	if err != nil {
+		return err
+	}
+	selectList := make([]string, len(columns))
+	for i, c := range columns {
+		selectList[i] = c
+		if c == "amount" {
+			// Stored floats such as 15.99 are really 1598.9999...; rounding restores the exact cents.
+			selectList[i] = "CAST(ROUND(amount * 100) AS INTEGER)"
+		}
+	}
+
+	log.Printf("Converting %s.amount to exact minor units", table)
+
+	legacy := table + "_legacy"
+	steps := []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", table, legacy),
+		realAmountColumn.ReplaceAllString(createSQL, "amount INTEGER"),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+			table, strings.Join(columns, ", "), strings.Join(selectList, ", "), legacy),
+		fmt.Sprintf("DROP TABLE %s", legacy),
+	}
+	for _, stmt := range steps {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func columnNames(tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.Query(`SELECT name FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -2,16 +2,17 @@ CREATE TABLE IF NOT EXISTS transactions (
                                             id INTEGER PRIMARY KEY AUTOINCREMENT,
                                             date TEXT NOT NULL,
                                             description TEXT,
-                                            amount REAL NOT NULL,
+                                            amount INTEGER NOT NULL,
                                             category TEXT,
                                             account TEXT,
+                                            currency TEXT,
                                             created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE TABLE IF NOT EXISTS budgets (
                                        id INTEGER PRIMARY KEY AUTOINCREMENT,
                                        category TEXT NOT NULL,
-                                       amount REAL NOT NULL,
+                                       amount INTEGER NOT NULL,
                                        period TEXT NOT NULL
 );
 
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS accounts (
+                                        id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                        name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+                                        type TEXT NOT NULL DEFAULT 'checking',
+                                        currency TEXT,
+                                        closed INTEGER NOT NULL DEFAULT 0,
+                                        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
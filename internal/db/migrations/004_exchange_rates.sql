@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS exchange_rates (
+                                              id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                              date TEXT NOT NULL,
+                                              base TEXT NOT NULL,
+                                              quote TEXT NOT NULL,
+                                              rate TEXT NOT NULL,
+                                              UNIQUE (date, base, quote)
+);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS transaction_splits (
+                                                  id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                                  transaction_id INTEGER NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+                                                  amount INTEGER NOT NULL,
+                                                  category TEXT NOT NULL,
+                                                  memo TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_splits_transaction ON transaction_splits(transaction_id);
+
+-- ledger_lines is what reports and budgets aggregate over: one line per split, plus the
+-- part of the transaction that no split covers, which stays in the transaction's category.
+-- Transactions without splits appear as a single line.
+CREATE VIEW IF NOT EXISTS ledger_lines AS
+SELECT t.id AS transaction_id, t.date, t.description, t.account, t.currency, t.category,
+       t.amount - COALESCE(s.total, 0) AS amount, '' AS memo
+FROM transactions t
+         LEFT JOIN (SELECT transaction_id, SUM(amount) AS total FROM transaction_splits GROUP BY transaction_id) s
+                   ON s.transaction_id = t.id
+WHERE s.total IS NULL OR t.amount != s.total
+UNION ALL
+SELECT t.id, t.date, t.description, t.account, t.currency, sp.category, sp.amount, sp.memo
+FROM transaction_splits sp
+         JOIN transactions t ON t.id = sp.transaction_id;
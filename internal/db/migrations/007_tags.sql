@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tags (
+                                    id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                    name TEXT NOT NULL UNIQUE COLLATE NOCASE
+);
+
+CREATE TABLE IF NOT EXISTS transaction_tags (
+                                                transaction_id INTEGER NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+                                                tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+                                                PRIMARY KEY (transaction_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_tags_tag ON transaction_tags(tag_id);
+
+-- A budget targets either a category or, when tag is set, every transaction carrying that tag
+ALTER TABLE budgets ADD COLUMN tag TEXT;
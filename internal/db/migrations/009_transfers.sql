@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS transfers (
+                                         id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                         created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Both legs of a transfer carry the same transfer_id
+ALTER TABLE transactions ADD COLUMN transfer_id INTEGER REFERENCES transfers(id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_transfer ON transactions(transfer_id);
+
+-- Money moved between own accounts is neither income nor expense, so reports and
+-- budgets don't see transfers. Otherwise unchanged from 008_category_tree.
+DROP VIEW IF EXISTS ledger_lines;
+
+CREATE VIEW ledger_lines AS
+SELECT t.id AS transaction_id, t.date, t.description, t.account, t.currency, t.category_id,
+       COALESCE(cp.path, 'Uncategorized') AS category,
+       t.amount - COALESCE(s.total, 0) AS amount, '' AS memo
+FROM transactions t
+         LEFT JOIN (SELECT transaction_id, SUM(amount) AS total FROM transaction_splits GROUP BY transaction_id) s
+                   ON s.transaction_id = t.id
+         LEFT JOIN category_paths cp ON cp.id = t.category_id
+WHERE t.transfer_id IS NULL
+  AND (s.total IS NULL OR t.amount != s.total)
+UNION ALL
+SELECT t.id, t.date, t.description, t.account, t.currency, sp.category_id,
+       COALESCE(cp.path, 'Uncategorized'), sp.amount, sp.memo
+FROM transaction_splits sp
+         JOIN transactions t ON t.id = sp.transaction_id
+         LEFT JOIN category_paths cp ON cp.id = sp.category_id
+WHERE t.transfer_id IS NULL;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS recurring (
+                                         id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                         description TEXT NOT NULL,
+                                         amount INTEGER NOT NULL,
+                                         category_id INTEGER REFERENCES categories(id),
+                                         account TEXT,
+                                         currency TEXT,
+    -- "days", "weekly", "monthly" or "yearly", repeated every "interval" units
+                                         frequency TEXT NOT NULL,
+                                         interval INTEGER NOT NULL DEFAULT 1,
+    -- day of the month for monthly templates; later days are clamped to the month's end
+                                         day INTEGER,
+                                         start_date DATE NOT NULL,
+                                         end_date DATE,
+    -- the last date 'recurring run' booked up to
+                                         last_run DATE,
+                                         created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
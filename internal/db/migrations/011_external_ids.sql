@@ -0,0 +1,5 @@
+-- The bank's own id of an imported transaction (OFX FITID). Re-imports of the
+-- same statement are recognized by it instead of by date, description and amount.
+ALTER TABLE transactions ADD COLUMN external_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_external_id ON transactions(account, external_id);
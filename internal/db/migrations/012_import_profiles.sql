@@ -0,0 +1,19 @@
+-- Layouts of bank CSV exports, see models.ImportProfile. Columns are named by their
+-- header text or by their 1-based position.
+CREATE TABLE IF NOT EXISTS import_profiles (
+                                               name TEXT PRIMARY KEY COLLATE NOCASE,
+                                               delimiter TEXT NOT NULL DEFAULT ',',
+                                               header INTEGER NOT NULL DEFAULT 1,
+                                               skip_rows INTEGER NOT NULL DEFAULT 0,
+                                               date_column TEXT NOT NULL,
+    -- several columns are joined with " - ", separated by "+" here
+                                               description_columns TEXT NOT NULL,
+                                               amount_column TEXT,
+                                               debit_column TEXT,
+                                               credit_column TEXT,
+                                               category_column TEXT,
+                                               currency_column TEXT,
+                                               date_layout TEXT NOT NULL,
+                                               decimal_separator TEXT NOT NULL DEFAULT '.',
+                                               sign TEXT NOT NULL DEFAULT 'signed'
+);
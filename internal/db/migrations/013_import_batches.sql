@@ -0,0 +1,22 @@
+-- Every run of 'finance import' is a batch. Its transactions point back to it, so an
+-- import can be listed and undone, and a file imported before is recognized by its hash.
+CREATE TABLE IF NOT EXISTS import_batches (
+                                              id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                              file_name TEXT NOT NULL,
+    -- SHA-256 of the file's content, hex encoded
+                                              file_hash TEXT NOT NULL,
+                                              format TEXT NOT NULL,
+                                              account TEXT,
+                                              imported INTEGER NOT NULL DEFAULT 0,
+                                              duplicates INTEGER NOT NULL DEFAULT 0,
+                                              errors INTEGER NOT NULL DEFAULT 0,
+                                              created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_batches_hash ON import_batches(file_hash);
+
+ALTER TABLE transactions ADD COLUMN import_batch_id INTEGER REFERENCES import_batches(id);
+-- Line of the file the transaction was read from, where the format has lines
+ALTER TABLE transactions ADD COLUMN source_line INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_import_batch ON transactions(import_batch_id);
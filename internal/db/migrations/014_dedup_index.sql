@@ -0,0 +1,9 @@
+-- Duplicate checks during imports look transactions up by account, date and amount
+-- (see models.TransactionExists). Without an index every imported row scanned the table.
+-- Transactions without an account are stored with a NULL account; older rows may have ''.
+UPDATE transactions SET account = NULL WHERE account = '';
+
+CREATE INDEX IF NOT EXISTS idx_transactions_dedup ON transactions(account, date, amount);
+
+-- Transfer detection after an import pairs the new rows with opposite amounts.
+CREATE INDEX IF NOT EXISTS idx_transactions_amount ON transactions(amount);
@@ -0,0 +1,23 @@
+-- Rules become an ordered list with conditions beyond the description and actions beyond
+-- the category (see models.ApplyRules). Existing rules keep the order they had by ID.
+ALTER TABLE category_rules ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+UPDATE category_rules SET priority = id;
+ALTER TABLE category_rules ADD COLUMN enabled INTEGER NOT NULL DEFAULT 1;
+
+-- Conditions; NULL means no restriction
+ALTER TABLE category_rules ADD COLUMN min_amount INTEGER;
+ALTER TABLE category_rules ADD COLUMN max_amount INTEGER;
+ALTER TABLE category_rules ADD COLUMN sign INTEGER;
+ALTER TABLE category_rules ADD COLUMN account TEXT;
+ALTER TABLE category_rules ADD COLUMN date_from TEXT;
+ALTER TABLE category_rules ADD COLUMN date_to TEXT;
+ALTER TABLE category_rules ADD COLUMN if_category_id INTEGER REFERENCES categories(id);
+
+-- Actions besides category_id, which may now be NULL too
+ALTER TABLE category_rules ADD COLUMN set_description TEXT;
+ALTER TABLE category_rules ADD COLUMN set_payee TEXT;
+ALTER TABLE category_rules ADD COLUMN add_tags TEXT; -- comma separated, normalized
+ALTER TABLE category_rules ADD COLUMN transfer INTEGER NOT NULL DEFAULT 0;
+
+-- Who was paid, as a rule names it; descriptions of card payments rarely say it plainly
+ALTER TABLE transactions ADD COLUMN payee TEXT;
@@ -0,0 +1,5 @@
+-- Banks only keep their transaction ids (OFX FITID) unique per account. The statement's
+-- account (BANKID/ACCTID, IBAN) is stored with the id, so that files of two banks
+-- imported without --account, both with a NULL account, don't shadow each other's ids.
+-- Rows imported before have no external_account and still match by the id alone.
+ALTER TABLE transactions ADD COLUMN external_account TEXT;
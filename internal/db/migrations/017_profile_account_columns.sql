@@ -0,0 +1,4 @@
+-- Columns naming the account a row belongs to and, for a transfer, the account of the
+-- other leg, as the CSV export writes them. Both are optional, like category_column.
+ALTER TABLE import_profiles ADD COLUMN account_column TEXT;
+ALTER TABLE import_profiles ADD COLUMN transfer_column TEXT;
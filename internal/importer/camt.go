@@ -0,0 +1,300 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// The parts of an ISO 20022 cash management message the importer reads. Element names are
+// matched without their namespace, so every version of camt.052/053/054 reads the same way.
+type camtDocument struct {
+	Statements    []camtStatement `xml:"BkToCstmrStmt>Stmt"`           // camt.053
+	Reports       []camtStatement `xml:"BkToCstmrAcctRpt>Rpt"`         // camt.052
+	Notifications []camtStatement `xml:"BkToCstmrDbtCdtNtfctn>Ntfctn"` // camt.054
+}
+
+type camtStatement struct {
+	ID      string        `xml:"Id"`
+	Account camtAccount   `xml:"Acct"`
+	Balance []camtBalance `xml:"Bal"`
+	Entries []camtEntry   `xml:"Ntry"`
+}
+
+type camtAccount struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+	Type     string `xml:"Tp>Cd"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtBalance struct {
+	Type   string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount camtAmount `xml:"Amt"`
+	Sign   string     `xml:"CdtDbtInd"`
+	Date   camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Reference  string          `xml:"NtryRef"`
+	Amount     camtAmount      `xml:"Amt"`
+	Sign       string          `xml:"CdtDbtInd"`
+	Status     camtStatus      `xml:"Sts"`
+	Booking    camtDate        `xml:"BookgDt"`
+	Value      camtDate        `xml:"ValDt"`
+	BankRef    string          `xml:"AcctSvcrRef"`
+	Details    []camtTxDetails `xml:"NtryDtls>TxDtls"`
+	Additional string          `xml:"AddtlNtryInf"`
+}
+
+// camtStatus is "BOOK" in camt.053.001.02 and <Cd>BOOK</Cd> from version 8 on.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+func (s camtStatus) String() string {
+	if s.Code != "" {
+		return strings.TrimSpace(s.Code)
+	}
+	return strings.TrimSpace(s.Text)
+}
+
+type camtTxDetails struct {
+	BankRef    string      `xml:"Refs>AcctSvcrRef"`
+	TxID       string      `xml:"Refs>TxId"`
+	EndToEndID string      `xml:"Refs>EndToEndId"`
+	Amount     *camtAmount `xml:"Amt"`
+	Sign       string      `xml:"CdtDbtInd"`
+	Debtor     camtParty   `xml:"RltdPties>Dbtr"`
+	DebtorIBAN string      `xml:"RltdPties>DbtrAcct>Id>IBAN"`
+	Creditor   camtParty   `xml:"RltdPties>Cdtr"`
+	CreditIBAN string      `xml:"RltdPties>CdtrAcct>Id>IBAN"`
+	Remittance []string    `xml:"RmtInf>Ustrd"`
+	Reference  string      `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	Additional string      `xml:"AddtlTxInf"`
+}
+
+// camtParty holds a name directly (version 2) or inside <Pty> (version 8 and later).
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+func (p camtParty) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.PartyName
+}
+
+type camtImporter struct{}
+
+func (camtImporter) Name() string         { return FormatCAMT }
+func (camtImporter) Extensions() []string { return []string{".camt", ".xml"} }
+
+func (camtImporter) Parse(r io.Reader) ([]Statement, error) { return ParseCAMT(r) }
+
+// Detect looks for the camt namespace or the message's root element.
+func (camtImporter) Detect(head []byte) bool {
+	return bytes.Contains(head, []byte("urn:iso:std:iso:20022:tech:xsd:camt.05")) || bytes.Contains(head, []byte("<BkToCstmr"))
+}
+
+// ParseCAMT reads an ISO 20022 bank-to-customer statement (camt.053), intraday report
+// (camt.052) or debit/credit notification (camt.054). Every Stmt, Rpt or Ntfctn becomes a
+// statement with its closing booked balance. Entries that aren't booked yet are left out,
+// and an entry with several transaction details (a batch booking) yields one record each.
+func ParseCAMT(r io.Reader) ([]Statement, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid camt XML: %w", err)
+	}
+
+	all := append(append(doc.Statements, doc.Reports...), doc.Notifications...)
+	if len(all) == 0 {
+		return nil, fmt.Errorf("no statement, report or notification found in camt data")
+	}
+
+	statements := make([]Statement, 0, len(all))
+	for _, cs := range all {
+		s, err := camtToStatement(cs)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, s)
+	}
+	return statements, nil
+}
+
+func camtToStatement(cs camtStatement) (Statement, error) {
+	s := Statement{AccountID: cs.Account.IBAN, AccountType: cs.Account.Type}
+	if s.AccountID == "" {
+		s.AccountID = cs.Account.Other
+	}
+
+	var err error
+	if s.Currency, err = models.NormalizeCurrency(cs.Account.Currency); err != nil {
+		return s, fmt.Errorf("invalid camt account currency: %w", err)
+	}
+
+	for _, b := range cs.Balance {
+		if b.Type != "CLBD" {
+			continue
+		}
+		amount, err := camtMoney(b.Amount.Value, b.Sign)
+		if err != nil {
+			return s, fmt.Errorf("invalid camt closing balance: %w", err)
+		}
+		date, err := b.Date.parse()
+		if err != nil {
+			return s, fmt.Errorf("invalid camt closing balance date: %w", err)
+		}
+		s.Balance = &Balance{Amount: amount, Date: date}
+		if s.Currency == "" {
+			s.Currency, _ = models.NormalizeCurrency(b.Amount.Currency)
+		}
+	}
+
+	for _, e := range cs.Entries {
+		if status := e.Status.String(); status != "" && status != "BOOK" {
+			continue // pending or informational
+		}
+		s.Records = append(s.Records, camtRecords(e)...)
+	}
+	return s, nil
+}
+
+// camtRecords turns an entry into records: one for the entry, or one per transaction detail
+// of a batch booking if every detail carries its own amount.
+func camtRecords(e camtEntry) []Record {
+	batch := len(e.Details) > 1
+	for _, d := range e.Details {
+		if d.Amount == nil {
+			batch = false
+		}
+	}
+
+	if !batch {
+		var d camtTxDetails
+		if len(e.Details) > 0 {
+			d = e.Details[0]
+		}
+		rec := camtRecord(e, d, e.Amount, e.Sign)
+		rec.ExternalID = firstOf(e.BankRef, d.BankRef, e.Reference, d.TxID, d.EndToEndID)
+		return []Record{rec}
+	}
+
+	records := make([]Record, 0, len(e.Details))
+	for i, d := range e.Details {
+		sign := d.Sign
+		if sign == "" {
+			sign = e.Sign
+		}
+		rec := camtRecord(e, d, *d.Amount, sign)
+		rec.ExternalID = firstOf(d.BankRef, d.TxID, d.EndToEndID)
+		if rec.ExternalID == "" {
+			if ref := firstOf(e.BankRef, e.Reference); ref != "" {
+				rec.ExternalID = fmt.Sprintf("%s/%d", ref, i+1)
+			}
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func camtRecord(e camtEntry, d camtTxDetails, amount camtAmount, sign string) Record {
+	var rec Record
+
+	// The counterparty is the creditor of money going out and the debtor of money coming in
+	if sign == "DBIT" {
+		rec.Counterparty, rec.CounterpartyIBAN = d.Creditor.name(), d.CreditIBAN
+	} else {
+		rec.Counterparty, rec.CounterpartyIBAN = d.Debtor.name(), d.DebtorIBAN
+	}
+
+	remittance := strings.Join(d.Remittance, " ")
+	if remittance == "" {
+		remittance = firstOf(d.Reference, d.Additional, e.Additional)
+	}
+	var parts []string
+	for _, p := range []string{rec.Counterparty, remittance} {
+		if p = strings.Join(strings.Fields(p), " "); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	rec.Description = strings.Join(parts, " - ")
+
+	var err error
+	if rec.Currency, err = models.NormalizeCurrency(amount.Currency); err != nil {
+		rec.Err = err
+		return rec
+	}
+	if rec.Amount, err = camtMoney(amount.Value, sign); err != nil {
+		rec.Err = err
+		return rec
+	}
+
+	if rec.Date, err = e.Booking.parse(); err != nil {
+		rec.Err = fmt.Errorf("invalid booking date")
+		return rec
+	}
+	if e.Value.Date != "" || e.Value.DateTime != "" {
+		if rec.ValueDate, err = e.Value.parse(); err != nil {
+			rec.Err = fmt.Errorf("invalid value date")
+		}
+	}
+	return rec
+}
+
+// camtMoney reads an unsigned camt amount; debits are negative. For a reversal the
+// indicator already gives the direction of the reversing booking.
+func camtMoney(value, sign string) (models.Money, error) {
+	m, err := models.ParseMoney(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	switch sign {
+	case "DBIT":
+		return -m.Abs(), nil
+	case "CRDT":
+		return m.Abs(), nil
+	default:
+		return 0, fmt.Errorf("invalid credit/debit indicator %q", sign)
+	}
+}
+
+func (d camtDate) parse() (time.Time, error) {
+	s := strings.TrimSpace(d.Date)
+	if s == "" {
+		s = strings.TrimSpace(d.DateTime)
+	}
+	if len(s) < 10 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return time.Parse("2006-01-02", s[:10])
+}
+
+// firstOf returns the first value that is set. "NOTPROVIDED" is how camt writes a missing id.
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" && v != "NOTPROVIDED" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,335 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// csvLayout holds the positions of a profile's columns in one file; -1 means unused.
+type csvLayout struct {
+	date, amount, debit, credit, category, currency int
+	account, transfer                               int
+	description                                     []int
+}
+
+// CSV reads CSV files laid out as Profile describes; the zero value uses the generic
+// layout. CSV has no marker, so it is only chosen by extension.
+type CSV struct {
+	Profile *models.ImportProfile
+}
+
+func (CSV) Name() string            { return FormatCSV }
+func (CSV) Detect(head []byte) bool { return false }
+func (CSV) Extensions() []string    { return []string{".csv", ".txt"} }
+
+// Parse returns the rows as a single statement without account details.
+func (c CSV) Parse(r io.Reader) ([]Statement, error) {
+	records, err := ParseCSV(r, c.profile())
+	if err != nil {
+		return nil, err
+	}
+	return []Statement{{Records: records}}, nil
+}
+
+// Stream hands out the rows one at a time, reading the file as it goes.
+func (c CSV) Stream(r io.Reader, fn func(Record) error) error {
+	return streamCSV(r, c.profile(), fn)
+}
+
+func (c CSV) profile() models.ImportProfile {
+	if c.Profile != nil {
+		return *c.Profile
+	}
+	for _, p := range models.BuiltinProfiles {
+		if p.Name == models.DefaultProfile {
+			return p
+		}
+	}
+	panic("importer: no built-in " + models.DefaultProfile + " profile")
+}
+
+// ParseCSV reads a bank's CSV export laid out as the profile describes. Without a header
+// row, a first row whose date doesn't parse is taken as a header and skipped.
+func ParseCSV(r io.Reader, p models.ImportProfile) ([]Record, error) {
+	var records []Record
+	err := streamCSV(r, p, func(rec Record) error {
+		records = append(records, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// streamCSV is ParseCSV calling fn for each record instead of collecting them.
+func streamCSV(r io.Reader, p models.ImportProfile, fn func(Record) error) error {
+	reader := newCSVReader(r, p)
+	reader.ReuseRecord = true // rows are parsed right away, not kept
+	var layout *csvLayout
+	for n := 1; ; n++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV data: %w", err)
+		}
+		if n == 1 && len(row) > 0 {
+			row[0] = strings.TrimPrefix(row[0], "\ufeff")
+		}
+		if n <= p.SkipRows {
+			continue
+		}
+
+		// The first row after the skipped ones tells where the columns are
+		if layout == nil {
+			var header []string
+			if p.Header {
+				header = row
+			}
+			l, err := newCSVLayout(p, header)
+			if err != nil {
+				return err
+			}
+			layout = &l
+			if p.Header {
+				continue
+			}
+		}
+
+		if blankRow(row) {
+			continue
+		}
+		rec := parseCSVRow(row, p, *layout)
+		rec.Line = n
+		if rec.Err != nil && n == p.SkipRows+1 && rec.Date.IsZero() {
+			continue // a header the profile doesn't declare
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+func parseCSVRow(row []string, p models.ImportProfile, l csvLayout) Record {
+	field := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var rec Record
+	var parts []string
+	for _, i := range l.description {
+		if s := field(i); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	rec.Description = strings.Join(parts, " - ")
+	rec.Category = field(l.category)
+	rec.Account, rec.Transfer = field(l.account), field(l.transfer)
+
+	date, err := time.Parse(p.DateLayout, field(l.date))
+	if err != nil {
+		rec.Err = fmt.Errorf("invalid date %q", field(l.date))
+		return rec
+	}
+	rec.Date = date
+
+	if rec.Amount, err = csvAmount(p, field(l.amount), field(l.debit), field(l.credit)); err != nil {
+		rec.Err = err
+		return rec
+	}
+
+	if rec.Currency, err = models.NormalizeCurrency(field(l.currency)); err != nil {
+		rec.Err = err
+	}
+	return rec
+}
+
+// csvAmount applies the profile's sign convention.
+func csvAmount(p models.ImportProfile, amount, debit, credit string) (models.Money, error) {
+	switch p.Sign {
+	case models.SignDebitCredit:
+		if debit == "" && credit == "" {
+			return 0, fmt.Errorf("neither debit nor credit is set")
+		}
+		var out, in models.Money
+		var err error
+		if debit != "" {
+			if out, err = ParseAmount(debit, p.Decimal); err != nil {
+				return 0, err
+			}
+		}
+		if credit != "" {
+			if in, err = ParseAmount(credit, p.Decimal); err != nil {
+				return 0, err
+			}
+		}
+		// Some banks write debits as negative numbers, others don't
+		return in.Abs() - out.Abs(), nil
+	case models.SignInverted:
+		m, err := ParseAmount(amount, p.Decimal)
+		return -m, err
+	default:
+		return ParseAmount(amount, p.Decimal)
+	}
+}
+
+// ParseAmount reads an amount the way banks write it: with the given decimal separator,
+// optional thousands separators ("1.234,56" or "1,234.56", also spaces or apostrophes)
+// and possibly a trailing minus ("12,00-"). Thousands separators are only accepted
+// between groups of three digits before the decimal separator, so that "15,99" read
+// with a "." decimal is an error rather than 1599.
+func ParseAmount(s, decimal string) (models.Money, error) {
+	thousands := ","
+	if decimal == "," {
+		thousands = "."
+	}
+	str := strings.TrimSpace(s)
+	if strings.HasSuffix(str, "-") {
+		str = "-" + strings.TrimSpace(strings.TrimSuffix(str, "-"))
+	}
+	sign := ""
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		sign, str = str[:1], strings.TrimSpace(str[1:])
+	}
+
+	whole, frac, hasDecimal := strings.Cut(str, decimal)
+	whole = strings.NewReplacer(" ", thousands, "\u00a0", thousands, "'", thousands).Replace(whole)
+	if strings.Contains(whole, thousands) {
+		groups := strings.Split(whole, thousands)
+		for i, g := range groups {
+			if (i == 0 && (len(g) == 0 || len(g) > 3)) || (i > 0 && len(g) != 3) {
+				return 0, fmt.Errorf("invalid amount %q", s)
+			}
+		}
+		whole = strings.Join(groups, "")
+	}
+	str = sign + whole
+	if hasDecimal {
+		str += "." + frac
+	}
+
+	m, err := models.ParseMoney(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return m, nil
+}
+
+func newCSVLayout(p models.ImportProfile, header []string) (csvLayout, error) {
+	var l csvLayout
+	var err error
+	index := func(col string) int {
+		if col == "" || err != nil {
+			return -1
+		}
+		var i int
+		i, err = columnIndex(col, header)
+		return i
+	}
+
+	l.date = index(p.Date)
+	for _, c := range p.Description {
+		l.description = append(l.description, index(c))
+	}
+	l.amount = index(p.Amount)
+	l.debit = index(p.Debit)
+	l.credit = index(p.Credit)
+	l.category = index(p.Category)
+	l.currency = index(p.Currency)
+	l.account = index(p.Account)
+	l.transfer = index(p.Transfer)
+	return l, err
+}
+
+// columnIndex finds a column by its 1-based position or by its header text.
+func columnIndex(col string, header []string) (int, error) {
+	if n, err := strconv.Atoi(col); err == nil && n > 0 {
+		return n - 1, nil
+	}
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(col)) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("column %q not found in the header", col)
+}
+
+// DetectProfile returns the profile whose header columns all appear in the file, preferring
+// the one that reads the most columns. Profiles without a header row can't be detected.
+func DetectProfile(data []byte, profiles []models.ImportProfile) (*models.ImportProfile, bool) {
+	var best *models.ImportProfile
+	bestScore := 0
+	for i, p := range profiles {
+		if !p.Header {
+			continue
+		}
+		rows, err := readCSV(bytes.NewReader(data), p, p.SkipRows+1)
+		if err != nil || len(rows) <= p.SkipRows {
+			continue
+		}
+		header := rows[p.SkipRows]
+
+		matched := true
+		for _, col := range p.Columns() {
+			if _, err := columnIndex(col, header); err != nil {
+				matched = false
+				break
+			}
+		}
+		if matched && len(p.Columns()) > bestScore {
+			best, bestScore = &profiles[i], len(p.Columns())
+		}
+	}
+	return best, best != nil
+}
+
+// readCSV reads up to limit rows (all if limit < 0) with the profile's delimiter.
+// A byte order mark, as written by spreadsheet programs, is dropped.
+func readCSV(r io.Reader, p models.ImportProfile, limit int) ([][]string, error) {
+	reader := newCSVReader(r, p)
+	var rows [][]string
+	for limit < 0 || len(rows) < limit {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV data: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+	}
+	return rows, nil
+}
+
+func newCSVReader(r io.Reader, p models.ImportProfile) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma, _ = utf8.DecodeRuneInString(p.Delimiter)
+	reader.FieldsPerRecord = -1 // Allow variable fields, e.g. in the lines before the header
+	reader.LazyQuotes = true
+	return reader
+}
+
+func blankRow(row []string) bool {
+	for _, f := range row {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
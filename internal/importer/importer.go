@@ -0,0 +1,79 @@
+// Package importer reads bank statement files into statements and records, and books
+// them as transactions. Each file format is an Importer; the registry picks the one
+// that recognizes a file, and the Pipeline does the rest for all of them.
+package importer
+
+import (
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// Statement is the part of a file that belongs to one bank account.
+type Statement struct {
+	BankID      string   // e.g. the routing number, if the file names one
+	AccountID   string   // the bank's account number
+	AccountType string   // e.g. "CHECKING" or "CREDITCARD"
+	Currency    string   // default currency of the records; empty if the file doesn't say
+	Opening     *Balance // MT940: the balance before the first record
+	Balance     *Balance // the closing balance
+	Records     []Record
+}
+
+// BankAccount identifies the statement's bank account, "BANKID/ACCTID" or just the
+// account number; empty if the file doesn't name it. The bank's ids of the records are
+// unique within it.
+func (s Statement) BankAccount() string {
+	if s.BankID != "" && s.AccountID != "" {
+		return s.BankID + "/" + s.AccountID
+	}
+	return s.AccountID
+}
+
+// Difference returns by how much the records fall short of explaining the change from the
+// opening to the closing balance. ok is false unless the statement has both balances.
+func (s Statement) Difference() (diff models.Money, ok bool) {
+	if s.Opening == nil || s.Balance == nil {
+		return 0, false
+	}
+	diff = s.Balance.Amount - s.Opening.Amount
+	for _, r := range s.Records {
+		if r.Err == nil {
+			diff -= r.Amount
+		}
+	}
+	return diff, true
+}
+
+// Balance is the balance a bank reported for an account.
+type Balance struct {
+	Amount models.Money
+	Date   time.Time
+}
+
+// Record is one transaction read from a file. Err is set if the entry couldn't be read;
+// the other fields are then incomplete.
+type Record struct {
+	Line             int       // line of the file the record starts on; 0 for formats without lines (camt)
+	Date             time.Time // booking date
+	ValueDate        time.Time // camt, MT940: when the money is available; zero if not given
+	Description      string
+	Amount           models.Money
+	Category         string // as given in the file; empty means auto-categorize
+	Transfer         string // QIF, CSV: the own account the money went to or came from, instead of a category
+	Account          string // CSV: the own account the row belongs to, if the file names one
+	Currency         string // empty means the statement's or the account's currency
+	ExternalID       string // the bank's own id, used to recognize re-imports
+	Counterparty     string // camt, MT940: name of the other party
+	CounterpartyIBAN string // camt, MT940: account of the other party
+	Splits           []Split
+	Err              error
+}
+
+// Split is one split line of a record, as QIF files carry them.
+type Split struct {
+	Amount   models.Money
+	Category string
+	Transfer string
+	Memo     string
+}
@@ -0,0 +1,294 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// mt940Field is one ":tag:" field of an MT940 message with its continuation lines.
+type mt940Field struct {
+	line  int
+	tag   string
+	lines []string
+}
+
+// mt940Line matches the first line of a :61: statement line: value date, optional entry
+// date, debit/credit mark, optional funds code, amount, transaction type, customer reference
+// and, after "//", the bank's reference.
+var mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(R?[DC])([A-Z])?([0-9]+,[0-9]*)([NFS][A-Z0-9]{3})(.*?)(?://(.*))?$`)
+
+// mt940Subfield finds the "?NN" subfields of a structured (German) :86: narrative.
+var mt940Subfield = regexp.MustCompile(`\?(\d{2})`)
+
+// mt940Start matches the beginning of an MT940 file: an optional SWIFT header block,
+// then the :20: transaction reference.
+var mt940Start = regexp.MustCompile(`(?s)^(\{1:[^}]*\}.*?\{4:\s*)?:20:`)
+
+type mt940Importer struct{}
+
+func (mt940Importer) Name() string         { return FormatMT940 }
+func (mt940Importer) Extensions() []string { return []string{".sta", ".mt940", ".940"} }
+func (mt940Importer) Detect(head []byte) bool {
+	return mt940Start.Match(head)
+}
+
+func (mt940Importer) Parse(r io.Reader) ([]Statement, error) { return ParseMT940(r) }
+
+// ParseMT940 reads a SWIFT MT940 customer statement file. Every message (starting at
+// :20:) becomes a statement with its opening (:60F:/:60M:) and closing (:62F:/:62M:)
+// balance; each :61: line becomes a record, described by the :86: narrative after it.
+func ParseMT940(r io.Reader) ([]Statement, error) {
+	fields, err := readMT940Fields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []Statement
+	var s *Statement
+	narrated := true // whether the last :61: line already has its :86: narrative
+	for _, f := range fields {
+		if f.tag == "20" {
+			statements = append(statements, Statement{})
+			s = &statements[len(statements)-1]
+			narrated = true
+			continue
+		}
+		if s == nil {
+			return nil, fmt.Errorf("line %d: MT940 field :%s: before the first :20:", f.line, f.tag)
+		}
+
+		value := strings.Join(f.lines, "\n")
+		switch f.tag {
+		case "25":
+			s.AccountID = strings.TrimSpace(value)
+		case "60F", "60M":
+			b, currency, err := parseMT940Balance(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid opening balance: %w", f.line, err)
+			}
+			s.Opening, s.Currency = b, currency
+		case "62F", "62M":
+			b, currency, err := parseMT940Balance(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid closing balance: %w", f.line, err)
+			}
+			s.Balance = b
+			if s.Currency == "" {
+				s.Currency = currency
+			}
+		case "61":
+			rec := parseMT940Line(f.lines)
+			rec.Line = f.line
+			s.Records = append(s.Records, rec)
+		case "86":
+			// The narrative belongs to the :61: line just before it; one after the closing
+			// balance is information about the whole statement
+			if !narrated {
+				name, iban, text := parseMT940Narrative(f.lines)
+				rec := &s.Records[len(s.Records)-1]
+				rec.Counterparty, rec.CounterpartyIBAN = name, iban
+				var parts []string
+				for _, p := range []string{name, text} {
+					if p != "" {
+						parts = append(parts, p)
+					}
+				}
+				if len(parts) > 0 {
+					rec.Description = strings.Join(parts, " - ")
+				}
+			}
+		}
+		narrated = f.tag != "61"
+	}
+
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no MT940 statement (:20:) found")
+	}
+	return statements, nil
+}
+
+// readMT940Fields splits the file into fields. SWIFT block headers ("{1:...}{4:") and the
+// "-" or "-}" lines ending a message are dropped.
+func readMT940Fields(r io.Reader) ([]mt940Field, error) {
+	var fields []mt940Field
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			line = line[i+len("{4:"):]
+		}
+		if trimmed := strings.TrimSpace(line); trimmed == "" || trimmed == "-" || trimmed == "-}" || strings.HasPrefix(trimmed, "{") {
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			if end := strings.Index(line[1:], ":"); end > 0 && end <= 4 {
+				fields = append(fields, mt940Field{line: n, tag: line[1 : end+1], lines: []string{line[end+2:]}})
+				continue
+			}
+		}
+		if len(fields) > 0 {
+			last := &fields[len(fields)-1]
+			last.lines = append(last.lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read MT940 data: %w", err)
+	}
+	return fields, nil
+}
+
+// parseMT940Balance reads a balance such as "C240229EUR1234,56".
+func parseMT940Balance(value string) (*Balance, string, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 11 {
+		return nil, "", fmt.Errorf("%q is too short", value)
+	}
+	date, err := parseMT940Date(value[1:7])
+	if err != nil {
+		return nil, "", err
+	}
+	currency, err := models.NormalizeCurrency(value[7:10])
+	if err != nil {
+		return nil, "", err
+	}
+	amount, err := ParseAmount(value[10:], ",")
+	if err != nil {
+		return nil, "", err
+	}
+	switch value[0] {
+	case 'D':
+		amount = -amount
+	case 'C':
+	default:
+		return nil, "", fmt.Errorf("invalid debit/credit mark %q", value[0])
+	}
+	return &Balance{Amount: amount, Date: date}, currency, nil
+}
+
+// parseMT940Line reads a :61: field. Its optional second line holds supplementary details,
+// used as the description if no :86: follows.
+func parseMT940Line(lines []string) Record {
+	var rec Record
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if m == nil {
+		rec.Err = fmt.Errorf("invalid statement line %q", lines[0])
+		return rec
+	}
+	valueDate, entryDate, mark, amount, customerRef, bankRef := m[1], m[2], m[3], m[5], m[7], m[8]
+
+	var err error
+	if rec.ValueDate, err = parseMT940Date(valueDate); err != nil {
+		rec.Err = err
+		return rec
+	}
+	rec.Date = rec.ValueDate
+	if entryDate != "" {
+		if rec.Date, err = mt940EntryDate(entryDate, rec.ValueDate); err != nil {
+			rec.Err = err
+			return rec
+		}
+	}
+
+	if rec.Amount, err = ParseAmount(amount, ","); err != nil {
+		rec.Err = err
+		return rec
+	}
+	// A reversed debit (RD) brings money back, a reversed credit (RC) takes it away
+	if mark == "D" || mark == "RC" {
+		rec.Amount = -rec.Amount
+	}
+
+	rec.ExternalID = firstOf(mt940Ref(bankRef), mt940Ref(customerRef))
+	if len(lines) > 1 {
+		rec.Description = strings.TrimSpace(strings.Join(lines[1:], " "))
+	}
+	return rec
+}
+
+func mt940Ref(ref string) string {
+	if ref = strings.TrimSpace(ref); ref == "NONREF" {
+		return ""
+	}
+	return ref
+}
+
+// parseMT940Narrative reads a :86: field. The structured variant of German banks
+// ("166?00GUTSCHRIFT?20...?32Name") is split into counterparty, IBAN and remittance text;
+// anything else is taken as free text.
+func parseMT940Narrative(lines []string) (name, iban, text string) {
+	joined := strings.Join(lines, "")
+	if len(joined) < 4 || joined[3] != '?' || !isNumeric(joined[:3]) {
+		return "", "", strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+	}
+
+	sub := map[int]string{}
+	marks := mt940Subfield.FindAllStringSubmatchIndex(joined, -1)
+	for i, m := range marks {
+		end := len(joined)
+		if i+1 < len(marks) {
+			end = marks[i+1][0]
+		}
+		code, _ := strconv.Atoi(joined[m[2]:m[3]])
+		sub[code] = joined[m[1]:end]
+	}
+
+	var purpose strings.Builder
+	for _, code := range []int{20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 60, 61, 62, 63} {
+		purpose.WriteString(sub[code])
+	}
+	text = strings.Join(strings.Fields(purpose.String()), " ")
+	if text == "" {
+		text = strings.TrimSpace(sub[0]) // the posting text, e.g. "KARTENZAHLUNG"
+	}
+	name = strings.Join(strings.Fields(sub[32]+sub[33]), " ")
+	return name, strings.TrimSpace(sub[31]), text
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// parseMT940Date reads a YYMMDD date. Years 69-99 are 19xx.
+func parseMT940Date(s string) (time.Time, error) {
+	t, err := time.Parse("060102", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+// mt940EntryDate reads the MMDD booking date, which lies in the value date's year unless
+// the two straddle a new year.
+func mt940EntryDate(mmdd string, value time.Time) (time.Time, error) {
+	month, err1 := strconv.Atoi(mmdd[:2])
+	day, err2 := strconv.Atoi(mmdd[2:])
+	if err1 != nil || err2 != nil || month < 1 || month > 12 {
+		return time.Time{}, fmt.Errorf("invalid entry date %q", mmdd)
+	}
+	year := value.Year()
+	switch {
+	case month == 12 && value.Month() == time.January:
+		year--
+	case month == 1 && value.Month() == time.December:
+		year++
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid entry date %q", mmdd)
+	}
+	return t, nil
+}
@@ -0,0 +1,235 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// ofxNode is an element of an OFX document. Leaf elements carry a value, aggregates children.
+type ofxNode struct {
+	name     string
+	value    string
+	line     int // where its tag starts
+	children []*ofxNode
+}
+
+// child returns the first direct child with the given name.
+func (n *ofxNode) child(name string) *ofxNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// text returns the value of the leaf at path below n, or "" if it doesn't exist.
+func (n *ofxNode) text(path ...string) string {
+	for _, name := range path {
+		if n = n.child(name); n == nil {
+			return ""
+		}
+	}
+	return n.value
+}
+
+// findAll returns every element below n (at any depth) with one of the given names.
+func (n *ofxNode) findAll(names ...string) []*ofxNode {
+	var found []*ofxNode
+	for _, c := range n.children {
+		for _, name := range names {
+			if c.name == name {
+				found = append(found, c)
+			}
+		}
+		found = append(found, c.findAll(names...)...)
+	}
+	return found
+}
+
+type ofxImporter struct{}
+
+func (ofxImporter) Name() string         { return FormatOFX }
+func (ofxImporter) Extensions() []string { return []string{".ofx", ".qfx"} }
+
+func (ofxImporter) Parse(r io.Reader) ([]Statement, error) { return ParseOFX(r) }
+
+// Detect looks for the SGML header of OFX 1.x or the root element of OFX 2.x.
+func (ofxImporter) Detect(head []byte) bool {
+	upper := bytes.ToUpper(head)
+	return bytes.HasPrefix(upper, []byte("OFXHEADER")) || bytes.Contains(upper, []byte("<OFX>"))
+}
+
+// ParseOFX reads an OFX or QFX file. Both variants are understood: OFX 1.x is SGML and
+// leaves the tags of values unclosed (<TRNAMT>-50.00), OFX 2.x is XML. Every bank
+// (STMTRS) and credit card (CCSTMTRS) statement in the file is returned.
+func ParseOFX(r io.Reader) ([]Statement, error) {
+	root, err := parseOFXTree(r)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := root.findAll("STMTRS", "CCSTMTRS")
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no bank or credit card statement found in OFX data")
+	}
+
+	statements := make([]Statement, 0, len(nodes))
+	for _, n := range nodes {
+		s, err := ofxStatement(n)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, s)
+	}
+	return statements, nil
+}
+
+func ofxStatement(n *ofxNode) (Statement, error) {
+	s := Statement{}
+
+	currency, err := models.NormalizeCurrency(n.text("CURDEF"))
+	if err != nil {
+		return s, fmt.Errorf("invalid OFX currency: %w", err)
+	}
+	s.Currency = currency
+
+	if from := n.child("BANKACCTFROM"); from != nil {
+		s.AccountID = from.text("ACCTID")
+		s.AccountType = from.text("ACCTTYPE")
+		s.BankID = from.text("BANKID")
+	} else if from := n.child("CCACCTFROM"); from != nil {
+		s.AccountID = from.text("ACCTID")
+		s.AccountType = "CREDITCARD"
+	}
+
+	if bal := n.child("LEDGERBAL"); bal != nil {
+		amount, err := models.ParseMoney(bal.text("BALAMT"))
+		if err != nil {
+			return s, fmt.Errorf("invalid OFX ledger balance: %w", err)
+		}
+		date, err := parseOFXDate(bal.text("DTASOF"))
+		if err != nil {
+			return s, fmt.Errorf("invalid OFX ledger balance date: %w", err)
+		}
+		s.Balance = &Balance{Amount: amount, Date: date}
+	}
+
+	if list := n.child("BANKTRANLIST"); list != nil {
+		for _, t := range list.findAll("STMTTRN") {
+			s.Records = append(s.Records, ofxRecord(t))
+		}
+	}
+	return s, nil
+}
+
+func ofxRecord(t *ofxNode) Record {
+	rec := Record{ExternalID: t.text("FITID"), Line: t.line}
+
+	// Some banks put the payee into an aggregate instead of NAME
+	description := t.text("NAME")
+	if description == "" {
+		description = t.text("PAYEE", "NAME")
+	}
+	description = strings.TrimSpace(description)
+	if memo := strings.TrimSpace(t.text("MEMO")); memo != "" {
+		description += " - " + memo
+	}
+	rec.Description = description
+
+	var err error
+	if rec.Date, err = parseOFXDate(t.text("DTPOSTED")); err != nil {
+		rec.Err = fmt.Errorf("invalid date")
+		return rec
+	}
+	if rec.Amount, err = models.ParseMoney(t.text("TRNAMT")); err != nil {
+		rec.Err = fmt.Errorf("invalid amount")
+	}
+	return rec
+}
+
+// parseOFXTree builds the element tree of an OFX document. The SGML header
+// ("OFXHEADER:100" lines), XML declarations and processing instructions are skipped.
+// An element followed by text is a leaf; its closing tag is optional. Closing an
+// aggregate also closes every element still open inside it: those never had a value nor
+// a closing tag, so they are empty leaves such as "<MEMO>" on a line of its own, and the
+// elements read after them move up to the aggregate.
+func parseOFXTree(r io.Reader) (*ofxNode, error) {
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+	top := func() *ofxNode { return stack[len(stack)-1] }
+
+	in := bufio.NewReader(r)
+	var open *ofxNode // element whose first content hasn't been seen yet
+	line := 1
+	for {
+		text, err := in.ReadString('<')
+		line += strings.Count(text, "\n")
+		if value := strings.TrimSpace(strings.TrimSuffix(text, "<")); value != "" && open != nil {
+			// <NAME>value: a leaf, it doesn't take children
+			open.value = html.UnescapeString(value)
+			stack = stack[:len(stack)-1]
+		}
+		open = nil
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tag, err := in.ReadString('>')
+		if err != nil {
+			return nil, fmt.Errorf("unterminated OFX tag <%s", tag)
+		}
+		tagLine := line
+		line += strings.Count(tag, "\n")
+		tag = strings.TrimSuffix(tag, ">")
+
+		switch {
+		case strings.HasPrefix(tag, "?"), strings.HasPrefix(tag, "!"):
+			continue
+		case strings.HasPrefix(tag, "/"):
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					for j := len(stack) - 1; j > i; j-- {
+						parent := stack[j-1]
+						parent.children = append(parent.children, stack[j].children...)
+						stack[j].children = nil
+					}
+					stack = stack[:i]
+					break
+				}
+			}
+		default:
+			n := &ofxNode{name: strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(tag, "/"))), line: tagLine}
+			top().children = append(top().children, n)
+			if !strings.HasSuffix(tag, "/") {
+				stack = append(stack, n)
+				open = n
+			}
+		}
+	}
+
+	if len(root.children) == 0 {
+		return nil, fmt.Errorf("no OFX data found")
+	}
+	return root, nil
+}
+
+// parseOFXDate reads an OFX date such as "20231025", "20231025120000" or
+// "20231025120000.000[-5:EST]". Only the day matters.
+func parseOFXDate(dateStr string) (time.Time, error) {
+	if len(dateStr) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date length")
+	}
+	return time.Parse("20060102", dateStr[:8])
+}
@@ -0,0 +1,439 @@
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// Status says what the pipeline did, or would do, with a record.
+type Status string
+
+const (
+	StatusNew       Status = "new" // will be stored by Commit
+	StatusImported  Status = "imported"
+	StatusDuplicate Status = "duplicate" // already in the database or earlier in the file
+	StatusInvalid   Status = "invalid"   // couldn't be read from the file
+	StatusRejected  Status = "rejected"  // left out during the review
+)
+
+// Outcome is what happened to one record.
+type Outcome struct {
+	Record      Record
+	Transaction *models.Transaction // what the record became; nil if it is invalid
+	Status      Status
+	Reason      string            // why the record is a duplicate, invalid or rejected
+	Warnings    []string          // problems that didn't stop the import, e.g. a split line
+	Rules       models.RuleResult // what the matching rules left to do once it is stored
+	Learned     float64           // confidence of the category learned from history; 0 if it wasn't
+
+	// Match is the stored transaction a likely duplicate seems to repeat, when the
+	// duplicate was found by fuzzy matching rather than being exactly the same.
+	Match *models.Transaction
+}
+
+// Pipeline books records as transactions of one account, or of the account a statement
+// or record names if it is one of Accounts. Every record is run through the
+// rules (see models.RuleSet.Apply), categorized by the Classifier if the rules and the
+// file left it uncategorized, normalized, checked against the existing transactions
+// and, if new, stored together with its split lines, the rules' tags and transfer link.
+//
+// Check and Commit are the two halves of Import: Check decides what would happen without
+// writing, so the outcomes can be shown or reviewed before Commit stores the new ones.
+//
+// With Fuzzy enabled, a record that isn't exactly a stored transaction but closely matches
+// one from another source, e.g. the CSV export of a card payment the OFX statement already
+// brought, is a duplicate too. Each stored transaction is matched at most once.
+//
+// Between Begin and End everything runs in one database transaction, so an import is
+// stored completely or not at all. Outside of it, each Commit is a transaction of its own.
+type Pipeline struct {
+	DB      *sql.DB
+	Account *models.Account   // nil leaves the transactions without an account
+	Rules   *models.RuleSet   // nil applies no rules
+	Fuzzy   models.FuzzyMatch // zero turns fuzzy matching off
+	Source  string            // importer name; fuzzy matches come from other sources only
+
+	// Accounts are the open accounts a file may name by their name: QIF's !Account
+	// blocks and "[Account]" transfers, the account and transfer columns of a CSV file.
+	// A statement or record of one of them is booked there instead of in Account.
+	Accounts []models.Account
+
+	// Classifier guesses the category of records that are still uncategorized; its guess
+	// is taken if it is at least Confidence likely. nil learns nothing.
+	Classifier *models.Classifier
+	Confidence float64
+
+	tx      *models.ImportTx
+	batch   int64          // import batch the transactions are filed under, if any
+	seen    *batchIndex    // transactions found new by Check so far
+	matched map[int64]bool // stored transactions already taken as a record's fuzzy match
+}
+
+// Begin starts the database transaction of an import. With a batch, the batch is
+// recorded and the transactions are filed under it.
+func (p *Pipeline) Begin(batch *models.ImportBatch) error {
+	tx, err := models.BeginImport(p.DB)
+	if err != nil {
+		return err
+	}
+	if batch != nil {
+		if err := tx.CreateBatch(batch); err != nil {
+			tx.Rollback()
+			return err
+		}
+		p.batch = batch.ID
+	}
+	p.tx = tx
+	return nil
+}
+
+// End stores the batch's counts, if there is a batch, and commits the import.
+func (p *Pipeline) End(batch *models.ImportBatch) error {
+	if batch != nil {
+		if err := p.tx.FinishBatch(batch); err != nil {
+			return err
+		}
+	}
+	err := p.tx.Commit()
+	p.tx = nil
+	if err != nil {
+		return fmt.Errorf("failed to commit the import: %w", err)
+	}
+	return nil
+}
+
+// Abort rolls back the import started by Begin. It does nothing if End was reached.
+func (p *Pipeline) Abort() {
+	if p.tx != nil {
+		p.tx.Rollback()
+		p.tx = nil
+	}
+}
+
+// Import runs the records of one statement through the pipeline. The error is set only
+// if the database failed; problems with single records are reported in their outcome.
+func (p *Pipeline) Import(s Statement) ([]Outcome, error) {
+	outcomes, err := p.Check(s)
+	if err != nil {
+		return nil, err
+	}
+	return outcomes, p.Commit(outcomes)
+}
+
+// ImportRecord runs a single record through the pipeline, for importers that hand out
+// records one at a time. It needs Begin.
+func (p *Pipeline) ImportRecord(s Statement, rec Record) (Outcome, error) {
+	if p.tx == nil {
+		return Outcome{}, fmt.Errorf("ImportRecord called outside of Begin and End")
+	}
+	o, err := p.CheckRecord(s, rec)
+	if err != nil || o.Status != StatusNew {
+		return o, err
+	}
+	return o, p.store(&o)
+}
+
+// Check builds the transactions of a statement and finds the duplicates among them,
+// without writing anything. A record repeating one this pipeline checked before, e.g.
+// earlier in the file, counts as a duplicate too, as it would once that one is stored.
+func (p *Pipeline) Check(s Statement) ([]Outcome, error) {
+	outcomes := make([]Outcome, 0, len(s.Records))
+	for _, rec := range s.Records {
+		o, err := p.CheckRecord(s, rec)
+		if err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, nil
+}
+
+// CheckRecord is Check for a single record of the statement.
+func (p *Pipeline) CheckRecord(s Statement, rec Record) (Outcome, error) {
+	if p.seen == nil {
+		p.seen = newBatchIndex()
+	}
+
+	o := Outcome{Record: rec}
+	if rec.Err != nil {
+		o.Status, o.Reason = StatusInvalid, rec.Err.Error()
+		if rec.Line > 0 {
+			o.Reason = fmt.Sprintf("line %d: %v", rec.Line, rec.Err)
+		}
+		return o, nil
+	}
+
+	tr, result := p.Transaction(s, rec)
+	o.Transaction, o.Rules = tr, result
+	o.Learned = p.learnCategory(tr)
+	var exists bool
+	var err error
+	if p.tx != nil {
+		exists, err = p.tx.TransactionExists(tr)
+	} else {
+		exists, err = models.TransactionExists(p.DB, tr)
+	}
+	if err != nil {
+		return o, fmt.Errorf("failed to check duplicate: %w", err)
+	}
+
+	switch {
+	case exists && tr.ExternalID != "":
+		o.Status, o.Reason = StatusDuplicate, fmt.Sprintf("reference %s already imported", tr.ExternalID)
+	case exists:
+		o.Status, o.Reason = StatusDuplicate, "same date, description and amount already booked"
+	case p.seen.contains(tr):
+		o.Status, o.Reason = StatusDuplicate, "repeats an earlier line of the file"
+	default:
+		match, err := p.fuzzyMatch(tr)
+		if err != nil {
+			return o, fmt.Errorf("failed to check duplicate: %w", err)
+		}
+		if match != nil {
+			o.Status, o.Match = StatusDuplicate, match
+			o.Reason = fmt.Sprintf("likely the same as #%d %q of %s", match.ID, match.Description, match.Date.Format("2006-01-02"))
+			break
+		}
+		o.Status = StatusNew
+		p.seen.add(tr)
+	}
+	return o, nil
+}
+
+// fuzzyMatch returns the closest stored transaction from another source that tr likely
+// repeats and no earlier record was matched with, or nil.
+func (p *Pipeline) fuzzyMatch(tr *models.Transaction) (*models.Transaction, error) {
+	if !p.Fuzzy.Enabled() {
+		return nil, nil
+	}
+
+	var candidates []models.Transaction
+	var err error
+	if p.tx != nil {
+		candidates, err = p.tx.FindLikelyDuplicates(tr, p.Source, p.Fuzzy)
+	} else {
+		candidates, err = models.FindLikelyDuplicates(p.DB, tr, p.Source, p.Fuzzy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.matched == nil {
+		p.matched = map[int64]bool{}
+	}
+	for i := range candidates {
+		if c := &candidates[i]; !p.matched[c.ID] {
+			p.matched[c.ID] = true
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+// Commit stores the transactions of the outcomes that are new, with their split lines,
+// and updates their status. The others are left alone. If one can't be stored, none is.
+func (p *Pipeline) Commit(outcomes []Outcome) error {
+	if p.tx == nil {
+		if err := p.Begin(nil); err != nil {
+			return err
+		}
+		defer p.Abort()
+		if err := p.Commit(outcomes); err != nil {
+			return err
+		}
+		return p.End(nil)
+	}
+
+	for i := range outcomes {
+		if outcomes[i].Status != StatusNew {
+			continue
+		}
+		if err := p.store(&outcomes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// store writes the transaction of a new outcome inside the import's database transaction.
+func (p *Pipeline) store(o *Outcome) error {
+	tr := o.Transaction
+	tr.BatchID, tr.SourceLine = p.batch, o.Record.Line
+	if err := p.tx.CreateTransaction(tr); err != nil {
+		return fmt.Errorf("failed to store %q of %s: %w", tr.Description, tr.Date.Format("2006-01-02"), err)
+	}
+	if err := p.tx.ApplyRuleResult(tr, o.Rules); err != nil {
+		return fmt.Errorf("failed to apply the rules to %q of %s: %w", tr.Description, tr.Date.Format("2006-01-02"), err)
+	}
+	o.Status = StatusImported
+	o.Warnings = p.addSplits(tr, o.Record.Splits)
+	if err := p.linkTransfer(tr, o.Record); err != nil {
+		return fmt.Errorf("failed to link the transfer of %q of %s: %w", tr.Description, tr.Date.Format("2006-01-02"), err)
+	}
+	return nil
+}
+
+// Transaction builds the transaction a record of the statement becomes, without storing it.
+// The result says what the rules still have to do once it is stored.
+func (p *Pipeline) Transaction(s Statement, rec Record) (*models.Transaction, models.RuleResult) {
+	tr := &models.Transaction{
+		Date:        rec.Date,
+		Description: rec.Description,
+		Amount:      rec.Amount,
+		Category:    rec.Category,
+		ExternalID:  rec.ExternalID,
+	}
+	if rec.ExternalID != "" {
+		tr.ExternalAccount = s.BankAccount()
+	}
+
+	// The record's currency wins, then the statement's, then the account's
+	tr.Currency = rec.Currency
+	if tr.Currency == "" {
+		tr.Currency = s.Currency
+	}
+	name := rec.Account
+	if name == "" {
+		name = s.AccountID
+	}
+	if account := p.accountFor(name); account != nil {
+		tr.Account = account.Name
+		if tr.Currency == "" {
+			tr.Currency = account.Currency
+		}
+	}
+
+	result := p.Rules.Apply(tr)
+	if rec.Transfer != "" {
+		tr.Category = models.TransferCategory
+	}
+	tr.Category = models.NormalizeCategory(tr.Category)
+	return tr, result
+}
+
+// AccountOf returns the account the records of a statement are booked in, unless a
+// record names its own; nil if none.
+func (p *Pipeline) AccountOf(s Statement) *models.Account {
+	return p.accountFor(s.AccountID)
+}
+
+// accountFor returns the account a record is booked in: the one of Accounts its
+// statement or the record itself names, else Account.
+func (p *Pipeline) accountFor(name string) *models.Account {
+	if name != "" {
+		for i := range p.Accounts {
+			if p.Accounts[i].Name == name {
+				return &p.Accounts[i]
+			}
+		}
+	}
+	return p.Account
+}
+
+// transferAccount returns the name of the account a transfer goes to if it is one of
+// Accounts, else "".
+func (p *Pipeline) transferAccount(name string) string {
+	for _, a := range p.Accounts {
+		if a.Name == name {
+			return a.Name
+		}
+	}
+	return ""
+}
+
+// linkTransfer links a transaction booked from a transfer record, or from one with a
+// transfer split line, with the other leg in the account the record names, if that leg is
+// stored already; otherwise the other leg finds this one once it is. A transfer to an
+// account that isn't one of Accounts is linked with a leg in any other account.
+func (p *Pipeline) linkTransfer(tr *models.Transaction, rec Record) error {
+	if tr.TransferID != 0 {
+		return nil // linked by a rule
+	}
+	if rec.Transfer != "" {
+		return p.tx.LinkTransferLeg(tr, p.transferAccount(rec.Transfer), tr.Amount)
+	}
+	for _, sp := range rec.Splits {
+		if sp.Transfer == "" {
+			continue
+		}
+		amount := sp.Amount
+		if (amount < 0) != (tr.Amount < 0) {
+			amount = -amount
+		}
+		return p.tx.LinkTransferLeg(tr, p.transferAccount(sp.Transfer), amount)
+	}
+	return nil
+}
+
+// learnCategory gives an uncategorized transaction the category the classifier predicts,
+// if it is confident enough, and returns the confidence; otherwise it returns 0.
+func (p *Pipeline) learnCategory(tr *models.Transaction) float64 {
+	if p.Classifier == nil || p.Confidence <= 0 || tr.Category != "Uncategorized" {
+		return 0
+	}
+	prediction := p.Classifier.Predict(tr.Description, tr.Amount)
+	if prediction.Category == "" || prediction.Confidence < p.Confidence {
+		return 0
+	}
+	tr.Category = prediction.Category
+	return prediction.Confidence
+}
+
+// addSplits stores the split lines read from a file. A line in the transaction's own
+// category is what the other lines leave over, so it isn't stored as a split.
+func (p *Pipeline) addSplits(tr *models.Transaction, splits []Split) []string {
+	var warnings []string
+	for _, sp := range splits {
+		category := models.NormalizeCategory(sp.Category)
+		if sp.Transfer != "" {
+			category = models.TransferCategory
+		}
+		if category == tr.Category {
+			continue
+		}
+		s := &models.Split{TransactionID: tr.ID, Amount: sp.Amount, Category: category, Memo: sp.Memo}
+		if err := p.tx.AddSplit(s); err != nil {
+			warnings = append(warnings, fmt.Sprintf("split of transaction %d not added: %v", tr.ID, err))
+		}
+	}
+	return warnings
+}
+
+// batchIndex finds the transactions of one file that models.TransactionExists would
+// consider the same: equal references, or equal date, description and amount when one
+// of the two has no reference.
+type batchIndex struct {
+	refs map[string]bool
+	keys map[string]bool // value: whether one of them has no reference
+}
+
+func newBatchIndex() *batchIndex {
+	return &batchIndex{refs: map[string]bool{}, keys: map[string]bool{}}
+}
+
+// batchRef is the bank's id of a transaction, qualified by the account it is unique in.
+func batchRef(tr *models.Transaction) string {
+	return tr.ExternalAccount + "|" + tr.ExternalID
+}
+
+func batchKey(tr *models.Transaction) string {
+	return fmt.Sprintf("%s|%s|%d", tr.Date.Format("2006-01-02"), tr.Description, tr.Amount)
+}
+
+func (b *batchIndex) contains(tr *models.Transaction) bool {
+	if tr.ExternalID != "" && b.refs[batchRef(tr)] {
+		return true
+	}
+	withoutRef, ok := b.keys[batchKey(tr)]
+	return ok && (withoutRef || tr.ExternalID == "")
+}
+
+func (b *batchIndex) add(tr *models.Transaction) {
+	if tr.ExternalID != "" {
+		b.refs[batchRef(tr)] = true
+	}
+	k := batchKey(tr)
+	b.keys[k] = b.keys[k] || tr.ExternalID == ""
+}
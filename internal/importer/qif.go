@@ -0,0 +1,315 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// qifTypes maps the QIF section types holding plain transactions to statement account types.
+// Investment, memorized and category sections are skipped.
+var qifTypes = map[string]string{
+	"bank":  "BANK",
+	"ccard": "CREDITCARD",
+	"cash":  "CASH",
+	"oth a": "ASSET",
+	"oth l": "LIABILITY",
+}
+
+// qifEntry collects the fields of one transaction until its "^" line.
+type qifEntry struct {
+	line                      int
+	date, amount, payee, memo string
+	category                  string
+	splits                    []qifSplit
+}
+
+type qifSplit struct {
+	category, memo, amount string
+}
+
+type qifImporter struct{}
+
+func (qifImporter) Name() string         { return FormatQIF }
+func (qifImporter) Extensions() []string { return []string{".qif"} }
+
+func (qifImporter) Parse(r io.Reader) ([]Statement, error) { return ParseQIF(r) }
+
+// Detect looks for a "!Type:", "!Account" or "!Option:" header on the first line.
+func (qifImporter) Detect(head []byte) bool {
+	first, _, _ := bytes.Cut(head, []byte("\n"))
+	line := strings.ToLower(strings.TrimSpace(string(first)))
+	return strings.HasPrefix(line, "!type:") || strings.HasPrefix(line, "!account") || strings.HasPrefix(line, "!option:")
+}
+
+// ParseQIF reads a QIF file as Quicken, GnuCash or HomeBank write it. Every !Type:Bank,
+// !Type:CCard, !Type:Cash and !Type:Oth A/L section becomes a statement; the name of a
+// preceding !Account block becomes its AccountID. Categories ("L") keep their
+// "Parent:Child" path, "[Account]" categories are transfers, and split lines
+// ("S", "E", "$") are returned as the record's splits.
+func ParseQIF(r io.Reader) ([]Statement, error) {
+	var statements []Statement
+	current := -1 // index of the statement being read; -1 outside a readable section
+	skipping := false
+	inAccount := false
+	account := ""
+	var entry *qifEntry
+
+	finish := func() {
+		if entry != nil && current >= 0 {
+			statements[current].Records = append(statements[current].Records, entry.record())
+		}
+		entry = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" {
+			continue
+		}
+
+		if text[0] == '!' {
+			finish()
+			header := strings.TrimSpace(text[1:])
+			switch lower := strings.ToLower(header); {
+			case lower == "account":
+				inAccount, account = true, ""
+			case strings.HasPrefix(lower, "type:"):
+				if kind, ok := qifTypes[strings.TrimSpace(lower[len("type:"):])]; ok {
+					statements = append(statements, Statement{AccountID: account, AccountType: kind})
+					current, skipping = len(statements)-1, false
+				} else {
+					current, skipping = -1, true
+				}
+			}
+			// Options such as !Option:AutoSwitch don't change how entries are read
+			continue
+		}
+
+		code, value := text[0], strings.TrimSpace(text[1:])
+		if inAccount {
+			switch code {
+			case 'N':
+				account = value
+			case '^':
+				inAccount = false
+			}
+			continue
+		}
+		if current < 0 {
+			if skipping {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: transaction data before a !Type header", line)
+		}
+
+		if code == '^' {
+			finish()
+			continue
+		}
+		if entry == nil {
+			entry = &qifEntry{line: line}
+		}
+		switch code {
+		case 'D':
+			entry.date = value
+		case 'T', 'U':
+			if entry.amount == "" {
+				entry.amount = value
+			}
+		case 'P':
+			entry.payee = value
+		case 'M':
+			entry.memo = value
+		case 'L':
+			entry.category = value
+		case 'S':
+			entry.splits = append(entry.splits, qifSplit{category: value})
+		case 'E', '$':
+			if len(entry.splits) == 0 {
+				continue // split details without a split category; nothing to attach them to
+			}
+			s := &entry.splits[len(entry.splits)-1]
+			if code == 'E' {
+				s.memo = value
+			} else {
+				s.amount = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read QIF data: %w", err)
+	}
+	finish() // the last entry may lack its "^"
+
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no !Type:Bank or !Type:CCard section found in QIF data")
+	}
+	return statements, nil
+}
+
+func (e *qifEntry) record() Record {
+	rec := Record{Line: e.line}
+
+	rec.Description = e.payee
+	if e.memo != "" {
+		if rec.Description != "" {
+			rec.Description += " - "
+		}
+		rec.Description += e.memo
+	}
+	rec.Category, rec.Transfer = qifCategory(e.category)
+
+	var err error
+	if rec.Date, err = parseQIFDate(e.date); err != nil {
+		rec.Err = fmt.Errorf("invalid date %q", e.date)
+		return rec
+	}
+	if rec.Amount, err = ParseAmount(e.amount, "."); err != nil {
+		rec.Err = err
+		return rec
+	}
+
+	for _, s := range e.splits {
+		amount, err := ParseAmount(s.amount, ".")
+		if err != nil {
+			rec.Err = fmt.Errorf("split %q: %w", s.category, err)
+			return rec
+		}
+		category, transfer := qifCategory(s.category)
+		rec.Splits = append(rec.Splits, Split{Amount: amount, Category: category, Transfer: transfer, Memo: s.memo})
+	}
+	return rec
+}
+
+// qifCategory splits an "L" or "S" value into a category path and a transfer account.
+// "Food:Groceries/Vacation" is the category Food:Groceries with the class Vacation, which is
+// dropped; "[Savings]" is a transfer to the account Savings.
+func qifCategory(value string) (category, transfer string) {
+	if strings.HasPrefix(value, "[") {
+		end := strings.Index(value, "]")
+		if end < 0 {
+			end = len(value)
+		}
+		return "", strings.TrimSpace(value[1:end])
+	}
+	if i := strings.Index(value, "/"); i >= 0 {
+		value = value[:i]
+	}
+	if value == "--Split--" {
+		return "", ""
+	}
+	return strings.TrimSpace(value), ""
+}
+
+// parseQIFDate reads the date styles found in QIF files: "01/15/2024", "1/15'24" and
+// "1/15' 4" (Quicken's years after 2000), "15.01.2024" (European programs) and "2024-01-15".
+// Two-digit years after a slash are 1970-2069.
+func parseQIFDate(s string) (time.Time, error) {
+	compact := strings.ReplaceAll(s, " ", "")
+	parts := strings.FieldsFunc(compact, func(r rune) bool {
+		return r == '/' || r == '\'' || r == '.' || r == '-'
+	})
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid date")
+	}
+	n := make([]int, 3)
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date")
+		}
+		n[i] = v
+	}
+
+	var year, month, day int
+	switch {
+	case len(parts[0]) == 4:
+		year, month, day = n[0], n[1], n[2]
+	case strings.Contains(compact, "."):
+		day, month, year = n[0], n[1], n[2]
+	default:
+		month, day, year = n[0], n[1], n[2]
+	}
+	if len(parts[2]) <= 2 && len(parts[0]) != 4 {
+		switch {
+		case strings.Contains(compact, "'"), year < 70:
+			year += 2000
+		default:
+			year += 1900
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date")
+	}
+	return t, nil
+}
+
+// qifTypeNames is the inverse of qifTypes, for writing.
+var qifTypeNames = map[string]string{
+	"BANK":       "Bank",
+	"CREDITCARD": "CCard",
+	"CASH":       "Cash",
+	"ASSET":      "Oth A",
+	"LIABILITY":  "Oth L",
+}
+
+// WriteQIF writes statements as QIF, one !Type section per statement, preceded by an
+// !Account block if the statement names its account. Dates are written as MM/DD/YYYY.
+// QIF has no currencies; amounts are written as they are.
+func WriteQIF(w io.Writer, statements []Statement) error {
+	out := bufio.NewWriter(w)
+	for _, s := range statements {
+		kind := qifTypeNames[s.AccountType]
+		if kind == "" {
+			kind = "Bank"
+		}
+		if s.AccountID != "" {
+			fmt.Fprintf(out, "!Account\nN%s\nT%s\n^\n", qifValue(s.AccountID), kind)
+		}
+		fmt.Fprintf(out, "!Type:%s\n", kind)
+
+		for _, rec := range s.Records {
+			fmt.Fprintf(out, "D%s\n", rec.Date.Format("01/02/2006"))
+			fmt.Fprintf(out, "T%s\n", rec.Amount)
+			fmt.Fprintf(out, "P%s\n", qifValue(rec.Description))
+			if l := qifCategoryValue(rec.Category, rec.Transfer); l != "" {
+				fmt.Fprintf(out, "L%s\n", l)
+			}
+			for _, sp := range rec.Splits {
+				fmt.Fprintf(out, "S%s\n", qifCategoryValue(sp.Category, sp.Transfer))
+				if sp.Memo != "" {
+					fmt.Fprintf(out, "E%s\n", qifValue(sp.Memo))
+				}
+				fmt.Fprintf(out, "$%s\n", sp.Amount)
+			}
+			fmt.Fprintln(out, "^")
+		}
+	}
+	return out.Flush()
+}
+
+func qifCategoryValue(category, transfer string) string {
+	if transfer != "" {
+		return "[" + qifValue(transfer) + "]"
+	}
+	// A slash would start a class
+	return strings.ReplaceAll(qifValue(category), "/", "-")
+}
+
+// qifValue keeps a value on its line.
+func qifValue(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Importer reads one statement file format. Supporting a new format means writing an
+// Importer and registering it; detection and the Pipeline work the same for every format.
+type Importer interface {
+	// Name identifies the format, e.g. "ofx".
+	Name() string
+	// Detect reports whether a file starting with head is in this format. Formats
+	// without a marker of their own (CSV) return false and are chosen by extension.
+	Detect(head []byte) bool
+	// Extensions lists file extensions, e.g. ".ofx", used when no importer recognizes
+	// the content.
+	Extensions() []string
+	// Parse reads a whole file.
+	Parse(r io.Reader) ([]Statement, error)
+}
+
+// RecordStreamer is implemented by importers that can hand out the records of a file one
+// at a time, so a large file is never held in memory as a whole. The records belong to a
+// single statement without account details.
+type RecordStreamer interface {
+	// Stream calls fn for every record; an error from fn stops it and is returned.
+	Stream(r io.Reader, fn func(Record) error) error
+}
+
+// Names of the built-in formats.
+const (
+	FormatCSV   = "csv"
+	FormatOFX   = "ofx"
+	FormatQIF   = "qif"
+	FormatCAMT  = "camt"
+	FormatMT940 = "mt940"
+)
+
+// SniffLen is how much of the start of a file Detect looks at.
+const SniffLen = 4096
+
+// importers are asked in order; those recognizing content come before CSV.
+var importers = []Importer{camtImporter{}, ofxImporter{}, mt940Importer{}, qifImporter{}, CSV{}}
+
+// Register adds an importer for another format. It is asked after the built-in ones.
+func Register(i Importer) {
+	importers = append(importers, i)
+}
+
+// Importers returns the registered importers in the order they are asked.
+func Importers() []Importer {
+	return append([]Importer(nil), importers...)
+}
+
+// Lookup returns the importer with the given name.
+func Lookup(name string) (Importer, error) {
+	for _, i := range importers {
+		if strings.EqualFold(i.Name(), name) {
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown import format %q (use one of: %s)", name, strings.Join(formatNames(), ", "))
+}
+
+// Detect picks the importer for a file: the first that recognizes the content, else the
+// first that claims the file's extension.
+func Detect(filename string, data []byte) (Importer, error) {
+	head := data
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+	head = bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\ufeff")))
+
+	for _, i := range importers {
+		if i.Detect(head) {
+			return i, nil
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, i := range importers {
+		for _, e := range i.Extensions() {
+			if e == ext {
+				return i, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported file format '%s'. Please use one of: %s", ext, strings.Join(formatNames(), ", "))
+}
+
+func formatNames() []string {
+	names := make([]string, len(importers))
+	for i, imp := range importers {
+		names[i] = imp.Name()
+	}
+	return names
+}
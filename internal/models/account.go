@@ -0,0 +1,228 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccountTypes lists the kinds of accounts the CLI knows about.
+var AccountTypes = []string{"checking", "savings", "credit", "cash"}
+
+type Account struct {
+	ID        int64
+	Name      string
+	Type      string // "checking", "savings", "credit", "cash"
+	Currency  string // default currency of its transactions; empty means the base currency
+	Closed    bool
+	CreatedAt time.Time
+}
+
+// AccountBalance holds the running balance of a single account
+type AccountBalance struct {
+	Account Account
+	Balance Money
+}
+
+// ValidAccountType reports whether t is one of AccountTypes.
+func ValidAccountType(t string) bool {
+	for _, known := range AccountTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+func CreateAccount(db *sql.DB, a *Account) error {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" {
+		return fmt.Errorf("account name cannot be empty")
+	}
+	if a.Type == "" {
+		a.Type = "checking"
+	}
+	if !ValidAccountType(a.Type) {
+		return fmt.Errorf("unknown account type %q (use one of: %s)", a.Type, strings.Join(AccountTypes, ", "))
+	}
+	currency, err := NormalizeCurrency(a.Currency)
+	if err != nil {
+		return err
+	}
+	a.Currency = currency
+
+	res, err := db.Exec(`INSERT INTO accounts (name, type, currency) VALUES (?, ?, ?)`, a.Name, a.Type, nullIfEmpty(a.Currency))
+	if err != nil {
+		return fmt.Errorf("failed to insert account: %w", err)
+	}
+	a.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetAccountByName looks an account up by its (case-insensitive) name.
+func GetAccountByName(db *sql.DB, name string) (*Account, error) {
+	row := db.QueryRow(`
+        SELECT `+accountColumns+`
+        FROM accounts WHERE name = ?;
+    `, strings.TrimSpace(name))
+
+	a, err := scanAccount(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("account %q not found", name)
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// ResolveOpenAccount looks up an account that new transactions can be recorded
+// against. An empty name resolves to no account (nil, nil).
+func ResolveOpenAccount(db *sql.DB, name string) (*Account, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, nil
+	}
+	a, err := GetAccountByName(db, name)
+	if err != nil {
+		return nil, err
+	}
+	if a.Closed {
+		return nil, fmt.Errorf("account %q is closed", a.Name)
+	}
+	return a, nil
+}
+
+// ListAccounts returns accounts ordered by name. Closed accounts are only included on request.
+func ListAccounts(db *sql.DB, includeClosed bool) ([]Account, error) {
+	query := `SELECT ` + accountColumns + ` FROM accounts`
+	if !includeClosed {
+		query += ` WHERE closed = 0`
+	}
+	query += ` ORDER BY name;`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Account
+	for rows.Next() {
+		a, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *a)
+	}
+	return list, rows.Err()
+}
+
+// RenameAccount renames an account and moves its transactions and recurring templates along with it.
+func RenameAccount(db *sql.DB, oldName, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("account name cannot be empty")
+	}
+
+	a, err := GetAccountByName(db, oldName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE accounts SET name = ? WHERE id = ?`, newName, a.ID); err != nil {
+		return fmt.Errorf("failed to rename account: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE transactions SET account = ? WHERE account = ?`, newName, a.Name); err != nil {
+		return fmt.Errorf("failed to move transactions: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE recurring SET account = ? WHERE account = ?`, newName, a.Name); err != nil {
+		return fmt.Errorf("failed to move recurring transactions: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE category_rules SET account = ? WHERE account = ?`, newName, a.Name); err != nil {
+		return fmt.Errorf("failed to move rules: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE import_batches SET account = ? WHERE account = ?`, newName, a.Name); err != nil {
+		return fmt.Errorf("failed to move import batches: %w", err)
+	}
+	return tx.Commit()
+}
+
+// CloseAccount marks an account as closed. Its history is kept, but no new
+// transactions can be recorded against it.
+func CloseAccount(db *sql.DB, name string) error {
+	a, err := GetAccountByName(db, name)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE accounts SET closed = 1 WHERE id = ?`, a.ID)
+	return err
+}
+
+// GetAccountBalances sums all transactions per account, in the account's currency.
+// Transactions in other currencies are converted with the rate of their date;
+// the converter's base currency stands in for accounts and transactions without one.
+// A nil converter sums the amounts as-is.
+func GetAccountBalances(db *sql.DB, includeClosed bool, conv *Converter) ([]AccountBalance, error) {
+	accounts, err := ListAccounts(db, includeClosed)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]AccountBalance, 0, len(accounts))
+	for _, a := range accounts {
+		rows, err := db.Query(`
+            SELECT account, COALESCE(currency, ''), date, SUM(amount)
+            FROM transactions WHERE account = ?
+            GROUP BY 2, 3;
+        `, a.Name)
+		if err != nil {
+			return nil, err
+		}
+		sums, err := scanDailySums(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		var balance Money
+		for _, s := range sums {
+			converted, err := conv.convertDaily(s, a.Currency)
+			if err != nil {
+				return nil, fmt.Errorf("account %s: %w", a.Name, err)
+			}
+			balance += converted
+		}
+
+		balances = append(balances, AccountBalance{Account: a, Balance: balance})
+	}
+	return balances, nil
+}
+
+// GetAccountBalanceOn sums the transactions of an account up to and including a date, like a
+// bank statement's closing balance. Only transactions in the account's own currency count.
+func GetAccountBalanceOn(db *sql.DB, name string, date time.Time) (Money, error) {
+	var balance Money
+	err := db.QueryRow(`
+        SELECT COALESCE(SUM(t.amount), 0)
+        FROM transactions t JOIN accounts a ON a.name = t.account
+        WHERE t.account = ? AND t.date <= ?
+          AND COALESCE(t.currency, '') = COALESCE(a.currency, '')
+    `, name, date.Format("2006-01-02")).Scan(&balance)
+	return balance, err
+}
+
+const accountColumns = `id, name, type, COALESCE(currency, ''), closed, created_at`
+
+func scanAccount(row rowScanner) (*Account, error) {
+	var a Account
+	if err := row.Scan(&a.ID, &a.Name, &a.Type, &a.Currency, &a.Closed, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
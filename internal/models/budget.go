@@ -2,27 +2,42 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 )
 
 type Budget struct {
 	ID       int64
-	Category string
-	Amount   float64
+	Category string // full path; the budget also covers all subcategories
+	Tag      string // when set, the budget covers transactions with this tag instead of a category
+	Amount   Money
 	Period   string // "monthly", "weekly", "yearly"
 }
 
-// CreateBudget creates a new budget or updates an existing one for the category
+// Target is what the budget limits, as shown to the user: a category or a #tag.
+func (b Budget) Target() string {
+	if b.Tag != "" {
+		return "#" + b.Tag
+	}
+	return b.Category
+}
+
+// CreateBudget creates a new budget or updates an existing one for the category (or tag)
 func CreateBudget(db *sql.DB, b *Budget) error {
+	categoryID, err := budgetCategoryID(db, b)
+	if err != nil {
+		return err
+	}
+
 	// 1. Check if a budget for this category already exists
 	var existingID int64 // FIXED: Changed from int to int64
-	err := db.QueryRow("SELECT id FROM budgets WHERE category = ?", b.Category).Scan(&existingID)
+	err = db.QueryRow("SELECT id FROM budgets WHERE COALESCE(category_id, 0) = ? AND COALESCE(tag, '') = ?", categoryID, b.Tag).Scan(&existingID)
 
 	if err == sql.ErrNoRows {
 		// Case A: No budget exists, create a new one (INSERT)
-		query := `INSERT INTO budgets (category, amount, period) VALUES (?, ?, ?)`
-		result, err := db.Exec(query, b.Category, b.Amount, b.Period)
+		query := `INSERT INTO budgets (category_id, tag, amount, period) VALUES (?, ?, ?, ?)`
+		result, err := db.Exec(query, nullIfZero(categoryID), nullIfEmpty(b.Tag), b.Amount, b.Period)
 		if err != nil {
 			return fmt.Errorf("failed to insert budget: %w", err)
 		}
@@ -46,13 +61,13 @@ func CreateBudget(db *sql.DB, b *Budget) error {
 
 func GetBudget(db *sql.DB, id int64) (*Budget, error) {
 	query := `
-        SELECT id, category, amount, period
-        FROM budgets WHERE id = ?;
+        SELECT ` + budgetColumns + `
+        FROM ` + budgetSource + ` WHERE b.id = ?;
     `
 	row := db.QueryRow(query, id)
 
 	var b Budget
-	if err := row.Scan(&b.ID, &b.Category, &b.Amount, &b.Period); err != nil {
+	if err := row.Scan(&b.ID, &b.Category, &b.Tag, &b.Amount, &b.Period); err != nil {
 		return nil, err
 	}
 
@@ -60,7 +75,7 @@ func GetBudget(db *sql.DB, id int64) (*Budget, error) {
 }
 
 func ListBudgets(db *sql.DB) ([]Budget, error) {
-	rows, err := db.Query("SELECT id, category, amount, period FROM budgets")
+	rows, err := db.Query("SELECT " + budgetColumns + " FROM " + budgetSource)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +84,7 @@ func ListBudgets(db *sql.DB) ([]Budget, error) {
 	var budgets []Budget
 	for rows.Next() {
 		var b Budget
-		if err := rows.Scan(&b.ID, &b.Category, &b.Amount, &b.Period); err != nil {
+		if err := rows.Scan(&b.ID, &b.Category, &b.Tag, &b.Amount, &b.Period); err != nil {
 			return nil, err
 		}
 		budgets = append(budgets, b)
@@ -78,35 +93,91 @@ func ListBudgets(db *sql.DB) ([]Budget, error) {
 }
 
 func UpdateBudget(db *sql.DB, b *Budget) error {
-	_, err := db.Exec(`
+	categoryID, err := budgetCategoryID(db, b)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
         UPDATE budgets
-        SET category = ?, amount = ?, period = ?
+        SET category_id = ?, tag = ?, amount = ?, period = ?
         WHERE id = ?;
-    `, b.Category, b.Amount, b.Period, b.ID)
+    `, nullIfZero(categoryID), nullIfEmpty(b.Tag), b.Amount, b.Period, b.ID)
 	return err
 }
 
+// budgetCategoryID resolves the category of a budget; tag budgets have none.
+func budgetCategoryID(db *sql.DB, b *Budget) (int64, error) {
+	if b.Tag != "" {
+		return 0, nil
+	}
+	id, path, err := categoryIDFor(db, b.Category)
+	b.Category = path
+	return id, err
+}
+
 func DeleteBudget(db *sql.DB, id int64) error {
 	_, err := db.Exec("DELETE FROM budgets WHERE id = ?", id)
 	return err
 }
 
-func GetSpendingTotal(db *sql.DB, category string, month time.Month, year int) (float64, error) {
+// budgetSource and budgetColumns read budgets with their category path.
+const budgetSource = `budgets b LEFT JOIN category_paths cp ON cp.id = b.category_id`
+const budgetColumns = `b.id, COALESCE(cp.path, ''), COALESCE(b.tag, ''), b.amount, b.period`
+
+// GetSpendingTotal returns how much was spent in a category and its subcategories during a month,
+// as a positive amount in the converter's base currency (a nil converter sums amounts as-is).
+// Split transactions count with the split lines that belong to the category.
+func GetSpendingTotal(db *sql.DB, category string, month time.Month, year int, conv *Converter) (Money, error) {
+	c, err := FindCategory(db, category)
+	if errors.Is(err, ErrCategoryNotFound) {
+		return 0, nil // nothing can have been spent in a category that doesn't exist
+	}
+	if err != nil {
+		return 0, err
+	}
+	return spendingTotal(db, subtreeCondition("category_id"), c.ID, month, year, conv)
+}
+
+// GetTagSpendingTotal is GetSpendingTotal for the transactions carrying a tag.
+func GetTagSpendingTotal(db *sql.DB, tag string, month time.Month, year int, conv *Converter) (Money, error) {
+	return spendingTotal(db, taggedCondition("transaction_id"), tag, month, year, conv)
+}
+
+// GetBudgetSpending returns how much of a budget was used during a month.
+func GetBudgetSpending(db *sql.DB, b Budget, month time.Month, year int, conv *Converter) (Money, error) {
+	if b.Tag != "" {
+		return GetTagSpendingTotal(db, b.Tag, month, year, conv)
+	}
+	return GetSpendingTotal(db, b.Category, month, year, conv)
+}
+
+// spendingTotal sums the expenses of a month over the ledger lines matching cond.
+func spendingTotal(db *sql.DB, cond string, arg interface{}, month time.Month, year int, conv *Converter) (Money, error) {
 	dateFilter := fmt.Sprintf("%04d-%02d%%", year, month)
 	query := `
-		SELECT SUM(amount)
-		FROM transactions
-		WHERE category = ? 
+		SELECT '', COALESCE(currency, ''), date, SUM(amount)
+		FROM ledger_lines
+		WHERE ` + cond + `
 		AND date LIKE ?
-		AND amount < 0; 
+		AND amount < 0
+		GROUP BY 2, 3;
 	`
-	var total sql.NullFloat64
-	err := db.QueryRow(query, category, dateFilter).Scan(&total)
+	rows, err := db.Query(query, arg, dateFilter)
+	if err != nil {
+		return 0, err
+	}
+	sums, err := scanDailySums(rows)
 	if err != nil {
 		return 0, err
 	}
-	if total.Valid {
-		return -total.Float64, nil
+
+	var total Money
+	for _, s := range sums {
+		converted, err := conv.convertDaily(s, "")
+		if err != nil {
+			return 0, err
+		}
+		total += converted
 	}
-	return 0, nil
+	return -total, nil
 }
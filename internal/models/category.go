@@ -0,0 +1,289 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// ErrCategoryNotFound is returned when a category path doesn't exist.
+var ErrCategoryNotFound = errors.New("category not found")
+
+// CategorySeparator separates the levels of a category path such as "Food:Groceries".
+const CategorySeparator = ":"
+
+// Category is a node of the category tree.
+type Category struct {
+	ID       int64
+	Name     string
+	ParentID int64  // 0 for top-level categories
+	Path     string // e.g. "Food:Groceries"
+}
+
+// CategoryNode is a category in a report tree. Total rolls up Own and all children.
+type CategoryNode struct {
+	Name     string
+	Path     string
+	Own      Money
+	Total    Money
+	Children []*CategoryNode
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// NormalizeCategory title-cases every level of a category path
+// (e.g. "food : groceries" -> "Food:Groceries"); an empty path is "Uncategorized".
+func NormalizeCategory(c string) string {
+	caser := cases.Title(language.English)
+
+	var levels []string
+	for _, name := range strings.Split(c, CategorySeparator) {
+		if name = strings.TrimSpace(name); name != "" {
+			levels = append(levels, caser.String(name))
+		}
+	}
+	if len(levels) == 0 {
+		return "Uncategorized"
+	}
+	return strings.Join(levels, CategorySeparator)
+}
+
+// IsSubcategory reports whether path is parent itself or lies below it.
+func IsSubcategory(path, parent string) bool {
+	return strings.EqualFold(path, parent) ||
+		(len(path) > len(parent) && strings.EqualFold(path[:len(parent)+1], parent+CategorySeparator))
+}
+
+// EnsureCategory returns the category for a path, creating the missing levels.
+func EnsureCategory(q querier, path string) (*Category, error) {
+	var c *Category
+	for _, name := range strings.Split(NormalizeCategory(path), CategorySeparator) {
+		child, err := findChild(q, c, name)
+		if errors.Is(err, sql.ErrNoRows) {
+			child = &Category{Name: name}
+			if c != nil {
+				child.ParentID = c.ID
+			}
+			res, err := q.Exec(`INSERT INTO categories (name, parent_id) VALUES (?, ?)`, name, nullIfZero(child.ParentID))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create category: %w", err)
+			}
+			if child.ID, err = res.LastInsertId(); err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			return nil, err
+		}
+
+		child.Path = child.Name
+		if c != nil {
+			child.Path = c.Path + CategorySeparator + child.Name
+		}
+		c = child
+	}
+	return c, nil
+}
+
+func findChild(q querier, parent *Category, name string) (*Category, error) {
+	var parentID int64
+	if parent != nil {
+		parentID = parent.ID
+	}
+	c := Category{ParentID: parentID}
+	err := q.QueryRow(`SELECT id, name FROM categories WHERE COALESCE(parent_id, 0) = ? AND name = ? COLLATE NOCASE`,
+		parentID, name).Scan(&c.ID, &c.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FindCategory looks a category up by its (case-insensitive) path.
+func FindCategory(db *sql.DB, path string) (*Category, error) {
+	row := db.QueryRow(`
+		SELECT `+categoryColumns+`
+		FROM categories c JOIN category_paths cp ON cp.id = c.id
+		WHERE cp.path = ? COLLATE NOCASE`, NormalizeCategory(path))
+	c, err := scanCategory(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrCategoryNotFound, path)
+	}
+	return c, err
+}
+
+// ListCategories returns all categories ordered by path, so parents precede their children.
+func ListCategories(db *sql.DB) ([]Category, error) {
+	rows, err := db.Query(`
+		SELECT ` + categoryColumns + `
+		FROM categories c JOIN category_paths cp ON cp.id = c.id
+		ORDER BY cp.path COLLATE NOCASE`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *c)
+	}
+	return list, rows.Err()
+}
+
+// RenameCategory gives a category a new name; its children and transactions follow.
+func RenameCategory(db *sql.DB, path, newName string) error {
+	c, err := FindCategory(db, path)
+	if err != nil {
+		return err
+	}
+	newName = NormalizeCategory(newName)
+	if strings.Contains(newName, CategorySeparator) {
+		return fmt.Errorf("a name cannot contain %q; use 'category move' to change the parent", CategorySeparator)
+	}
+	if _, err := db.Exec(`UPDATE categories SET name = ? WHERE id = ?`, newName, c.ID); err != nil {
+		return fmt.Errorf("failed to rename category (does %q already exist?): %w", newName, err)
+	}
+	return nil
+}
+
+// MoveCategory puts a category below a new parent; an empty parent makes it top-level.
+func MoveCategory(db *sql.DB, path, parentPath string) error {
+	c, err := FindCategory(db, path)
+	if err != nil {
+		return err
+	}
+
+	var parentID int64
+	if strings.TrimSpace(parentPath) != "" {
+		parent, err := FindCategory(db, parentPath)
+		if err != nil {
+			return err
+		}
+		if IsSubcategory(parent.Path, c.Path) {
+			return fmt.Errorf("cannot move %s below itself", c.Path)
+		}
+		parentID = parent.ID
+	}
+
+	if _, err := db.Exec(`UPDATE categories SET parent_id = ? WHERE id = ?`, nullIfZero(parentID), c.ID); err != nil {
+		return fmt.Errorf("failed to move category (is there already one named %q?): %w", c.Name, err)
+	}
+	return nil
+}
+
+// DeleteCategory removes a category nothing refers to any more.
+func DeleteCategory(db *sql.DB, path string) error {
+	c, err := FindCategory(db, path)
+	if err != nil {
+		return err
+	}
+
+	var uses int
+	err = db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM categories WHERE parent_id = ?1)
+		     + (SELECT COUNT(*) FROM transactions WHERE category_id = ?1)
+		     + (SELECT COUNT(*) FROM transaction_splits WHERE category_id = ?1)
+		     + (SELECT COUNT(*) FROM budgets WHERE category_id = ?1)
+		     + (SELECT COUNT(*) FROM category_rules WHERE category_id = ?1 OR if_category_id = ?1)
+		     + (SELECT COUNT(*) FROM recurring WHERE category_id = ?1)`, c.ID).Scan(&uses)
+	if err != nil {
+		return err
+	}
+	if uses > 0 {
+		return fmt.Errorf("category %s is still used by subcategories, transactions, budgets, rules or recurring transactions", c.Path)
+	}
+
+	_, err = db.Exec(`DELETE FROM categories WHERE id = ?`, c.ID)
+	return err
+}
+
+// subtreeCondition selects rows whose category column is the given category or one below it.
+func subtreeCondition(column string) string {
+	return column + ` IN (
+		WITH RECURSIVE subtree(id) AS (
+			SELECT ?
+			UNION ALL
+			SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+		)
+		SELECT id FROM subtree)`
+}
+
+// BuildCategoryTree arranges per-category totals into a tree in which every parent's
+// Total includes its children. Siblings are sorted like the flat report: biggest expenses first.
+func BuildCategoryTree(totals []CategoryTotal) []*CategoryNode {
+	root := &CategoryNode{}
+	nodes := map[string]*CategoryNode{}
+
+	for _, t := range totals {
+		parent := root
+		levels := strings.Split(t.Category, CategorySeparator)
+		for i := range levels {
+			path := strings.Join(levels[:i+1], CategorySeparator)
+			node, ok := nodes[path]
+			if !ok {
+				node = &CategoryNode{Name: levels[i], Path: path}
+				nodes[path] = node
+				parent.Children = append(parent.Children, node)
+			}
+			node.Total += t.Amount
+			parent = node
+		}
+		parent.Own += t.Amount
+	}
+
+	var sortNodes func(list []*CategoryNode)
+	sortNodes = func(list []*CategoryNode) {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Total != list[j].Total {
+				return list[i].Total < list[j].Total
+			}
+			return list[i].Name < list[j].Name
+		})
+		for _, n := range list {
+			sortNodes(n.Children)
+		}
+	}
+	sortNodes(root.Children)
+	return root.Children
+}
+
+// categoryColumns is the column list understood by scanCategory.
+const categoryColumns = `c.id, c.name, COALESCE(c.parent_id, 0), cp.path`
+
+func scanCategory(row rowScanner) (*Category, error) {
+	var c Category
+	if err := row.Scan(&c.ID, &c.Name, &c.ParentID, &c.Path); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// categoryIDFor resolves a category path to its id, creating the category if needed.
+func categoryIDFor(q querier, path string) (int64, string, error) {
+	c, err := EnsureCategory(q, path)
+	if err != nil {
+		return 0, "", err
+	}
+	return c.ID, c.Path, nil
+}
+
+// nullIfZero stores unset ids as NULL
+func nullIfZero(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
@@ -2,20 +2,89 @@ package models
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"regexp"
+	"strings"
+	"time"
 )
 
+// CategoryRule changes the transactions that meet all of its conditions when they are
+// imported or added. Zero conditions match every transaction; see RuleSet.Apply for how
+// the actions of several rules combine (migration 015 still calls it ApplyRules, its
+// name before rules were compiled into a RuleSet).
 type CategoryRule struct {
 	ID       int64
-	Pattern  string // regex
-	Category string
+	Priority int  // rules are applied lowest first
+	Disabled bool // kept, but not applied
+
+	// Conditions
+	Pattern    string    // regex on the description
+	MinAmount  Money     // the amount without its sign is at least this
+	MaxAmount  Money     // and at most this; 0 means no limit
+	Sign       int       // -1 only expenses, 1 only income, 0 both
+	Account    string    // only transactions of this account
+	From, To   time.Time // only transactions booked in this range; zero means open
+	IfCategory string    // only transactions already in this category or below it
+
+	// Actions
+	Category    string   // full path; empty keeps the category
+	Description string   // new description; $1, $2, ... are groups of Pattern
+	Payee       string   // payee to set
+	Tags        []string // tags to add
+	Transfer    bool     // the transaction moves money between own accounts
+}
+
+// Validate checks that the rule can be stored: its regex compiles, its ranges make sense
+// and it has at least one action.
+func (r *CategoryRule) Validate() error {
+	if _, err := regexp.Compile(r.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", r.Pattern, err)
+	}
+	if r.MinAmount < 0 || r.MaxAmount < 0 {
+		return fmt.Errorf("amount limits are given without sign; use the sign condition for income or expenses")
+	}
+	if r.MaxAmount != 0 && r.MaxAmount < r.MinAmount {
+		return fmt.Errorf("the minimum amount %s is above the maximum %s", r.MinAmount, r.MaxAmount)
+	}
+	if r.Sign < -1 || r.Sign > 1 {
+		return fmt.Errorf("invalid sign %d", r.Sign)
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.To.Before(r.From) {
+		return fmt.Errorf("the date range ends before it starts")
+	}
+	for i, raw := range r.Tags {
+		tag, err := NormalizeTag(raw)
+		if err != nil {
+			return err
+		}
+		r.Tags[i] = tag
+	}
+	if r.Category == "" && r.Description == "" && r.Payee == "" && len(r.Tags) == 0 && !r.Transfer {
+		return fmt.Errorf("the rule does nothing: give a category, description, payee, tags or transfer")
+	}
+	return nil
 }
 
+// CreateRule stores a new rule. Without a priority it is appended after the existing rules.
 func CreateRule(db *sql.DB, r *CategoryRule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if r.Priority == 0 {
+		if err := db.QueryRow(`SELECT COALESCE(MAX(priority), 0) + 1 FROM category_rules`).Scan(&r.Priority); err != nil {
+			return err
+		}
+	}
+
+	args, err := ruleArgs(db, r)
+	if err != nil {
+		return err
+	}
 	res, err := db.Exec(`
-        INSERT INTO category_rules (pattern, category)
-        VALUES (?, ?);
-    `, r.Pattern, r.Category)
+        INSERT INTO category_rules (`+ruleColumnNames+`)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+    `, args...)
 	if err != nil {
 		return err
 	}
@@ -23,11 +92,42 @@ func CreateRule(db *sql.DB, r *CategoryRule) error {
 	return err
 }
 
+// UpdateRule stores the changed fields of an existing rule.
+func UpdateRule(db *sql.DB, r *CategoryRule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	args, err := ruleArgs(db, r)
+	if err != nil {
+		return err
+	}
+
+	sets := strings.Split(ruleColumnNames, ", ")
+	for i := range sets {
+		sets[i] += " = ?"
+	}
+	res, err := db.Exec(`UPDATE category_rules SET `+strings.Join(sets, ", ")+` WHERE id = ?`, append(args, r.ID)...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("rule %d not found", r.ID)
+	}
+	return nil
+}
+
+// GetRule looks a rule up by ID.
+func GetRule(db *sql.DB, id int64) (*CategoryRule, error) {
+	r, err := scanRule(db.QueryRow(`SELECT `+ruleSelect+` WHERE r.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("rule %d not found", id)
+	}
+	return r, err
+}
+
+// ListRules returns all rules, disabled ones included, in the order they are applied.
 func ListRules(db *sql.DB) ([]CategoryRule, error) {
-	rows, err := db.Query(`
-        SELECT id, pattern, category
-        FROM category_rules;
-    `)
+	rows, err := db.Query(`SELECT ` + ruleSelect + ` ORDER BY r.priority, r.id`)
 	if err != nil {
 		return nil, err
 	}
@@ -35,28 +135,217 @@ func ListRules(db *sql.DB) ([]CategoryRule, error) {
 
 	var list []CategoryRule
 	for rows.Next() {
-		var r CategoryRule
-		if err := rows.Scan(&r.ID, &r.Pattern, &r.Category); err != nil {
+		r, err := scanRule(rows)
+		if err != nil {
 			return nil, err
 		}
-		list = append(list, r)
+		list = append(list, *r)
+	}
+	return list, rows.Err()
+}
+
+// MoveRule gives a rule the given position (1 = applied first) and renumbers the
+// priorities of all rules to 1, 2, 3, ...
+func MoveRule(db *sql.DB, id int64, position int) error {
+	rules, err := ListRules(db)
+	if err != nil {
+		return err
+	}
+	from := -1
+	for i, r := range rules {
+		if r.ID == id {
+			from = i
+		}
+	}
+	if from < 0 {
+		return fmt.Errorf("rule %d not found", id)
+	}
+	if position < 1 || position > len(rules) {
+		return fmt.Errorf("invalid position %d (use 1 to %d)", position, len(rules))
+	}
+
+	moved := rules[from]
+	rules = append(rules[:from], rules[from+1:]...)
+	rules = append(rules[:position-1], append([]CategoryRule{moved}, rules[position-1:]...)...)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i, r := range rules {
+		if _, err := tx.Exec(`UPDATE category_rules SET priority = ? WHERE id = ?`, i+1, r.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetRuleEnabled turns a rule on or off.
+func SetRuleEnabled(db *sql.DB, id int64, enabled bool) error {
+	res, err := db.Exec(`UPDATE category_rules SET enabled = ? WHERE id = ?`, enabled, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("rule %d not found", id)
 	}
-	return list, nil
+	return nil
 }
 
+// DeleteRule removes a rule. Transactions it changed stay as they are.
 func DeleteRule(db *sql.DB, id int64) error {
-	_, err := db.Exec(`DELETE FROM category_rules WHERE id = ?`, id)
-	return err
+	res, err := db.Exec(`DELETE FROM category_rules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("rule %d not found", id)
+	}
+	return nil
+}
+
+// LoadRules compiles the enabled rules, in order. Unlike ListRules it fails if a rule
+// can't be applied, e.g. a pattern stored before patterns were checked: such a rule
+// would otherwise never match, without anyone noticing.
+func LoadRules(db *sql.DB) (*RuleSet, error) {
+	rules, err := ListRules(db)
+	if err != nil {
+		return nil, err
+	}
+	return CompileRules(rules)
+}
+
+// matchesConditions reports whether the transaction meets the conditions of the rule
+// other than its pattern, which RuleSet matches.
+func (r *CategoryRule) matchesConditions(t *Transaction) bool {
+	amount := t.Amount.Abs()
+	if amount < r.MinAmount || (r.MaxAmount != 0 && amount > r.MaxAmount) {
+		return false
+	}
+	if (r.Sign < 0 && t.Amount >= 0) || (r.Sign > 0 && t.Amount <= 0) {
+		return false
+	}
+	if r.Account != "" && !strings.EqualFold(r.Account, t.Account) {
+		return false
+	}
+	if (!r.From.IsZero() && t.Date.Before(r.From)) || (!r.To.IsZero() && t.Date.After(r.To)) {
+		return false
+	}
+	if r.IfCategory != "" && !IsSubcategory(NormalizeCategory(t.Category), r.IfCategory) {
+		return false
+	}
+	return true
+}
+
+// RuleResult is what RuleSet.Apply did besides changing the transaction.
+type RuleResult struct {
+	Rules    []int64  // IDs of the rules that matched, in the order they were applied
+	Tags     []string // tags to add
+	Transfer bool     // link the transaction with the other leg of its transfer
+}
+
+// ApplyRuleResult does what RuleSet.Apply left to be done once the transaction is stored:
+// it adds the tags and, for a transfer, links the other leg if it is already booked.
+func ApplyRuleResult(db *sql.DB, t *Transaction, result RuleResult) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := applyRuleResult(tx, t, result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func applyRuleResult(tx *sql.Tx, t *Transaction, result RuleResult) error {
+	if err := addTags(tx, t.ID, result.Tags...); err != nil {
+		return err
+	}
+	if result.Transfer {
+		if _, err := linkOtherLeg(tx, t); err != nil {
+			return fmt.Errorf("failed to link the transfer: %w", err)
+		}
+	}
+	return nil
+}
+
+// MatchCategory returns the category the rules give a transaction with only this
+// description, or "" if none does. An invalid pattern is an error. It compiles the
+// rules for every call; to categorize many transactions, compile them once with
+// CompileRules.
+func MatchCategory(rules []CategoryRule, description string) (string, error) {
+	set, err := CompileRules(rules)
+	if err != nil {
+		return "", err
+	}
+	t := &Transaction{Description: description}
+	set.Apply(t)
+	return t.Category, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
-func MatchCategory(rules []CategoryRule, description string) string {
-	for _, rule := range rules {
-		// (?i) makes it case-insensitive if the user didn't include it
-		pattern := rule.Pattern
-		matched, err := regexp.MatchString(pattern, description)
-		if err == nil && matched {
-			return rule.Category
+// ruleColumnNames are the stored fields of a rule, in the order of ruleArgs.
+const ruleColumnNames = `priority, enabled, pattern, min_amount, max_amount, sign, account, date_from, date_to, ` +
+	`if_category_id, category_id, set_description, set_payee, add_tags, transfer`
+
+// ruleArgs resolves the categories of a rule, creating them if needed, and returns the
+// values for ruleColumnNames.
+func ruleArgs(db *sql.DB, r *CategoryRule) ([]interface{}, error) {
+	var categoryID, ifCategoryID int64
+	var err error
+	if r.Category != "" {
+		if categoryID, r.Category, err = categoryIDFor(db, r.Category); err != nil {
+			return nil, err
 		}
 	}
-	return ""
+	if r.IfCategory != "" {
+		if ifCategoryID, r.IfCategory, err = categoryIDFor(db, r.IfCategory); err != nil {
+			return nil, err
+		}
+	}
+
+	return []interface{}{
+		r.Priority, !r.Disabled, r.Pattern,
+		nullIfZero(int64(r.MinAmount)), nullIfZero(int64(r.MaxAmount)), nullIfZero(int64(r.Sign)),
+		nullIfEmpty(r.Account), nullIfZeroDate(r.From), nullIfZeroDate(r.To),
+		nullIfZero(ifCategoryID), nullIfZero(categoryID),
+		nullIfEmpty(r.Description), nullIfEmpty(r.Payee), nullIfEmpty(strings.Join(r.Tags, ",")), r.Transfer,
+	}, nil
+}
+
+const ruleSelect = `r.id, r.priority, r.enabled, r.pattern, COALESCE(r.min_amount, 0), COALESCE(r.max_amount, 0),
+        COALESCE(r.sign, 0), COALESCE(r.account, ''), COALESCE(r.date_from, ''), COALESCE(r.date_to, ''),
+        COALESCE(ic.path, ''), COALESCE(cp.path, ''), COALESCE(r.set_description, ''), COALESCE(r.set_payee, ''),
+        COALESCE(r.add_tags, ''), r.transfer
+        FROM category_rules r
+        LEFT JOIN category_paths cp ON cp.id = r.category_id
+        LEFT JOIN category_paths ic ON ic.id = r.if_category_id`
+
+func scanRule(row rowScanner) (*CategoryRule, error) {
+	var r CategoryRule
+	var enabled bool
+	var from, to, tags string
+	err := row.Scan(&r.ID, &r.Priority, &enabled, &r.Pattern, &r.MinAmount, &r.MaxAmount, &r.Sign, &r.Account,
+		&from, &to, &r.IfCategory, &r.Category, &r.Description, &r.Payee, &tags, &r.Transfer)
+	if err != nil {
+		return nil, err
+	}
+	r.Disabled = !enabled
+	r.From, _ = time.Parse("2006-01-02", from)
+	r.To, _ = time.Parse("2006-01-02", to)
+	if tags != "" {
+		r.Tags = strings.Split(tags, ",")
+	}
+	return &r, nil
 }
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseConfidence reads the classifier_confidence setting: a number above 0 and up to 1,
+// or "off", which returns 0.
+func ParseConfidence(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "off") {
+		return 0, nil
+	}
+	c, err := strconv.ParseFloat(s, 64)
+	if err != nil || c <= 0 || c > 1 {
+		return 0, fmt.Errorf("invalid confidence %q (use a number above 0 and up to 1, or off)", s)
+	}
+	return c, nil
+}
+
+// FormatConfidence gives a confidence in the form ParseConfidence reads.
+func FormatConfidence(c float64) string {
+	if c <= 0 {
+		return "off"
+	}
+	return strconv.FormatFloat(c, 'g', -1, 64)
+}
+
+// Prediction is the category the classifier finds most likely for a transaction.
+type Prediction struct {
+	Category   string
+	Confidence float64 // probability of the category given the transaction, 0 to 1
+}
+
+// Classifier guesses categories from the transactions categorized so far: a naive Bayes
+// model over the words of the description (as NormalizeDescription keeps them) and the
+// size of the amount. It learns what rules would need a regex per merchant for.
+type Classifier struct {
+	classes []string
+	index   map[string]int   // class by category
+	docs    []int            // transactions per class
+	counts  []map[string]int // feature counts per class
+	totals  []int            // all feature counts per class
+	vocab   map[string]bool  // every feature seen
+	words   map[string]bool  // the description words among them
+	n       int              // transactions learned
+}
+
+// NewClassifier returns a classifier that has learned nothing yet.
+func NewClassifier() *Classifier {
+	return &Classifier{index: map[string]int{}, vocab: map[string]bool{}, words: map[string]bool{}}
+}
+
+// TrainClassifier learns from every categorized transaction in the database. Transfers
+// and uncategorized transactions teach nothing about spending.
+func TrainClassifier(db *sql.DB) (*Classifier, error) {
+	rows, err := db.Query(`
+        SELECT t.description, t.amount, cp.path
+        FROM transactions t JOIN category_paths cp ON cp.id = t.category_id
+        WHERE t.transfer_id IS NULL AND cp.path NOT IN ('Uncategorized', ?)
+    `, TransferCategory)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	c := NewClassifier()
+	for rows.Next() {
+		var description, category string
+		var amount Money
+		if err := rows.Scan(&description, &amount, &category); err != nil {
+			return nil, err
+		}
+		c.Learn(description, amount, category)
+	}
+	return c, rows.Err()
+}
+
+// Learn adds one categorized transaction to what the classifier knows.
+func (c *Classifier) Learn(description string, amount Money, category string) {
+	i, ok := c.index[category]
+	if !ok {
+		i = len(c.classes)
+		c.index[category] = i
+		c.classes = append(c.classes, category)
+		c.docs = append(c.docs, 0)
+		c.counts = append(c.counts, map[string]int{})
+		c.totals = append(c.totals, 0)
+	}
+
+	c.n++
+	c.docs[i]++
+	words, bucket := classifierFeatures(description, amount)
+	for _, f := range append(words, bucket) {
+		c.counts[i][f]++
+		c.totals[i]++
+		c.vocab[f] = true
+	}
+	for _, w := range words {
+		c.words[w] = true
+	}
+}
+
+// Predict returns the most likely category of a transaction. Without a word of its
+// description seen before, or before two categories were learned to tell apart, there is
+// nothing to go by, and the prediction is empty; so it is for a nil Classifier.
+func (c *Classifier) Predict(description string, amount Money) Prediction {
+	if c == nil {
+		return Prediction{}
+	}
+	words, bucket := classifierFeatures(description, amount)
+	known := false
+	for _, w := range words {
+		known = known || c.words[w]
+	}
+	if !known || len(c.classes) < 2 {
+		return Prediction{}
+	}
+
+	// log P(class) + sum of log P(feature | class), with add-one smoothing
+	features := append(words, bucket)
+	scores := make([]float64, len(c.classes))
+	for i := range c.classes {
+		score := math.Log(float64(c.docs[i]) / float64(c.n))
+		denominator := math.Log(float64(c.totals[i] + len(c.vocab)))
+		for _, f := range features {
+			score += math.Log(float64(c.counts[i][f]+1)) - denominator
+		}
+		scores[i] = score
+	}
+
+	best := 0
+	for i := range scores {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	// The posterior of the best class: 1 / sum of exp(score - best score)
+	sum := 0.0
+	for _, s := range scores {
+		sum += math.Exp(s - scores[best])
+	}
+	return Prediction{Category: c.classes[best], Confidence: 1 / sum}
+}
+
+// classifierFeatures returns the words of a description and a feature for the sign and
+// order of magnitude of the amount: coffees and rents of the same shop differ in both.
+func classifierFeatures(description string, amount Money) ([]string, string) {
+	words := strings.Fields(NormalizeDescription(description))
+	sign := "+"
+	if amount < 0 {
+		sign = "-"
+	}
+	digits := len(strconv.FormatInt(int64(amount.Abs()/100), 10))
+	return words, fmt.Sprintf("amount:%s%d", sign, digits)
+}
+
+// Suggestion is a learned category proposed for an uncategorized transaction.
+type Suggestion struct {
+	Transaction Transaction
+	Prediction
+}
+
+// SuggestCategories predicts a category for every uncategorized transaction the
+// classifier has something to say about, most confident first.
+func SuggestCategories(db *sql.DB, c *Classifier) ([]Suggestion, error) {
+	txs, err := FindTransactions(db, TransactionFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Suggestion
+	for _, t := range txs {
+		if t.Category != "Uncategorized" || t.TransferID != 0 {
+			continue
+		}
+		if p := c.Predict(t.Description, t.Amount); p.Category != "" {
+			list = append(list, Suggestion{Transaction: t, Prediction: p})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Confidence > list[j].Confidence })
+	return list, nil
+}
+
+// AcceptSuggestions stores the suggested categories, all or none.
+func AcceptSuggestions(db *sql.DB, list []Suggestion) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, s := range list {
+		id, _, err := categoryIDFor(tx, s.Category)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE transactions SET category_id = ? WHERE id = ?`, id, s.Transaction.ID); err != nil {
+			return fmt.Errorf("failed to categorize transaction %d: %w", s.Transaction.ID, err)
+		}
+	}
+	return tx.Commit()
+}
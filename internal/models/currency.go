@@ -0,0 +1,317 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// DefaultBaseCurrency is used for reports when no base currency is configured.
+// Transactions without a currency are assumed to be in the base currency.
+const DefaultBaseCurrency = "EUR"
+
+// ExchangeRate says that on Date, 1 unit of Base was worth Rate units of Quote
+// (the convention used by the ECB reference rates: 1 EUR = 1.0765 USD).
+type ExchangeRate struct {
+	ID    int64
+	Date  time.Time
+	Base  string
+	Quote string
+	Rate  string // exact decimal, e.g. "4.9765"
+}
+
+// NormalizeCurrency upper-cases and validates an ISO 4217 style code ("usd" -> "USD").
+// An empty code stays empty.
+func NormalizeCurrency(code string) (string, error) {
+	c := strings.ToUpper(strings.TrimSpace(code))
+	if c == "" {
+		return "", nil
+	}
+	if len(c) != 3 {
+		return "", fmt.Errorf("invalid currency code %q (expected 3 letters, e.g. EUR)", code)
+	}
+	for _, r := range c {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency code %q (expected 3 letters, e.g. EUR)", code)
+		}
+	}
+	return c, nil
+}
+
+// ParseRate parses an exchange rate into an exact rational number.
+func ParseRate(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || r.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid exchange rate %q", s)
+	}
+	return r, nil
+}
+
+// Mul multiplies m by an exact rate, rounding half away from zero to the nearest minor unit.
+// It fails if the result is too large to hold.
+func (m Money) Mul(rate *big.Rat) (Money, error) {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(m)), rate)
+	num, den := product.Num(), product.Denom()
+
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	twiceRem := new(big.Int).Abs(rem)
+	twiceRem.Lsh(twiceRem, 1)
+	if twiceRem.Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("amount %s times %s is too large", m, rate.RatString())
+	}
+	return Money(q.Int64()), nil
+}
+
+// SetRate stores a rate, replacing an existing one for the same day and pair.
+func SetRate(db *sql.DB, r *ExchangeRate) error {
+	var err error
+	if r.Base, err = NormalizeCurrency(r.Base); err != nil {
+		return err
+	}
+	if r.Quote, err = NormalizeCurrency(r.Quote); err != nil {
+		return err
+	}
+	if r.Base == "" || r.Quote == "" || r.Base == r.Quote {
+		return fmt.Errorf("a rate needs two different currencies")
+	}
+	if _, err := ParseRate(r.Rate); err != nil {
+		return err
+	}
+	r.Rate = strings.TrimSpace(r.Rate)
+
+	res, err := db.Exec(`
+        INSERT INTO exchange_rates (date, base, quote, rate)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT(date, base, quote) DO UPDATE SET rate = excluded.rate;
+    `, r.Date.Format("2006-01-02"), r.Base, r.Quote, r.Rate)
+	if err != nil {
+		return fmt.Errorf("failed to store rate: %w", err)
+	}
+	r.ID, err = res.LastInsertId()
+	return err
+}
+
+// ListRates returns stored rates, newest first. A non-empty currency limits the
+// list to rates where it is either the base or the quote.
+func ListRates(db *sql.DB, currency string) ([]ExchangeRate, error) {
+	query := `SELECT id, date, base, quote, rate FROM exchange_rates`
+	var args []interface{}
+	if currency != "" {
+		query += ` WHERE base = ? OR quote = ?`
+		args = append(args, currency, currency)
+	}
+	query += ` ORDER BY date DESC, base, quote;`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []ExchangeRate
+	for rows.Next() {
+		var r ExchangeRate
+		var dateStr string
+		if err := rows.Scan(&r.ID, &dateStr, &r.Base, &r.Quote, &r.Rate); err != nil {
+			return nil, err
+		}
+		r.Date, _ = time.Parse("2006-01-02", dateStr)
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+func DeleteRate(db *sql.DB, id int64) error {
+	_, err := db.Exec(`DELETE FROM exchange_rates WHERE id = ?`, id)
+	return err
+}
+
+// Converter turns amounts into a base currency using the rate in effect on each
+// transaction date, i.e. the most recent rate recorded on or before that day.
+// Looked-up rates are cached, so a Converter should be used for a single report.
+type Converter struct {
+	db     *sql.DB
+	base   string
+	cache  map[string]*big.Rat
+	pivots []string
+}
+
+// NewConverter creates a Converter into base (DefaultBaseCurrency if empty).
+func NewConverter(db *sql.DB, base string) (*Converter, error) {
+	base, err := NormalizeCurrency(base)
+	if err != nil {
+		return nil, err
+	}
+	if base == "" {
+		base = DefaultBaseCurrency
+	}
+	return &Converter{db: db, base: base, cache: map[string]*big.Rat{}}, nil
+}
+
+// Base returns the currency amounts are converted into.
+func (c *Converter) Base() string {
+	return c.base
+}
+
+// Convert converts amount from currency into the base currency. An empty
+// currency means the amount already is in the base currency.
+func (c *Converter) Convert(amount Money, currency string, date time.Time) (Money, error) {
+	return c.ConvertTo(amount, currency, c.base, date)
+}
+
+// ConvertTo converts amount between two currencies; empty codes mean the base currency.
+func (c *Converter) ConvertTo(amount Money, from, to string, date time.Time) (Money, error) {
+	if from == "" {
+		from = c.base
+	}
+	if to == "" {
+		to = c.base
+	}
+	if from == to || amount == 0 {
+		return amount, nil
+	}
+	rate, err := c.Rate(from, to, date)
+	if err != nil {
+		return 0, err
+	}
+	return amount.Mul(rate)
+}
+
+// Rate finds how many units of `to` one unit of `from` was worth on date. It uses a
+// direct rate, the inverse of the opposite rate, or a cross rate through a third
+// currency (ECB files only contain EUR-based rates, so USD->RON goes through EUR).
+func (c *Converter) Rate(from, to string, date time.Time) (*big.Rat, error) {
+	day := date.Format("2006-01-02")
+	key := from + to + day
+	if r, ok := c.cache[key]; ok {
+		return r, nil
+	}
+
+	r, err := c.pairRate(from, to, day)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		if c.pivots == nil {
+			if c.pivots, err = c.rateCurrencies(); err != nil {
+				return nil, err
+			}
+		}
+		for _, pivot := range c.pivots {
+			if pivot == from || pivot == to {
+				continue
+			}
+			toPivot, err := c.pairRate(from, pivot, day)
+			if err != nil {
+				return nil, err
+			}
+			if toPivot == nil {
+				continue
+			}
+			fromPivot, err := c.pairRate(pivot, to, day)
+			if err != nil {
+				return nil, err
+			}
+			if fromPivot != nil {
+				r = new(big.Rat).Mul(toPivot, fromPivot)
+				break
+			}
+		}
+	}
+	if r == nil {
+		return nil, fmt.Errorf("no exchange rate from %s to %s on or before %s", from, to, day)
+	}
+
+	c.cache[key] = r
+	return r, nil
+}
+
+// pairRate looks for a direct or inverse rate between two currencies. It returns
+// nil (without error) when neither is recorded.
+func (c *Converter) pairRate(from, to, day string) (*big.Rat, error) {
+	var base, rateStr string
+	err := c.db.QueryRow(`
+        SELECT base, rate FROM exchange_rates
+        WHERE ((base = ? AND quote = ?) OR (base = ? AND quote = ?))
+        AND date <= ?
+        ORDER BY date DESC, base = ? DESC
+        LIMIT 1;
+    `, from, to, to, from, day, from).Scan(&base, &rateStr)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := ParseRate(rateStr)
+	if err != nil {
+		return nil, err
+	}
+	if base != from {
+		r.Inv(r)
+	}
+	return r, nil
+}
+
+func (c *Converter) rateCurrencies() ([]string, error) {
+	rows, err := c.db.Query(`SELECT base FROM exchange_rates UNION SELECT quote FROM exchange_rates ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []string{}
+	for rows.Next() {
+		var cur string
+		if err := rows.Scan(&cur); err != nil {
+			return nil, err
+		}
+		list = append(list, cur)
+	}
+	return list, rows.Err()
+}
+
+// dailySum is an amount summed per key (e.g. category), currency and day, the granularity
+// at which exchange rates change.
+type dailySum struct {
+	Key      string
+	Currency string
+	Day      string // "YYYY-MM-DD"
+	Amount   Money
+}
+
+// scanDailySums reads (key, currency, day, sum) rows completely before any rate is looked
+// up, so no second query runs while the result set is still open.
+func scanDailySums(rows *sql.Rows) ([]dailySum, error) {
+	defer rows.Close()
+
+	var sums []dailySum
+	for rows.Next() {
+		var s dailySum
+		if err := rows.Scan(&s.Key, &s.Currency, &s.Day, &s.Amount); err != nil {
+			return nil, err
+		}
+		sums = append(sums, s)
+	}
+	return sums, rows.Err()
+}
+
+// convertDaily converts a daily sum into the target currency (empty = base currency).
+// A nil converter leaves the amount untouched.
+func (c *Converter) convertDaily(s dailySum, to string) (Money, error) {
+	if c == nil {
+		return s.Amount, nil
+	}
+	date, _ := time.Parse("2006-01-02", s.Day)
+	return c.ConvertTo(s.Amount, s.Currency, to, date)
+}
@@ -0,0 +1,406 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FuzzyMatch says how close two transactions from different sources must be to count as
+// the same one, e.g. a card payment read once from a CSV export ("STARBUCKS 054") and
+// once from an OFX statement ("Starbucks - Coffee") with a later posting date.
+type FuzzyMatch struct {
+	Days       int     // booking dates at most this many days apart
+	Tolerance  Money   // amounts at most this far apart
+	Similarity float64 // minimum DescriptionSimilarity; 0 turns fuzzy matching off
+}
+
+// DefaultFuzzyMatch gives the limits a fuzzy_dedup setting leaves out; "on" is all of
+// them. Imports only match fuzzily if the setting turns it on, but 'duplicates' uses
+// them unless told otherwise.
+var DefaultFuzzyMatch = FuzzyMatch{Days: 3, Similarity: 0.5}
+
+// Enabled reports whether fuzzy matching is turned on.
+func (m FuzzyMatch) Enabled() bool {
+	return m.Similarity > 0
+}
+
+// String gives the setting in the form ParseFuzzyMatch reads.
+func (m FuzzyMatch) String() string {
+	if !m.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("days=%d,amount=%s,similarity=%s", m.Days, m.Tolerance, strconv.FormatFloat(m.Similarity, 'g', -1, 64))
+}
+
+// ParseFuzzyMatch reads a setting such as "days=3,amount=0.50,similarity=0.6"; keys left
+// out keep their default. "on" is DefaultFuzzyMatch and "off" turns fuzzy matching off.
+func ParseFuzzyMatch(s string) (FuzzyMatch, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "off") {
+		return FuzzyMatch{}, nil
+	}
+	if strings.EqualFold(s, "on") {
+		return DefaultFuzzyMatch, nil
+	}
+
+	m := DefaultFuzzyMatch
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok {
+			return m, fmt.Errorf("invalid fuzzy matching setting %q (use e.g. days=3,amount=0.50,similarity=0.5 or off)", part)
+		}
+
+		switch key {
+		case "days":
+			days, err := strconv.Atoi(value)
+			if err != nil || days < 0 || days > 31 {
+				return m, fmt.Errorf("invalid number of days %q (use 0 to 31)", value)
+			}
+			m.Days = days
+		case "amount":
+			amount, err := ParseMoney(value)
+			if err != nil || amount < 0 {
+				return m, fmt.Errorf("invalid amount tolerance %q", value)
+			}
+			m.Tolerance = amount
+		case "similarity":
+			sim, err := strconv.ParseFloat(value, 64)
+			if err != nil || sim <= 0 || sim > 1 {
+				return m, fmt.Errorf("invalid similarity %q (use a number above 0 and up to 1)", value)
+			}
+			m.Similarity = sim
+		default:
+			return m, fmt.Errorf("unknown fuzzy matching key %q (use days, amount or similarity)", key)
+		}
+	}
+	return m, nil
+}
+
+// descriptionNoise are words banks add to descriptions that say nothing about the payee.
+var descriptionNoise = map[string]bool{
+	"card": true, "payment": true, "purchase": true, "pos": true, "debit": true, "credit": true,
+	"visa": true, "mastercard": true, "maestro": true, "contactless": true, "online": true,
+	"sepa": true, "direct": true, "transfer": true, "ref": true, "www": true, "com": true,
+}
+
+// NormalizeDescription reduces a bank description to the words naming the payee: lower
+// case, letters only, without reference numbers and words like "card payment".
+func NormalizeDescription(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) })
+	kept := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) > 1 && !descriptionNoise[w] {
+			kept = append(kept, w)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// DescriptionSimilarity compares two descriptions by the words of their normalized forms:
+// the share of the shorter one's words that the other one contains, from 0 to 1. It is 1
+// for "STARBUCKS 054" and "Starbucks - Coffee", as one source often adds words the other
+// leaves out.
+func DescriptionSimilarity(a, b string) float64 {
+	wa, wb := strings.Fields(NormalizeDescription(a)), strings.Fields(NormalizeDescription(b))
+	if len(wa) == 0 || len(wb) == 0 {
+		// Nothing but noise: only the same text is the same payee
+		if strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b)) {
+			return 1
+		}
+		return 0
+	}
+	if len(wa) > len(wb) {
+		wa, wb = wb, wa
+	}
+
+	words := map[string]bool{}
+	for _, w := range wb {
+		words[w] = true
+	}
+	common := 0
+	for _, w := range wa {
+		if words[w] {
+			common++
+			delete(words, w)
+		}
+	}
+	return float64(common) / float64(len(wa))
+}
+
+// similarity returns how alike two transactions are and whether they are close enough
+// to be the same one. The account and the source are checked by the callers.
+func (m FuzzyMatch) similarity(a, b *Transaction) (float64, bool) {
+	if (a.Amount < 0) != (b.Amount < 0) || (a.Amount-b.Amount).Abs() > m.Tolerance || a.Currency != b.Currency {
+		return 0, false
+	}
+	if daysApart(a.Date, b.Date) > m.Days {
+		return 0, false
+	}
+	sim := DescriptionSimilarity(a.Description, b.Description)
+	return sim, sim >= m.Similarity
+}
+
+func daysApart(a, b time.Time) int {
+	d := int(a.Sub(b).Hours() / 24)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// sourcedTransactions are the transactions with the source they came from: the format of
+// their import batch, or "" if they were entered by hand.
+const sourcedTransactions = `(
+        SELECT t.*, COALESCE(b.format, '') AS source
+        FROM ` + transactionSource + ` t LEFT JOIN import_batches b ON b.id = t.import_batch_id)`
+
+// likelyDuplicatesSQL finds the candidates near a transaction by idx_transactions_dedup.
+// It reads only what the comparison needs; the category path is costly to resolve, and
+// most candidates turn out not to match.
+const likelyDuplicatesSQL = `
+        SELECT t.id, t.date, t.description, t.amount, COALESCE(t.currency, '')
+        FROM transactions t LEFT JOIN import_batches b ON b.id = t.import_batch_id
+        WHERE t.account IS ? AND t.date BETWEEN ? AND ? AND t.amount BETWEEN ? AND ?
+        AND COALESCE(b.format, '') != ?`
+
+func likelyDuplicatesArgs(t *Transaction, source string, m FuzzyMatch) []interface{} {
+	return []interface{}{
+		nullIfEmpty(t.Account),
+		t.Date.AddDate(0, 0, -m.Days).Format("2006-01-02"), t.Date.AddDate(0, 0, m.Days).Format("2006-01-02"),
+		t.Amount - m.Tolerance, t.Amount + m.Tolerance,
+		source,
+	}
+}
+
+// FindLikelyDuplicates returns the transactions of the same account that fuzzily match t
+// but came from another source than source (an importer name, "" for entered by hand).
+// The closest ones come first: nearest date, then most similar description.
+func FindLikelyDuplicates(db *sql.DB, t *Transaction, source string, m FuzzyMatch) ([]Transaction, error) {
+	rows, err := db.Query(likelyDuplicatesSQL, likelyDuplicatesArgs(t, source, m)...)
+	if err != nil {
+		return nil, err
+	}
+	return likelyDuplicates(db, rows, t, m)
+}
+
+func likelyDuplicates(q querier, rows *sql.Rows, t *Transaction, m FuzzyMatch) ([]Transaction, error) {
+	type candidate struct {
+		id   int64
+		days int
+		sim  float64
+	}
+	var found []candidate
+	for rows.Next() {
+		var c Transaction
+		var dateStr string
+		if err := rows.Scan(&c.ID, &dateStr, &c.Description, &c.Amount, &c.Currency); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.Date, _ = time.Parse("2006-01-02", dateStr)
+		if sim, ok := m.similarity(t, &c); ok {
+			found = append(found, candidate{c.ID, daysApart(t.Date, c.Date), sim})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].days != found[j].days {
+			return found[i].days < found[j].days
+		}
+		return found[i].sim > found[j].sim
+	})
+	list := make([]Transaction, len(found))
+	for i, c := range found {
+		tr, err := getTransaction(q, c.id)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = *tr
+	}
+	return list, nil
+}
+
+// DuplicatePair is one transaction booked twice. Keep is the one MergeDuplicates keeps:
+// the one with the bank's reference, else the older one.
+type DuplicatePair struct {
+	Keep       Transaction
+	Duplicate  Transaction
+	Similarity float64 // DescriptionSimilarity of the two
+}
+
+// FindDuplicates looks for transactions already stored twice: pairs in the same account
+// that fuzzily match and came from different sources (two import formats, or an import
+// and an entry by hand). Each transaction is in at most one pair; the closest ones win.
+func FindDuplicates(db *sql.DB, m FuzzyMatch) ([]DuplicatePair, error) {
+	if !m.Enabled() {
+		return nil, fmt.Errorf("fuzzy matching is off")
+	}
+
+	rows, err := db.Query(`SELECT ` + transactionColumns + `, source FROM ` + sourcedTransactions + `
+        ORDER BY COALESCE(account, ''), date, id`)
+	if err != nil {
+		return nil, err
+	}
+	var list []Transaction
+	var sources []string
+	for rows.Next() {
+		var source string
+		t, err := scanTransaction(withColumn{rows, &source})
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, *t)
+		sources = append(sources, source)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		a, b int // indexes into list
+		days int
+		sim  float64
+	}
+	var candidates []candidate
+	for i := range list {
+		for j := i + 1; j < len(list) && list[j].Account == list[i].Account; j++ {
+			days := daysApart(list[i].Date, list[j].Date)
+			if days > m.Days {
+				break // sorted by date
+			}
+			if sources[i] == sources[j] {
+				continue
+			}
+			if sim, ok := m.similarity(&list[i], &list[j]); ok {
+				candidates = append(candidates, candidate{i, j, days, sim})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].days != candidates[j].days {
+			return candidates[i].days < candidates[j].days
+		}
+		return candidates[i].sim > candidates[j].sim
+	})
+
+	used := map[int]bool{}
+	var pairs []DuplicatePair
+	for _, c := range candidates {
+		if used[c.a] || used[c.b] {
+			continue
+		}
+		used[c.a], used[c.b] = true, true
+
+		keep, dup := list[c.a], list[c.b]
+		if prefer(dup, keep) {
+			keep, dup = dup, keep
+		}
+		pairs = append(pairs, DuplicatePair{Keep: keep, Duplicate: dup, Similarity: c.sim})
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Keep.Date.Before(pairs[j].Keep.Date) })
+	return pairs, nil
+}
+
+// prefer reports whether a is the better one to keep of two duplicates.
+func prefer(a, b Transaction) bool {
+	if (a.ExternalID != "") != (b.ExternalID != "") {
+		return a.ExternalID != ""
+	}
+	return a.ID < b.ID
+}
+
+// withColumn scans one more column after those of the wrapped row.
+type withColumn struct {
+	rowScanner
+	dest interface{}
+}
+
+func (w withColumn) Scan(dest ...interface{}) error {
+	return w.rowScanner.Scan(append(dest, w.dest)...)
+}
+
+// MergeDuplicates folds the transaction dupID into keepID and deletes it. keepID takes over
+// dupID's tags and, where it has none of its own, its category, split lines, bank reference
+// and transfer, so nothing entered on either of them is lost.
+func MergeDuplicates(db *sql.DB, keepID, dupID int64) error {
+	if keepID == dupID {
+		return fmt.Errorf("can't merge transaction %d with itself", keepID)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	keep, err := getTransaction(tx, keepID)
+	if err != nil {
+		return fmt.Errorf("transaction %d not found", keepID)
+	}
+	dup, err := getTransaction(tx, dupID)
+	if err != nil {
+		return fmt.Errorf("transaction %d not found", dupID)
+	}
+	if keep.Account != dup.Account {
+		return fmt.Errorf("transactions %d and %d belong to different accounts", keepID, dupID)
+	}
+	if keep.TransferID != 0 && keep.TransferID == dup.TransferID {
+		return fmt.Errorf("transactions %d and %d are the two legs of a transfer", keepID, dupID)
+	}
+
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO transaction_tags (transaction_id, tag_id)
+        SELECT ?, tag_id FROM transaction_tags WHERE transaction_id = ?`, keepID, dupID); err != nil {
+		return err
+	}
+	if keep.Category == "Uncategorized" && dup.Category != "Uncategorized" {
+		if _, err := tx.Exec(`UPDATE transactions SET category_id = ? WHERE id = ?`, dup.CategoryID, keepID); err != nil {
+			return err
+		}
+	}
+
+	// Split lines only fit a transaction of the same amount
+	var splits int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM transaction_splits WHERE transaction_id = ?`, keepID).Scan(&splits); err != nil {
+		return err
+	}
+	if splits == 0 && keep.Amount == dup.Amount {
+		if _, err := tx.Exec(`UPDATE transaction_splits SET transaction_id = ? WHERE transaction_id = ?`, keepID, dupID); err != nil {
+			return err
+		}
+	}
+
+	// With the bank's reference, a later import of dup's source recognizes it exactly
+	if keep.ExternalID == "" && dup.ExternalID != "" {
+		if _, err := tx.Exec(`UPDATE transactions SET external_id = ?, external_account = ? WHERE id = ?`,
+			dup.ExternalID, nullIfEmpty(dup.ExternalAccount), keepID); err != nil {
+			return err
+		}
+	}
+	if keep.TransferID == 0 && dup.TransferID != 0 {
+		if _, err := tx.Exec(`UPDATE transactions SET transfer_id = CASE id WHEN ? THEN ? END WHERE id IN (?, ?)`,
+			keepID, dup.TransferID, keepID, dupID); err != nil {
+			return err
+		}
+	}
+
+	if err := deleteTransaction(tx, dupID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ImportBatch is one run of 'finance import': the file read and what became of its records.
+// The transactions it stored carry its ID.
+type ImportBatch struct {
+	ID         int64
+	FileName   string
+	FileHash   string // see HashFile
+	Format     string // importer name, e.g. "ofx"
+	Account    string // empty if the transactions have no account
+	Imported   int
+	Duplicates int
+	Errors     int
+	CreatedAt  time.Time
+}
+
+// HashFile returns the hash identifying a file's content in import_batches.
+func HashFile(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FindImportBatchByHash returns the latest batch that read a file with the given hash,
+// or nil if there is none.
+func FindImportBatchByHash(db *sql.DB, hash string) (*ImportBatch, error) {
+	b, err := scanImportBatch(db.QueryRow(`SELECT `+importBatchColumns+` FROM import_batches
+        WHERE file_hash = ? ORDER BY id DESC LIMIT 1`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// GetImportBatch looks a batch up by ID.
+func GetImportBatch(db *sql.DB, id int64) (*ImportBatch, error) {
+	b, err := scanImportBatch(db.QueryRow(`SELECT `+importBatchColumns+` FROM import_batches WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("import batch %d not found (see 'finance import list')", id)
+	}
+	return b, err
+}
+
+// ListImportBatches returns all batches, newest first.
+func ListImportBatches(db *sql.DB) ([]ImportBatch, error) {
+	rows, err := db.Query(`SELECT ` + importBatchColumns + ` FROM import_batches ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []ImportBatch
+	for rows.Next() {
+		b, err := scanImportBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *b)
+	}
+	return list, rows.Err()
+}
+
+// UndoImportBatch deletes the transactions of a batch, with their split lines and tags,
+// and then the batch itself, so the file can be imported again. It returns the number
+// of transactions deleted.
+func UndoImportBatch(db *sql.DB, id int64) (int, error) {
+	if _, err := GetImportBatch(db, id); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id FROM transactions WHERE import_batch_id = ?`, id)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var tid int64
+		if err := rows.Scan(&tid); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, tid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, tid := range ids {
+		if err := deleteTransaction(tx, tid); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM import_batches WHERE id = ?`, id); err != nil {
+		return 0, err
+	}
+	return len(ids), tx.Commit()
+}
+
+const importBatchColumns = `id, file_name, file_hash, format, COALESCE(account, ''), imported, duplicates, errors, created_at`
+
+func scanImportBatch(row rowScanner) (*ImportBatch, error) {
+	var b ImportBatch
+	if err := row.Scan(&b.ID, &b.FileName, &b.FileHash, &b.Format, &b.Account, &b.Imported, &b.Duplicates, &b.Errors, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
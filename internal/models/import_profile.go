@@ -0,0 +1,229 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sign conventions of the amounts in a CSV export.
+const (
+	SignSigned      = "signed"       // one amount column, negative for expenses
+	SignInverted    = "inverted"     // one amount column, positive for expenses (common for credit cards)
+	SignDebitCredit = "debit-credit" // money out and money in in separate columns
+)
+
+// SignConventions lists the values ImportProfile.Sign accepts.
+var SignConventions = []string{SignSigned, SignInverted, SignDebitCredit}
+
+// DefaultProfile is the layout used for CSV files whose header matches no profile:
+// Date,Description,Amount[,Category[,Currency[,Account[,Transfer]]]] with ISO dates, as
+// the CSV export writes it.
+const DefaultProfile = "generic"
+
+// ImportProfile describes the CSV layout of one bank's export. A column is named by its
+// header text (case-insensitive) or by its 1-based position.
+type ImportProfile struct {
+	Name        string
+	Delimiter   string   // a single character
+	Header      bool     // the first row (after SkipRows) holds the column names
+	SkipRows    int      // lines before the header or data, e.g. account details
+	Date        string   // column of the booking date
+	Description []string // columns joined with " - "; their names can't contain "+"
+	Amount      string   // signed or inverted amounts
+	Debit       string   // debit-credit: money out
+	Credit      string   // debit-credit: money in
+	Category    string   // optional
+	Currency    string   // optional
+	Account     string   // optional: the account the row belongs to
+	Transfer    string   // optional: the account of the other leg, if the row is a transfer
+	DateLayout  string   // Go layout, e.g. "02.01.2006"
+	Decimal     string   // "." or ","; the other one is taken as thousands separator
+	Sign        string   // one of SignConventions
+	BuiltIn     bool     // shipped with the program, can't be changed
+}
+
+// BuiltinProfiles are the layouts known out of the box.
+var BuiltinProfiles = []ImportProfile{
+	{Name: DefaultProfile, Delimiter: ",", Date: "1", Description: []string{"2"}, Amount: "3", Category: "4", Currency: "5",
+		Account: "6", Transfer: "7", DateLayout: "2006-01-02", Decimal: ".", Sign: SignSigned},
+	{Name: "ing-ro", Delimiter: ",", Header: true, Date: "Data", Description: []string{"Detalii tranzactie"},
+		Debit: "Debit", Credit: "Credit", DateLayout: "02.01.2006", Decimal: ",", Sign: SignDebitCredit},
+	{Name: "revolut", Delimiter: ",", Header: true, Date: "Completed Date", Description: []string{"Description"},
+		Amount: "Amount", Currency: "Currency", DateLayout: "2006-01-02 15:04:05", Decimal: ".", Sign: SignSigned},
+	{Name: "sparkasse", Delimiter: ";", Header: true, Date: "Buchungstag",
+		Description: []string{"Beguenstigter/Zahlungspflichtiger", "Verwendungszweck"},
+		Amount:      "Betrag", Currency: "Waehrung", DateLayout: "02.01.06", Decimal: ",", Sign: SignSigned},
+	{Name: "chase", Delimiter: ",", Header: true, Date: "Transaction Date", Description: []string{"Description"},
+		Amount: "Amount", DateLayout: "01/02/2006", Decimal: ".", Sign: SignSigned},
+}
+
+func init() {
+	for i := range BuiltinProfiles {
+		BuiltinProfiles[i].BuiltIn = true
+	}
+}
+
+// Columns returns every column the profile reads, the optional ones only if set.
+func (p ImportProfile) Columns() []string {
+	cols := append([]string{p.Date}, p.Description...)
+	for _, c := range []string{p.Amount, p.Debit, p.Credit, p.Category, p.Currency, p.Account, p.Transfer} {
+		if c != "" {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// Validate checks that the profile describes a readable layout.
+func (p ImportProfile) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("a profile needs a name")
+	}
+	if utf8.RuneCountInString(p.Delimiter) != 1 {
+		return fmt.Errorf("the delimiter must be a single character, got %q", p.Delimiter)
+	}
+	if p.SkipRows < 0 {
+		return fmt.Errorf("skip rows cannot be negative")
+	}
+	if p.Date == "" || len(p.Description) == 0 {
+		return fmt.Errorf("a profile needs a date and a description column")
+	}
+	for _, c := range p.Description {
+		if strings.TrimSpace(c) == "" {
+			return fmt.Errorf("empty description column")
+		}
+		// The columns are stored joined by "+"
+		if strings.Contains(c, "+") {
+			return fmt.Errorf("description column %q contains '+'; name it by its position instead", c)
+		}
+	}
+	if p.DateLayout == "" {
+		return fmt.Errorf("a profile needs a date format")
+	}
+	if p.Decimal != "." && p.Decimal != "," {
+		return fmt.Errorf("the decimal separator must be '.' or ','")
+	}
+	switch p.Sign {
+	case SignSigned, SignInverted:
+		if p.Amount == "" {
+			return fmt.Errorf("sign %q needs an amount column", p.Sign)
+		}
+	case SignDebitCredit:
+		if p.Debit == "" || p.Credit == "" {
+			return fmt.Errorf("sign %q needs a debit and a credit column", p.Sign)
+		}
+	default:
+		return fmt.Errorf("unknown sign convention %q (use one of: %s)", p.Sign, strings.Join(SignConventions, ", "))
+	}
+	if !p.Header {
+		for _, c := range p.Columns() {
+			if !isDigits(c) || c == "0" {
+				return fmt.Errorf("without a header row, columns must be positions (1, 2, ...), got %q", c)
+			}
+		}
+	}
+	return nil
+}
+
+// SaveImportProfile creates a profile or replaces the one with the same name.
+// The built-in profiles can't be replaced.
+func SaveImportProfile(db *sql.DB, p *ImportProfile) error {
+	p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+	if builtinProfile(p.Name) != nil {
+		return fmt.Errorf("%s is a built-in profile; save your variant under another name", p.Name)
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+        INSERT OR REPLACE INTO import_profiles (name, delimiter, header, skip_rows, date_column, description_columns,
+            amount_column, debit_column, credit_column, category_column, currency_column, account_column, transfer_column,
+            date_layout, decimal_separator, sign)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+    `, p.Name, p.Delimiter, p.Header, p.SkipRows, p.Date, strings.Join(p.Description, "+"),
+		nullIfEmpty(p.Amount), nullIfEmpty(p.Debit), nullIfEmpty(p.Credit), nullIfEmpty(p.Category), nullIfEmpty(p.Currency),
+		nullIfEmpty(p.Account), nullIfEmpty(p.Transfer), p.DateLayout, p.Decimal, p.Sign)
+	if err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	return nil
+}
+
+// GetImportProfile looks a profile up by name, built-in ones first.
+func GetImportProfile(db *sql.DB, name string) (*ImportProfile, error) {
+	if p := builtinProfile(name); p != nil {
+		return p, nil
+	}
+	p, err := scanImportProfile(db.QueryRow(`SELECT `+importProfileColumns+` FROM import_profiles WHERE name = ?`, strings.TrimSpace(name)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("import profile %q not found (see 'finance profile list')", name)
+	}
+	return p, err
+}
+
+// ListImportProfiles returns the built-in profiles followed by the user's, by name.
+func ListImportProfiles(db *sql.DB) ([]ImportProfile, error) {
+	rows, err := db.Query(`SELECT ` + importProfileColumns + ` FROM import_profiles ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := append([]ImportProfile(nil), BuiltinProfiles...)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	for rows.Next() {
+		p, err := scanImportProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *p)
+	}
+	return list, rows.Err()
+}
+
+// DeleteImportProfile removes one of the user's profiles.
+func DeleteImportProfile(db *sql.DB, name string) error {
+	if builtinProfile(name) != nil {
+		return fmt.Errorf("%s is a built-in profile and can't be removed", name)
+	}
+	res, err := db.Exec(`DELETE FROM import_profiles WHERE name = ?`, strings.TrimSpace(name))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("import profile %q not found", name)
+	}
+	return nil
+}
+
+func builtinProfile(name string) *ImportProfile {
+	for _, p := range BuiltinProfiles {
+		if strings.EqualFold(p.Name, strings.TrimSpace(name)) {
+			p := p
+			return &p
+		}
+	}
+	return nil
+}
+
+const importProfileColumns = `name, delimiter, header, skip_rows, date_column, description_columns,
+	COALESCE(amount_column, ''), COALESCE(debit_column, ''), COALESCE(credit_column, ''),
+	COALESCE(category_column, ''), COALESCE(currency_column, ''), COALESCE(account_column, ''),
+	COALESCE(transfer_column, ''), date_layout, decimal_separator, sign`
+
+func scanImportProfile(row rowScanner) (*ImportProfile, error) {
+	var p ImportProfile
+	var description string
+	err := row.Scan(&p.Name, &p.Delimiter, &p.Header, &p.SkipRows, &p.Date, &description,
+		&p.Amount, &p.Debit, &p.Credit, &p.Category, &p.Currency, &p.Account, &p.Transfer, &p.DateLayout, &p.Decimal, &p.Sign)
+	if err != nil {
+		return nil, err
+	}
+	p.Description = strings.Split(description, "+")
+	return &p, nil
+}
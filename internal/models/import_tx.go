@@ -0,0 +1,130 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ImportTx writes one import inside a single database transaction: either the whole file
+// is stored or, after Rollback or a crash, nothing is. The duplicate checks and the insert
+// are prepared once and category ids are cached, so each record costs a few statements.
+type ImportTx struct {
+	tx         *sql.Tx
+	exists     *sql.Stmt
+	fuzzy      *sql.Stmt
+	insert     *sql.Stmt
+	categories map[string]*Category // by the path as given
+}
+
+// BeginImport starts the transaction of an import.
+func BeginImport(db *sql.DB) (*ImportTx, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the import: %w", err)
+	}
+	t := &ImportTx{tx: tx, categories: map[string]*Category{}}
+	if t.exists, err = tx.Prepare(transactionExistsSQL); err == nil {
+		if t.fuzzy, err = tx.Prepare(likelyDuplicatesSQL); err == nil {
+			t.insert, err = tx.Prepare(insertTransactionSQL)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to start the import: %w", err)
+	}
+	return t, nil
+}
+
+// TransactionExists is models.TransactionExists, seeing what the import stored so far.
+func (t *ImportTx) TransactionExists(tr *Transaction) (bool, error) {
+	var exists bool
+	err := t.exists.QueryRow(transactionExistsArgs(tr)...).Scan(&exists)
+	return exists, err
+}
+
+// FindLikelyDuplicates is models.FindLikelyDuplicates inside the import.
+func (t *ImportTx) FindLikelyDuplicates(tr *Transaction, source string, m FuzzyMatch) ([]Transaction, error) {
+	rows, err := t.fuzzy.Query(likelyDuplicatesArgs(tr, source, m)...)
+	if err != nil {
+		return nil, err
+	}
+	return likelyDuplicates(t.tx, rows, tr, m)
+}
+
+// CreateTransaction is models.CreateTransaction inside the import.
+func (t *ImportTx) CreateTransaction(tr *Transaction) error {
+	c, ok := t.categories[tr.Category]
+	if !ok {
+		var err error
+		if c, err = EnsureCategory(t.tx, tr.Category); err != nil {
+			return err
+		}
+		t.categories[tr.Category] = c
+	}
+	tr.CategoryID, tr.Category = c.ID, c.Path
+
+	res, err := t.insert.Exec(insertTransactionArgs(tr)...)
+	if err != nil {
+		return err
+	}
+	tr.ID, err = res.LastInsertId()
+	return err
+}
+
+// AddSplit is models.AddSplit inside the import.
+func (t *ImportTx) AddSplit(s *Split) error {
+	return addSplit(t.tx, s)
+}
+
+// ApplyRuleResult is models.ApplyRuleResult inside the import.
+func (t *ImportTx) ApplyRuleResult(tr *Transaction, result RuleResult) error {
+	return applyRuleResult(t.tx, tr, result)
+}
+
+// LinkTransferLeg links a stored transaction with the other leg of the transfer of amount
+// it makes to account, if that is booked already; see linkTransferLeg.
+func (t *ImportTx) LinkTransferLeg(tr *Transaction, account string, amount Money) error {
+	_, err := linkTransferLeg(t.tx, tr, account, amount)
+	return err
+}
+
+// CreateBatch records the batch the import's transactions belong to.
+func (t *ImportTx) CreateBatch(b *ImportBatch) error {
+	res, err := t.tx.Exec(`
+        INSERT INTO import_batches (file_name, file_hash, format, account)
+        VALUES (?, ?, ?, ?);
+    `, b.FileName, b.FileHash, b.Format, nullIfEmpty(b.Account))
+	if err != nil {
+		return fmt.Errorf("failed to record the import: %w", err)
+	}
+	b.ID, err = res.LastInsertId()
+	return err
+}
+
+// FinishBatch stores the counts of the batch, once all records went through.
+func (t *ImportTx) FinishBatch(b *ImportBatch) error {
+	_, err := t.tx.Exec(`UPDATE import_batches SET imported = ?, duplicates = ?, errors = ? WHERE id = ?`,
+		b.Imported, b.Duplicates, b.Errors, b.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record the import: %w", err)
+	}
+	return nil
+}
+
+// Commit makes the import permanent.
+func (t *ImportTx) Commit() error {
+	t.close()
+	return t.tx.Commit()
+}
+
+// Rollback discards everything the import wrote. After Commit it only returns sql.ErrTxDone.
+func (t *ImportTx) Rollback() error {
+	t.close()
+	return t.tx.Rollback()
+}
+
+func (t *ImportTx) close() {
+	t.exists.Close()
+	t.fuzzy.Close()
+	t.insert.Close()
+}
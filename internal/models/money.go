@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount expressed in minor units (cents).
+// It is stored as an INTEGER in SQLite, so sums and comparisons never drift.
+type Money int64
+
+// MinorUnits is the number of minor units in one major unit.
+const MinorUnits = 100
+
+// ParseMoney converts a decimal string such as "-15.99", "+3" or "1200.5" into Money.
+// More than two decimals are only accepted if the extra digits are zeros, because
+// anything else cannot be represented exactly.
+func ParseMoney(s string) (Money, error) {
+	str := strings.TrimSpace(s)
+	if str == "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	negative := false
+	switch str[0] {
+	case '-':
+		negative = true
+		str = str[1:]
+	case '+':
+		str = str[1:]
+	}
+
+	whole, frac, hasDot := strings.Cut(str, ".")
+	if whole == "" && (!hasDot || frac == "") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	if len(frac) > 2 {
+		if strings.Trim(frac[2:], "0") != "" {
+			return 0, fmt.Errorf("amount %q has more than 2 decimals", s)
+		}
+		frac = frac[:2]
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	minor, _ := strconv.ParseInt(frac, 10, 64)
+	if major > (math.MaxInt64-minor)/MinorUnits {
+		return 0, fmt.Errorf("amount %q is too large", s)
+	}
+
+	m := Money(major*MinorUnits + minor)
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+// String formats the amount with exactly two decimals, e.g. "-15.99".
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+	}
+	a := m.Abs()
+	return fmt.Sprintf("%s%d.%02d", sign, a/MinorUnits, a%MinorUnits)
+}
+
+// Abs returns the absolute value of m.
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,334 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Frequencies of recurring templates. A template repeats every Interval of these units.
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+	Yearly  = "yearly"
+)
+
+// Frequencies lists the schedules a recurring template can follow.
+var Frequencies = []string{Daily, Weekly, Monthly, Yearly}
+
+// Recurring is a template for a transaction that happens on a fixed schedule, e.g. rent.
+type Recurring struct {
+	ID          int64
+	Description string
+	Amount      Money
+	Category    string // full path
+	CategoryID  int64
+	Account     string
+	Currency    string
+	Frequency   string    // one of Frequencies
+	Interval    int       // repeat every Interval days/weeks/months/years
+	Day         int       // day of the month for monthly templates; 0 means the start date's day
+	Start       time.Time // first possible occurrence
+	End         time.Time // last possible occurrence; zero means no end
+	LastRun     time.Time // occurrences up to this date have been booked; zero means never
+}
+
+// Occurrence is one scheduled date of a recurring template.
+type Occurrence struct {
+	Recurring Recurring
+	Date      time.Time
+}
+
+// RecurringRun sums up what RunRecurring did.
+type RecurringRun struct {
+	Created []Transaction
+	Skipped int // occurrences that already existed as transactions
+}
+
+// Schedule describes the template's rule for humans, e.g. "monthly on day 1" or "every 2 weeks".
+func (r Recurring) Schedule() string {
+	units := map[string]string{Daily: "days", Weekly: "weeks", Monthly: "months", Yearly: "years"}
+
+	s := r.Frequency
+	if r.Interval > 1 {
+		s = fmt.Sprintf("every %d %s", r.Interval, units[r.Frequency])
+	}
+	if r.Frequency == Monthly {
+		s += fmt.Sprintf(" on day %d", r.day())
+	}
+	return s
+}
+
+// Validate checks the schedule of a template.
+func (r Recurring) Validate() error {
+	if strings.TrimSpace(r.Description) == "" {
+		return fmt.Errorf("a recurring transaction needs a description")
+	}
+	if !validFrequency(r.Frequency) {
+		return fmt.Errorf("unknown frequency %q (use one of: %s)", r.Frequency, strings.Join(Frequencies, ", "))
+	}
+	if r.Interval < 1 {
+		return fmt.Errorf("the interval must be at least 1")
+	}
+	if r.Day != 0 && r.Frequency != Monthly {
+		return fmt.Errorf("a day of the month only applies to monthly schedules")
+	}
+	if r.Day < 0 || r.Day > 31 {
+		return fmt.Errorf("day of the month must be between 1 and 31")
+	}
+	if r.Start.IsZero() {
+		return fmt.Errorf("a recurring transaction needs a start date")
+	}
+	if !r.End.IsZero() && r.End.Before(r.Start) {
+		return fmt.Errorf("the end date lies before the start date")
+	}
+	return nil
+}
+
+func validFrequency(f string) bool {
+	for _, known := range Frequencies {
+		if f == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Recurring) day() int {
+	if r.Day != 0 {
+		return r.Day
+	}
+	return r.Start.Day()
+}
+
+// nth returns the date of the k-th repetition counted from the start date. For monthly and
+// yearly schedules the day is clamped to the end of shorter months (e.g. day 31 in April).
+func (r Recurring) nth(k int) time.Time {
+	start := dateOnly(r.Start)
+	switch r.Frequency {
+	case Daily:
+		return start.AddDate(0, 0, k*r.Interval)
+	case Weekly:
+		return start.AddDate(0, 0, 7*k*r.Interval)
+	case Monthly:
+		return clampedDate(start.Year(), start.Month()+time.Month(k*r.Interval), r.day())
+	default: // Yearly
+		return clampedDate(start.Year()+k*r.Interval, start.Month(), start.Day())
+	}
+}
+
+// Occurrences returns the scheduled dates between from and to (both inclusive).
+func (r Recurring) Occurrences(from, to time.Time) []time.Time {
+	from, to = dateOnly(from), dateOnly(to)
+	start := dateOnly(r.Start)
+	if !r.End.IsZero() && dateOnly(r.End).Before(to) {
+		to = dateOnly(r.End)
+	}
+
+	var dates []time.Time
+	for k := 0; ; k++ {
+		d := r.nth(k)
+		if d.After(to) {
+			break
+		}
+		// A monthly day before the start day falls before the start in the first month
+		if d.Before(start) || d.Before(from) {
+			continue
+		}
+		dates = append(dates, d)
+	}
+	return dates
+}
+
+// pending returns the occurrences between from and to that haven't been booked by a run yet.
+func (r Recurring) pending(from, to time.Time) []time.Time {
+	if !r.LastRun.IsZero() && !dateOnly(from).After(dateOnly(r.LastRun)) {
+		from = dateOnly(r.LastRun).AddDate(0, 0, 1)
+	}
+	return r.Occurrences(from, to)
+}
+
+// Transaction is the transaction the template books on the given date.
+func (r Recurring) Transaction(date time.Time) *Transaction {
+	return &Transaction{
+		Date:        dateOnly(date),
+		Description: r.Description,
+		Amount:      r.Amount,
+		Category:    r.Category,
+		Account:     r.Account,
+		Currency:    r.Currency,
+	}
+}
+
+// CreateRecurring stores a new template. Its category is created if it doesn't exist yet.
+func CreateRecurring(db *sql.DB, r *Recurring) error {
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	var err error
+	if r.CategoryID, r.Category, err = categoryIDFor(db, r.Category); err != nil {
+		return err
+	}
+
+	res, err := db.Exec(`
+        INSERT INTO recurring (description, amount, category_id, account, currency, frequency, interval, day, start_date, end_date)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+    `, r.Description, r.Amount, r.CategoryID, nullIfEmpty(r.Account), nullIfEmpty(r.Currency),
+		r.Frequency, r.Interval, nullIfZero(int64(r.Day)), r.Start.Format("2006-01-02"), nullIfZeroDate(r.End))
+	if err != nil {
+		return fmt.Errorf("failed to insert recurring transaction: %w", err)
+	}
+	r.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetRecurring retrieves one template by ID.
+func GetRecurring(db *sql.DB, id int64) (*Recurring, error) {
+	return scanRecurring(db.QueryRow(`SELECT `+recurringColumns+` FROM `+recurringSource+` WHERE r.id = ?`, id))
+}
+
+// ListRecurring returns all templates in the order they were created.
+func ListRecurring(db *sql.DB) ([]Recurring, error) {
+	rows, err := db.Query(`SELECT ` + recurringColumns + ` FROM ` + recurringSource + ` ORDER BY r.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Recurring
+	for rows.Next() {
+		r, err := scanRecurring(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *r)
+	}
+	return list, rows.Err()
+}
+
+// DeleteRecurring removes a template. Transactions it already booked stay.
+func DeleteRecurring(db *sql.DB, id int64) error {
+	res, err := db.Exec(`DELETE FROM recurring WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("recurring transaction %d not found", id)
+	}
+	return nil
+}
+
+// UpcomingOccurrences returns the occurrences between from and to that no run has booked yet,
+// ordered by date.
+func UpcomingOccurrences(db *sql.DB, from, to time.Time) ([]Occurrence, error) {
+	templates, err := ListRecurring(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Occurrence
+	for _, r := range templates {
+		for _, d := range r.pending(from, to) {
+			list = append(list, Occurrence{Recurring: r, Date: d})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+	return list, nil
+}
+
+// RunRecurring books every occurrence due up to and including today. Occurrences that
+// already exist as transactions (see TransactionExists) are skipped, so running twice, or
+// after entering one by hand, doesn't create duplicates.
+func RunRecurring(db *sql.DB, today time.Time) (*RecurringRun, error) {
+	templates, err := ListRecurring(db)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &RecurringRun{}
+	for _, r := range templates {
+		for _, d := range r.pending(r.Start, today) {
+			tr := r.Transaction(d)
+			exists, err := TransactionExists(db, tr)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				run.Skipped++
+				continue
+			}
+			if err := CreateTransaction(db, tr); err != nil {
+				return nil, fmt.Errorf("recurring transaction %d: %w", r.ID, err)
+			}
+			run.Created = append(run.Created, *tr)
+		}
+
+		if _, err := db.Exec(`UPDATE recurring SET last_run = ? WHERE id = ?`, dateOnly(today).Format("2006-01-02"), r.ID); err != nil {
+			return nil, err
+		}
+	}
+	return run, nil
+}
+
+// recurringSource and recurringColumns read templates with their category path.
+const recurringSource = `recurring r LEFT JOIN category_paths cp ON cp.id = r.category_id`
+const recurringColumns = `r.id, r.description, r.amount, COALESCE(cp.path, 'Uncategorized'), COALESCE(r.category_id, 0),
+	COALESCE(r.account, ''), COALESCE(r.currency, ''), r.frequency, r.interval, COALESCE(r.day, 0),
+	r.start_date, COALESCE(r.end_date, ''), COALESCE(r.last_run, '')`
+
+func scanRecurring(row rowScanner) (*Recurring, error) {
+	var r Recurring
+	var start, end, lastRun string
+	err := row.Scan(&r.ID, &r.Description, &r.Amount, &r.Category, &r.CategoryID,
+		&r.Account, &r.Currency, &r.Frequency, &r.Interval, &r.Day, &start, &end, &lastRun)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Start, err = parseStoredDate(start); err != nil {
+		return nil, err
+	}
+	if r.End, err = parseStoredDate(end); err != nil {
+		return nil, err
+	}
+	if r.LastRun, err = parseStoredDate(lastRun); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// parseStoredDate reads an optional date column; the driver may append a time of day.
+func parseStoredDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", s[:min(len(s), 10)])
+}
+
+// clampedDate is the given day of a month, or the month's last day if it is shorter.
+// The month may overflow into later years.
+func clampedDate(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, last)-1)
+}
+
+// dateOnly drops the time of day, so dates compare as calendar days.
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// nullIfZeroDate stores an unset date as NULL.
+func nullIfZeroDate(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
@@ -3,49 +3,71 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 )
 
 // CategoryTotal holds the sum of amounts for a specific category
 type CategoryTotal struct {
 	Category string
-	Amount   float64
+	Amount   Money
 }
 
 // GetMonthlyReport returns the category breakdown, total income, and total expense for a given month/year.
-func GetMonthlyReport(db *sql.DB, year int, month int) ([]CategoryTotal, float64, float64, error) {
+// The filter can narrow the report down, e.g. to a single account. Amounts are converted into the
+// converter's base currency using the rate of each transaction date; a nil converter sums them as-is.
+// Split transactions are broken down into their split lines.
+func GetMonthlyReport(db *sql.DB, year int, month int, f TransactionFilter, conv *Converter) ([]CategoryTotal, Money, Money, error) {
 	// SQLite stores dates as strings "YYYY-MM-DD", so we filter by the "YYYY-MM" prefix
 	dateFilter := fmt.Sprintf("%04d-%02d", year, month)
+	cond, args := f.where("transaction_id")
 
+	// Rates change daily, so amounts are summed per category, currency and day before converting
 	query := `
-		SELECT category, SUM(amount)
-		FROM transactions
+		SELECT category, COALESCE(currency, ''), date, SUM(amount)
+		FROM ledger_lines
 		WHERE strftime('%Y-%m', date) = ?
-		GROUP BY category
-		ORDER BY SUM(amount) ASC;
+		AND ` + cond + `
+		GROUP BY category, 2, date;
 	`
 
-	rows, err := db.Query(query, dateFilter)
+	rows, err := db.Query(query, append([]interface{}{dateFilter}, args...)...)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	sums, err := scanDailySums(rows)
 	if err != nil {
 		return nil, 0, 0, err
 	}
-	defer rows.Close()
-
-	var breakdown []CategoryTotal
-	var totalIncome, totalExpense float64
 
-	for rows.Next() {
-		var ct CategoryTotal
-		if err := rows.Scan(&ct.Category, &ct.Amount); err != nil {
+	totals := map[string]Money{}
+	for _, s := range sums {
+		converted, err := conv.convertDaily(s, "")
+		if err != nil {
 			return nil, 0, 0, err
 		}
-		breakdown = append(breakdown, ct)
+		totals[s.Key] += converted
+	}
 
-		if ct.Amount > 0 {
-			totalIncome += ct.Amount
+	var breakdown []CategoryTotal
+	var totalIncome, totalExpense Money
+
+	for category, amount := range totals {
+		breakdown = append(breakdown, CategoryTotal{Category: category, Amount: amount})
+
+		if amount > 0 {
+			totalIncome += amount
 		} else {
-			totalExpense += ct.Amount // This will be negative
+			totalExpense += amount // This will be negative
 		}
 	}
 
+	// Biggest expenses first, like ORDER BY SUM(amount) ASC
+	sort.Slice(breakdown, func(i, j int) bool {
+		if breakdown[i].Amount != breakdown[j].Amount {
+			return breakdown[i].Amount < breakdown[j].Amount
+		}
+		return breakdown[i].Category < breakdown[j].Category
+	})
+
 	return breakdown, totalIncome, totalExpense, nil
 }
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RuleScope selects the stored transactions RuleChanges looks at.
+type RuleScope struct {
+	Since             time.Time // only transactions booked on or after this date; zero means all
+	OnlyUncategorized bool      // leave transactions that have a category alone
+}
+
+// RuleChange is what the rules would change on one stored transaction.
+type RuleChange struct {
+	Before Transaction
+	After  Transaction
+	Tags   []string // tags the transaction doesn't carry yet
+	Rules  []int64  // IDs of the rules that matched
+	Link   bool     // a transfer rule matched and the transaction isn't linked yet
+}
+
+// RuleChanges runs the rules over the stored transactions in scope, as if they were new,
+// and returns those that would change. Unlike for new transactions, any rule may replace
+// the category: applying the rules again is a request to recategorize. Legs of a linked
+// transfer are left alone.
+func RuleChanges(db *sql.DB, rules *RuleSet, scope RuleScope) ([]RuleChange, error) {
+	txs, err := FindTransactions(db, TransactionFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []RuleChange
+	for _, t := range txs {
+		if t.TransferID != 0 || (!scope.Since.IsZero() && t.Date.Before(scope.Since)) {
+			continue
+		}
+		if scope.OnlyUncategorized && t.Category != "Uncategorized" {
+			continue
+		}
+
+		after := t
+		result := rules.apply(&after, !scope.OnlyUncategorized)
+		if len(result.Rules) == 0 {
+			continue
+		}
+		after.Category = NormalizeCategory(after.Category)
+
+		c := RuleChange{Before: t, After: after, Rules: result.Rules, Link: result.Transfer}
+		if len(result.Tags) > 0 {
+			have, err := GetTags(db, t.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, tag := range result.Tags {
+				if !containsString(have, tag) {
+					c.Tags = append(c.Tags, tag)
+				}
+			}
+		}
+		if c.Changed() {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// Changed reports whether the change does anything.
+func (c *RuleChange) Changed() bool {
+	return c.Before.Category != c.After.Category || c.Before.Description != c.After.Description ||
+		c.Before.Payee != c.After.Payee || len(c.Tags) > 0 || c.Link
+}
+
+// ApplyRuleChanges stores the changes found by RuleChanges, all or none. It returns how
+// many transfers were linked; a transfer rule whose other leg isn't booked only sets the
+// category.
+func ApplyRuleChanges(db *sql.DB, changes []RuleChange) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	linked := map[int64]bool{} // both legs of the transfers linked so far
+	for i := range changes {
+		t := &changes[i].After
+		if t.CategoryID, t.Category, err = categoryIDFor(tx, t.Category); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE transactions SET description = ?, payee = ?, category_id = ? WHERE id = ?`,
+			t.Description, nullIfEmpty(t.Payee), t.CategoryID, t.ID); err != nil {
+			return 0, fmt.Errorf("failed to update transaction %d: %w", t.ID, err)
+		}
+		if err := addTags(tx, t.ID, changes[i].Tags...); err != nil {
+			return 0, err
+		}
+		if changes[i].Link && !linked[t.ID] {
+			other, err := linkOtherLeg(tx, t)
+			if err != nil {
+				return 0, fmt.Errorf("failed to link the transfer of transaction %d: %w", t.ID, err)
+			}
+			if other != 0 {
+				linked[t.ID], linked[other] = true, true
+			}
+		}
+	}
+	return len(linked) / 2, tx.Commit()
+}
@@ -0,0 +1,276 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// RuleSet is a list of rules compiled for applying them to many transactions: every
+// pattern is compiled once, and patterns that are plain words, like "(?i)netflix" or
+// "Lidl|Aldi", are all looked up in one pass over the description instead of one
+// regex per rule.
+type RuleSet struct {
+	rules    []CategoryRule   // the enabled rules, in order
+	patterns []*regexp.Regexp // compiled pattern of each rule; nil if it is empty
+	literal  []bool           // whether the rule's pattern is found by exact or folded
+	exact    *literalMatcher  // literal patterns, case-sensitive
+	folded   *literalMatcher  // literal patterns with (?i), on case-folded text
+}
+
+// CompileRules compiles the enabled rules, in the order given (ListRules' order). It
+// reports every rule whose pattern doesn't compile.
+func CompileRules(rules []CategoryRule) (*RuleSet, error) {
+	s := &RuleSet{exact: newLiteralMatcher(), folded: newLiteralMatcher()}
+	var errs []error
+	for _, r := range rules {
+		if r.Disabled {
+			continue
+		}
+		var re *regexp.Regexp
+		if r.Pattern != "" {
+			var err error
+			if re, err = regexp.Compile(r.Pattern); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d has an invalid pattern %q (fix it with 'rules edit %d --pattern' or remove it): %w",
+					r.ID, r.Pattern, r.ID, err))
+				continue
+			}
+		}
+
+		i := len(s.rules)
+		s.rules = append(s.rules, r)
+		s.patterns = append(s.patterns, re)
+		words, fold := literalPattern(r.Pattern)
+		s.literal = append(s.literal, words != nil)
+		for _, w := range words {
+			if fold {
+				s.folded.add(foldString(w), i)
+			} else {
+				s.exact.add(w, i)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	s.exact.build()
+	s.folded.build()
+	return s, nil
+}
+
+// Rules returns the rules of the set, in the order they are applied.
+func (s *RuleSet) Rules() []CategoryRule {
+	if s == nil {
+		return nil
+	}
+	return s.rules
+}
+
+// Apply applies the rules to a transaction about to be stored. Every rule whose conditions
+// hold applies its actions, but the first rule to set the description, payee or category
+// wins; tags add up. Conditions look at the transaction as it came, not as earlier rules
+// changed it.
+//
+// A rule sets the category only of an uncategorized transaction, unless its IfCategory
+// condition names the category to replace: a category given by the file or the user wins
+// over a rule that merely matches the description. A nil RuleSet has no rules.
+func (s *RuleSet) Apply(t *Transaction) RuleResult {
+	return s.apply(t, false)
+}
+
+// apply is Apply; with override, any rule may replace the category.
+func (s *RuleSet) apply(t *Transaction, override bool) RuleResult {
+	var result RuleResult
+	if s == nil || len(s.rules) == 0 {
+		return result
+	}
+	orig := *t
+	uncategorized := override || orig.Category == "" || NormalizeCategory(orig.Category) == "Uncategorized"
+	var setDescription, setPayee, setCategory bool
+	var found []bool // literal patterns found in the description; looked up on first need
+
+	for i := range s.rules {
+		r := &s.rules[i]
+		if !r.matchesConditions(&orig) {
+			continue
+		}
+		switch {
+		case s.patterns[i] == nil:
+		case s.literal[i]:
+			if found == nil {
+				found = make([]bool, len(s.rules))
+				s.exact.find(orig.Description, found)
+				s.folded.find(foldString(orig.Description), found)
+			}
+			if !found[i] {
+				continue
+			}
+		case !s.patterns[i].MatchString(orig.Description):
+			continue
+		}
+		result.Rules = append(result.Rules, r.ID)
+
+		if r.Description != "" && !setDescription {
+			t.Description, setDescription = expandDescription(s.patterns[i], r.Description, orig.Description), true
+		}
+		if r.Payee != "" && !setPayee {
+			t.Payee, setPayee = r.Payee, true
+		}
+
+		category := r.Category
+		if category == "" && r.Transfer {
+			category = TransferCategory
+		}
+		if category != "" && !setCategory && (uncategorized || r.IfCategory != "") {
+			t.Category, setCategory = category, true
+		}
+
+		for _, tag := range r.Tags {
+			if !containsString(result.Tags, tag) {
+				result.Tags = append(result.Tags, tag)
+			}
+		}
+		result.Transfer = result.Transfer || r.Transfer
+	}
+	return result
+}
+
+// expandDescription builds a rule's new description, filling in the groups its pattern
+// captured from the old one.
+func expandDescription(re *regexp.Regexp, template, old string) string {
+	if re == nil {
+		return template
+	}
+	match := re.FindStringSubmatchIndex(old)
+	if match == nil {
+		return template
+	}
+	return string(re.ExpandString(nil, template, old, match))
+}
+
+// literalPattern returns the words a pattern consists of, if it matches nothing but one
+// of them anywhere in the text, and whether they are matched regardless of case. Other
+// patterns (anchors, classes, repetition, groups) return nil.
+func literalPattern(pattern string) (words []string, fold bool) {
+	if pattern == "" {
+		return nil, false
+	}
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	re = re.Simplify()
+
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpAlternate {
+		subs = re.Sub
+	}
+	fold = subs[0].Flags&syntax.FoldCase != 0
+	for _, sub := range subs {
+		if sub.Op != syntax.OpLiteral || (sub.Flags&syntax.FoldCase != 0) != fold {
+			return nil, false
+		}
+		words = append(words, string(sub.Rune))
+	}
+	return words, fold
+}
+
+// foldString maps every letter to one representative of the letters it equals
+// regardless of case, as (?i) does: "K", "k" and the Kelvin sign all become "K".
+func foldString(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+func foldRune(r rune) rune {
+	if r < 0x80 {
+		if 'a' <= r && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
+
+// literalMatcher finds many words in a text at once (Aho-Corasick): a trie of the words
+// whose nodes also point to the longest suffix that is a prefix of another word, so the
+// text is read once, whatever the number of words.
+type literalMatcher struct {
+	next [][256]int32 // next[node][byte]; after build, complete transitions
+	fail []int32
+	out  [][]int // rules whose word ends at the node, including via fail
+}
+
+func newLiteralMatcher() *literalMatcher {
+	m := &literalMatcher{}
+	m.node()
+	return m
+}
+
+func (m *literalMatcher) node() int32 {
+	m.next = append(m.next, [256]int32{})
+	m.fail = append(m.fail, 0)
+	m.out = append(m.out, nil)
+	return int32(len(m.next) - 1)
+}
+
+// add records that rule i is found where word is.
+func (m *literalMatcher) add(word string, i int) {
+	n := int32(0)
+	for j := 0; j < len(word); j++ {
+		c := word[j]
+		if m.next[n][c] == 0 {
+			next := m.node()
+			m.next[n][c] = next
+		}
+		n = m.next[n][c]
+	}
+	m.out[n] = append(m.out[n], i)
+}
+
+// build links every node to its fail node and fills in the missing transitions, so find
+// takes exactly one step per byte.
+func (m *literalMatcher) build() {
+	var queue []int32
+	for c := 0; c < 256; c++ {
+		if n := m.next[0][c]; n != 0 {
+			queue = append(queue, n)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		m.out[n] = append(m.out[n], m.out[m.fail[n]]...)
+		for c := 0; c < 256; c++ {
+			child := m.next[n][c]
+			if child == 0 {
+				m.next[n][c] = m.next[m.fail[n]][c]
+				continue
+			}
+			m.fail[child] = m.next[m.fail[n]][c]
+			queue = append(queue, child)
+		}
+	}
+}
+
+// find marks the rules whose word occurs in text.
+func (m *literalMatcher) find(text string, found []bool) {
+	if len(m.next) == 1 {
+		return
+	}
+	n := int32(0)
+	for j := 0; j < len(text); j++ {
+		n = m.next[n][text[j]]
+		for _, i := range m.out[n] {
+			found[i] = true
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RuleSuggestion is a rule proposed for a merchant many uncategorized transactions go to.
+type RuleSuggestion struct {
+	Merchant string   // the normalized description the transactions share, e.g. "starbucks"
+	Pattern  string   // a pattern matching all of them, e.g. "(?i)starbucks"
+	Count    int      // uncategorized transactions the pattern catches
+	Examples []string // some of their descriptions, as stored
+	Category string   // the category the classifier predicts for most of them, if any
+}
+
+// SuggestRules groups the uncategorized transactions by merchant, the description as
+// NormalizeDescription leaves it ("STARBUCKS 054" and "Starbucks 112" are both
+// "starbucks"), and proposes a rule for every merchant with at least minCount of them,
+// those catching the most first. Transactions the current rules already categorize, and
+// transfers, are left out. The classifier, if not nil, proposes the categories.
+func SuggestRules(db *sql.DB, rules *RuleSet, c *Classifier, minCount int) ([]RuleSuggestion, error) {
+	txs, err := FindTransactions(db, TransactionFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var open []Transaction // uncategorized and not caught by a rule
+	clusters := map[string][]Transaction{}
+	for _, t := range txs {
+		if t.Category != "Uncategorized" || t.TransferID != 0 {
+			continue
+		}
+		after := t
+		rules.apply(&after, false)
+		if NormalizeCategory(after.Category) != "Uncategorized" {
+			continue
+		}
+		open = append(open, t)
+		if merchant := NormalizeDescription(t.Description); merchant != "" {
+			clusters[merchant] = append(clusters[merchant], t)
+		}
+	}
+
+	var list []RuleSuggestion
+	for merchant, members := range clusters {
+		if len(members) < minCount {
+			continue
+		}
+		s := RuleSuggestion{Merchant: merchant, Pattern: merchantPattern(merchant, members)}
+		re := regexp.MustCompile(s.Pattern)
+		for _, t := range open {
+			if re.MatchString(t.Description) {
+				s.Count++
+			}
+		}
+
+		votes := map[string]int{}
+		for _, t := range members {
+			if len(s.Examples) < 3 && !containsString(s.Examples, t.Description) {
+				s.Examples = append(s.Examples, t.Description)
+			}
+			if p := c.Predict(t.Description, t.Amount); p.Category != "" {
+				votes[p.Category]++
+			}
+		}
+		for category, n := range votes {
+			if n > votes[s.Category] || (n == votes[s.Category] && category < s.Category) {
+				s.Category = category
+			}
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Merchant < list[j].Merchant
+	})
+	return list, nil
+}
+
+// merchantPattern returns a case-insensitive pattern for a merchant's words: the words as
+// a phrase if every description contains them so, which keeps it a plain word that
+// RuleSet finds fast, else the words in order with anything in between.
+func merchantPattern(merchant string, members []Transaction) string {
+	words := strings.Fields(merchant)
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	for _, t := range members {
+		if !strings.Contains(strings.ToLower(t.Description), merchant) {
+			return "(?i)" + strings.Join(words, ".*")
+		}
+	}
+	return "(?i)" + strings.Join(words, " ")
+}
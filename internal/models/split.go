@@ -0,0 +1,160 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Split assigns part of a transaction's amount to its own category.
+// Whatever the splits of a transaction don't cover stays in the transaction's category.
+type Split struct {
+	ID            int64
+	TransactionID int64
+	Amount        Money  // same sign as the transaction
+	Category      string // full path
+	CategoryID    int64
+	Memo          string
+}
+
+// SplitLine is one line of a transaction as reports see it: a split or the uncovered remainder.
+type SplitLine struct {
+	Split
+	Remainder bool
+}
+
+// AddSplit adds a split line to a transaction. The amount takes the transaction's sign, so a
+// receipt can be split with positive numbers, and the splits may not exceed the transaction.
+func AddSplit(db *sql.DB, s *Split) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addSplit(tx, s); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func addSplit(db querier, s *Split) error {
+	t, err := getTransaction(db, s.TransactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("transaction %d not found", s.TransactionID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.Amount == 0 {
+		return fmt.Errorf("split amount must not be zero")
+	}
+	if (s.Amount < 0) != (t.Amount < 0) {
+		s.Amount = -s.Amount
+	}
+
+	var covered Money
+	if err := db.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM transaction_splits WHERE transaction_id = ?`, t.ID).Scan(&covered); err != nil {
+		return err
+	}
+	if remaining := t.Amount - covered; s.Amount.Abs() > remaining.Abs() || (remaining < 0) != (t.Amount < 0) {
+		return fmt.Errorf("split of %s exceeds the %s left on transaction %d", s.Amount.Abs(), remaining.Abs(), t.ID)
+	}
+
+	if s.CategoryID, s.Category, err = categoryIDFor(db, s.Category); err != nil {
+		return err
+	}
+
+	res, err := db.Exec(`INSERT INTO transaction_splits (transaction_id, amount, category_id, memo) VALUES (?, ?, ?, ?)`,
+		s.TransactionID, s.Amount, s.CategoryID, s.Memo)
+	if err != nil {
+		return fmt.Errorf("failed to insert split: %w", err)
+	}
+	s.ID, err = res.LastInsertId()
+	return err
+}
+
+// ListSplits returns the split lines of a transaction in the order they were added.
+func ListSplits(db *sql.DB, transactionID int64) ([]Split, error) {
+	rows, err := db.Query(`
+		SELECT s.id, s.transaction_id, s.amount, COALESCE(cp.path, 'Uncategorized'), COALESCE(s.category_id, 0), s.memo
+		FROM transaction_splits s LEFT JOIN category_paths cp ON cp.id = s.category_id
+		WHERE s.transaction_id = ?
+		ORDER BY s.id`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var splits []Split
+	for rows.Next() {
+		var s Split
+		if err := rows.Scan(&s.ID, &s.TransactionID, &s.Amount, &s.Category, &s.CategoryID, &s.Memo); err != nil {
+			return nil, err
+		}
+		splits = append(splits, s)
+	}
+	return splits, rows.Err()
+}
+
+// GetSplitLines returns how a transaction is broken down in reports: its splits followed
+// by the remainder in the transaction's own category, if anything is left.
+func GetSplitLines(db *sql.DB, t *Transaction) ([]SplitLine, error) {
+	splits, err := ListSplits(db, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []SplitLine
+	remainder := t.Amount
+	for _, s := range splits {
+		lines = append(lines, SplitLine{Split: s})
+		remainder -= s.Amount
+	}
+	if remainder != 0 || len(splits) == 0 {
+		lines = append(lines, SplitLine{
+			Split:     Split{TransactionID: t.ID, Amount: remainder, Category: t.Category, CategoryID: t.CategoryID},
+			Remainder: true,
+		})
+	}
+	return lines, nil
+}
+
+// DeleteSplit removes one split line; its amount falls back to the transaction's category.
+func DeleteSplit(db *sql.DB, id int64) error {
+	res, err := db.Exec(`DELETE FROM transaction_splits WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("split %d not found", id)
+	}
+	return nil
+}
+
+// ClearSplits removes all split lines of a transaction.
+func ClearSplits(db *sql.DB, transactionID int64) error {
+	_, err := db.Exec(`DELETE FROM transaction_splits WHERE transaction_id = ?`, transactionID)
+	return err
+}
+
+// CountSplits returns how many split lines each split transaction has.
+func CountSplits(db *sql.DB) (map[int64]int, error) {
+	rows, err := db.Query(`SELECT transaction_id, COUNT(*) FROM transaction_splits GROUP BY transaction_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int64]int{}
+	for rows.Next() {
+		var id int64
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		counts[id] = n
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TagCount is a tag together with the number of transactions carrying it.
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// NormalizeTag turns "#Vacation-2026" into "vacation-2026". Tags are single words;
+// the leading '#' is only how they are written and displayed.
+func NormalizeTag(s string) (string, error) {
+	tag := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	if tag == "" {
+		return "", fmt.Errorf("tag must not be empty")
+	}
+	if strings.ContainsAny(tag, " \t#,") {
+		return "", fmt.Errorf("invalid tag %q (tags are single words like vacation-2026)", s)
+	}
+	return tag, nil
+}
+
+// AddTags attaches tags to a transaction, creating tags that don't exist yet.
+// Tags the transaction already has are left alone.
+func AddTags(db *sql.DB, transactionID int64, tags ...string) error {
+	if _, err := GetTransaction(db, transactionID); errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("transaction %d not found", transactionID)
+	} else if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addTags(tx, transactionID, tags...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func addTags(q querier, transactionID int64, tags ...string) error {
+	for _, raw := range tags {
+		tag, err := NormalizeTag(raw)
+		if err != nil {
+			return err
+		}
+		if _, err := q.Exec(`INSERT OR IGNORE INTO tags (name) VALUES (?)`, tag); err != nil {
+			return fmt.Errorf("failed to create tag: %w", err)
+		}
+		if _, err := q.Exec(`
+			INSERT OR IGNORE INTO transaction_tags (transaction_id, tag_id)
+			SELECT ?, id FROM tags WHERE name = ?`, transactionID, tag); err != nil {
+			return fmt.Errorf("failed to tag transaction: %w", err)
+		}
+	}
+	return nil
+}
+
+// RemoveTags detaches tags from a transaction. Tags no transaction uses any more are deleted,
+// unless a budget still targets them.
+func RemoveTags(db *sql.DB, transactionID int64, tags ...string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, raw := range tags {
+		tag, err := NormalizeTag(raw)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			DELETE FROM transaction_tags
+			WHERE transaction_id = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)`, transactionID, tag); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM tags
+		WHERE id NOT IN (SELECT tag_id FROM transaction_tags)
+		AND name NOT IN (SELECT tag FROM budgets WHERE tag IS NOT NULL)`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTags returns the tags of a transaction in alphabetical order.
+func GetTags(db *sql.DB, transactionID int64) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT g.name FROM tags g
+		JOIN transaction_tags tt ON tt.tag_id = g.id
+		WHERE tt.transaction_id = ?
+		ORDER BY g.name`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// ListTags returns every tag with the number of transactions carrying it.
+func ListTags(db *sql.DB) ([]TagCount, error) {
+	rows, err := db.Query(`
+		SELECT g.name, COUNT(tt.transaction_id) FROM tags g
+		LEFT JOIN transaction_tags tt ON tt.tag_id = g.id
+		GROUP BY g.id
+		ORDER BY g.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []TagCount
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.Name, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// taggedCondition is the SQL condition selecting rows whose transaction carries a tag.
+func taggedCondition(idColumn string) string {
+	return idColumn + ` IN (
+		SELECT tt.transaction_id FROM transaction_tags tt
+		JOIN tags g ON g.id = tt.tag_id
+		WHERE g.name = ?)`
+}
@@ -2,44 +2,80 @@ package models
 
 import (
 	"database/sql"
+	"fmt"
 	"strings"
 	"time"
-
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
 type Transaction struct {
-	ID          int64
-	Date        time.Time
-	Description string
-	Amount      float64
-	Category    string
-	CreatedAt   time.Time
+	ID              int64
+	Date            time.Time
+	Description     string
+	Payee           string // who was paid or paid us, if a rule named it
+	Amount          Money
+	Category        string // full path, e.g. "Food:Groceries"
+	CategoryID      int64
+	Account         string
+	Currency        string // ISO code; empty means the base currency
+	TransferID      int64  // set on both legs of a transfer between own accounts
+	ExternalID      string // the bank's id of an imported transaction (OFX FITID), if any
+	ExternalAccount string // the bank account of the statement the ExternalID is unique in, if the file names it
+	BatchID         int64  // the import batch it came from; 0 if entered by hand
+	SourceLine      int    // line of the imported file, where the format has lines
+	CreatedAt       time.Time
+}
+
+// TransactionFilter narrows down which transactions a query returns.
+// Zero values mean "no restriction".
+type TransactionFilter struct {
+	Account string // exact account name
+	Query   string // keyword matched against description, payee or category
+	Tag     string // normalized tag the transaction must carry
+	Batch   int64  // import batch the transaction came from
 }
 
-func NormalizeCategory(c string) string {
-	if c == "" {
-		return "Uncategorized"
+// where builds the SQL condition (without the WHERE keyword) and its arguments.
+// idColumn names the column holding the transaction ID in the queried table or view.
+func (f TransactionFilter) where(idColumn string) (string, []interface{}) {
+	conds := []string{"1 = 1"}
+	var args []interface{}
+
+	if f.Account != "" {
+		conds = append(conds, "account = ?")
+		args = append(args, f.Account)
+	}
+	if f.Query != "" {
+		// We use the LIKE operator for simple keyword matching
+		// The payee is looked up in the table, as not every queried view has it
+		conds = append(conds, "(description LIKE ? OR category LIKE ? OR "+idColumn+" IN (SELECT id FROM transactions WHERE payee LIKE ?))")
+		searchTerm := "%" + f.Query + "%"
+		args = append(args, searchTerm, searchTerm, searchTerm)
+	}
+	if f.Tag != "" {
+		conds = append(conds, taggedCondition(idColumn))
+		args = append(args, f.Tag)
+	}
+	if f.Batch != 0 {
+		conds = append(conds, idColumn+" IN (SELECT id FROM transactions WHERE import_batch_id = ?)")
+		args = append(args, f.Batch)
 	}
-	// Converts to Title Case (e.g., "groceries" -> "Groceries")
-	caser := cases.Title(language.English)
-	return caser.String(strings.TrimSpace(c))
+
+	return strings.Join(conds, " AND "), args
 }
 
-// CreateTransaction inserts a new transaction.
+// CreateTransaction inserts a new transaction. Its category is looked up by path
+// and created if it doesn't exist yet.
 func CreateTransaction(db *sql.DB, t *Transaction) error {
-	query := `
-        INSERT INTO transactions (date, description, amount, category)
-        VALUES (?, ?, ?, ?);
-    `
+	return insertTransaction(db, t)
+}
 
-	res, err := db.Exec(query,
-		t.Date.Format("2006-01-02"),
-		t.Description,
-		t.Amount,
-		t.Category,
-	)
+func insertTransaction(q querier, t *Transaction) error {
+	var err error
+	if t.CategoryID, t.Category, err = categoryIDFor(q, t.Category); err != nil {
+		return err
+	}
+
+	res, err := q.Exec(insertTransactionSQL, insertTransactionArgs(t)...)
 	if err != nil {
 		return err
 	}
@@ -48,47 +84,91 @@ func CreateTransaction(db *sql.DB, t *Transaction) error {
 	return err
 }
 
-// TransactionExists checks if a transaction with the same date, amount, and description already exists
+const insertTransactionSQL = `
+        INSERT INTO transactions (date, description, payee, amount, category_id, account, currency, external_id, external_account, import_batch_id, source_line)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+    `
+
+// insertTransactionArgs are the values for insertTransactionSQL; the category must be resolved.
+func insertTransactionArgs(t *Transaction) []interface{} {
+	return []interface{}{
+		t.Date.Format("2006-01-02"),
+		t.Description,
+		nullIfEmpty(t.Payee),
+		t.Amount,
+		t.CategoryID,
+		nullIfEmpty(t.Account),
+		nullIfEmpty(t.Currency),
+		nullIfEmpty(t.ExternalID),
+		nullIfEmpty(t.ExternalAccount),
+		nullIfZero(t.BatchID),
+		nullIfZero(int64(t.SourceLine)),
+	}
+}
+
+// TransactionExists checks if a transaction with the same date, amount, and description
+// already exists in the same account. Amounts are exact, so they are compared with '='.
+// A transaction with an ExternalID matches the one with the same id from the same bank
+// account instead; two purchases with equal details but different bank ids are both kept,
+// as are equal ids from statements of different bank accounts. Rows without an id (manual
+// entries, older imports) still match by their details, and rows stored without the
+// statement's account match by the id alone.
 func TransactionExists(db *sql.DB, t *Transaction) (bool, error) {
-	var count int
-	query := `
-		SELECT COUNT(*) FROM transactions 
-		WHERE date = ? 
-		AND description = ? 
-		AND ABS(amount - ?) < 0.001
-	`
-	err := db.QueryRow(query, t.Date.Format("2006-01-02"), t.Description, t.Amount).Scan(&count)
-	return count > 0, err
+	var exists bool
+	err := db.QueryRow(transactionExistsSQL, transactionExistsArgs(t)...).Scan(&exists)
+	return exists, err
 }
 
-// GetTransaction retrieves one by ID
-func GetTransaction(db *sql.DB, id int64) (*Transaction, error) {
-	query := `
-        SELECT id, date, description, amount, category, created_at
-        FROM transactions WHERE id = ?;
+// transactionExistsSQL is written so that both lookups can use an index: the bank's id by
+// idx_transactions_external_id, the details by idx_transactions_dedup. Transactions
+// without an account have a NULL account, which "IS" matches.
+const transactionExistsSQL = `
+        SELECT EXISTS (
+            SELECT 1 FROM transactions WHERE account IS ? AND external_id = ?
+            AND (external_account IS NULL OR ? IS NULL OR external_account = ?)
+        ) OR EXISTS (
+            SELECT 1 FROM transactions
+            WHERE account IS ? AND date = ? AND amount = ? AND description = ?
+            AND (? = '' OR external_id IS NULL)
+        )
     `
 
-	row := db.QueryRow(query, id)
+func transactionExistsArgs(t *Transaction) []interface{} {
+	account := nullIfEmpty(t.Account)
+	external := nullIfEmpty(t.ExternalAccount)
+	return []interface{}{account, t.ExternalID, external, external, account, t.Date.Format("2006-01-02"), t.Amount, t.Description, t.ExternalID}
+}
 
-	var t Transaction
-	var dateStr string
+// GetTransaction retrieves one by ID
+func GetTransaction(db *sql.DB, id int64) (*Transaction, error) {
+	return getTransaction(db, id)
+}
 
-	if err := row.Scan(&t.ID, &dateStr, &t.Description, &t.Amount, &t.Category, &t.CreatedAt); err != nil {
-		return nil, err
-	}
+func getTransaction(db querier, id int64) (*Transaction, error) {
+	query := `
+        SELECT ` + transactionColumns + `
+        FROM ` + transactionSource + ` WHERE id = ?;
+    `
 
-	t.Date, _ = time.Parse("2006-01-02", dateStr)
-	return &t, nil
+	return scanTransaction(db.QueryRow(query, id))
 }
 
-// ListTransactions retrieves all (optionally filtered later)
+// ListTransactions retrieves all transactions, newest first
 func ListTransactions(db *sql.DB) ([]Transaction, error) {
+	return FindTransactions(db, TransactionFilter{})
+}
+
+// FindTransactions retrieves the transactions matching the filter, newest first
+func FindTransactions(db *sql.DB, f TransactionFilter) ([]Transaction, error) {
+	cond, args := f.where("id")
 	query := `
-        SELECT id, date, description, amount, category, created_at
-        FROM transactions ORDER BY date DESC;
+        SELECT ` + transactionColumns + `
+        FROM ` + transactionSource + `
+        WHERE ` + cond + `
+        ORDER BY date DESC;
     `
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -97,69 +177,126 @@ func ListTransactions(db *sql.DB) ([]Transaction, error) {
 	var list []Transaction
 
 	for rows.Next() {
-		var t Transaction
-		var dateStr string
-
-		if err := rows.Scan(&t.ID, &dateStr, &t.Description, &t.Amount, &t.Category, &t.CreatedAt); err != nil {
+		t, err := scanTransaction(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		t.Date, _ = time.Parse("2006-01-02", dateStr)
-		list = append(list, t)
+		list = append(list, *t)
 	}
 
-	return list, nil
+	return list, rows.Err()
 }
 
-// UpdateTransaction (simple)
+// UpdateTransaction stores the changed fields of a transaction. The amount of a split
+// transaction may only change as far as its split lines still fit into it, with the same
+// sign; otherwise the lines would no longer add up and reports would be wrong.
 func UpdateTransaction(db *sql.DB, t *Transaction) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if t.CategoryID, t.Category, err = categoryIDFor(tx, t.Category); err != nil {
+		return err
+	}
+
+	var covered Money
+	if err := tx.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM transaction_splits WHERE transaction_id = ?`, t.ID).Scan(&covered); err != nil {
+		return err
+	}
+	if covered != 0 && (covered.Abs() > t.Amount.Abs() || (covered < 0) != (t.Amount < 0)) {
+		return fmt.Errorf("transaction %d has split lines of %s, which an amount of %s can't hold; change or clear the splits first",
+			t.ID, covered, t.Amount)
+	}
+
 	query := `
         UPDATE transactions
-        SET date = ?, description = ?, amount = ?, category = ?
+        SET date = ?, description = ?, payee = ?, amount = ?, category_id = ?, account = ?, currency = ?
         WHERE id = ?;
     `
 
-	_, err := db.Exec(query,
+	_, err = tx.Exec(query,
 		t.Date.Format("2006-01-02"),
 		t.Description,
+		nullIfEmpty(t.Payee),
 		t.Amount,
-		t.Category,
+		t.CategoryID,
+		nullIfEmpty(t.Account),
+		nullIfEmpty(t.Currency),
 		t.ID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// DeleteTransaction
+// DeleteTransaction removes a transaction together with its split lines and tags.
+// The other leg of a transfer stays as an ordinary transaction.
 func DeleteTransaction(db *sql.DB, id int64) error {
-	_, err := db.Exec(`DELETE FROM transactions WHERE id = ?`, id)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteTransaction(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteTransaction removes a transaction with its split lines and tags, and unlinks
+// the other leg if it is part of a transfer.
+func deleteTransaction(tx *sql.Tx, id int64) error {
+	if _, err := tx.Exec(`DELETE FROM transaction_splits WHERE transaction_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM transaction_tags WHERE transaction_id = ?`, id); err != nil {
+		return err
+	}
+	if err := unlinkTransfer(tx, id); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM transactions WHERE id = ?`, id)
 	return err
 }
 
-// SearchTransactions filters transactions where description or category matches the query.
+// SearchTransactions filters transactions where description, payee or category matches the query.
 func SearchTransactions(db *sql.DB, queryStr string) ([]Transaction, error) {
-	// We use the LIKE operator for simple keyword matching
-	sqlQuery := `
-        SELECT id, date, description, amount, category, created_at
-        FROM transactions 
-        WHERE description LIKE ? OR category LIKE ?
-        ORDER BY date DESC;
-    `
-	searchTerm := "%" + queryStr + "%"
-	rows, err := db.Query(sqlQuery, searchTerm, searchTerm)
-	if err != nil {
+	return FindTransactions(db, TransactionFilter{Query: queryStr})
+}
+
+// transactionSource is the transactions table with each category id resolved to its path.
+const transactionSource = `(
+        SELECT t.*, COALESCE(cp.path, 'Uncategorized') AS category
+        FROM transactions t LEFT JOIN category_paths cp ON cp.id = t.category_id)`
+
+// transactionColumns is the column list understood by scanTransaction.
+const transactionColumns = `id, date, description, COALESCE(payee, ''), amount, category, COALESCE(category_id, 0), COALESCE(account, ''), COALESCE(currency, ''), COALESCE(transfer_id, 0), COALESCE(external_id, ''), COALESCE(external_account, ''), COALESCE(import_batch_id, 0), COALESCE(source_line, 0), created_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row rowScanner) (*Transaction, error) {
+	var t Transaction
+	var dateStr string
+
+	if err := row.Scan(&t.ID, &dateStr, &t.Description, &t.Payee, &t.Amount, &t.Category, &t.CategoryID, &t.Account, &t.Currency, &t.TransferID, &t.ExternalID, &t.ExternalAccount, &t.BatchID, &t.SourceLine, &t.CreatedAt); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var list []Transaction
-	for rows.Next() {
-		var t Transaction
-		var dateStr string
-		if err := rows.Scan(&t.ID, &dateStr, &t.Description, &t.Amount, &t.Category, &t.CreatedAt); err != nil {
-			return nil, err
-		}
-		t.Date, _ = time.Parse("2006-01-02", dateStr)
-		list = append(list, t)
+	t.Date, _ = time.Parse("2006-01-02", dateStr)
+	return &t, nil
+}
+
+// nullIfEmpty stores empty optional strings as NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
 	}
-	return list, nil
+	return s
 }
@@ -0,0 +1,368 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TransferCategory is the category given to both legs of a transfer.
+const TransferCategory = "Transfer"
+
+// DefaultTransferWindow is how many days apart the two legs of a detected transfer may be booked.
+const DefaultTransferWindow = 3
+
+// Transfer describes money moved from one own account to another.
+type Transfer struct {
+	Date        time.Time
+	Description string
+	From        string // account names
+	To          string
+	Amount      Money // leaves From, in From's currency
+	ToAmount    Money // arrives in To, in To's currency; zero means the same as Amount
+}
+
+// TransferPair is a detected candidate: Out and In look like the two legs of one transfer.
+type TransferPair struct {
+	Out Transaction // the negative leg
+	In  Transaction // the positive leg
+}
+
+// CreateTransfer books both legs of a transfer and links them. It returns the two legs.
+func CreateTransfer(db *sql.DB, t Transfer) (*Transaction, *Transaction, error) {
+	if t.Amount <= 0 {
+		return nil, nil, fmt.Errorf("transfer amount must be positive")
+	}
+	from, err := ResolveOpenAccount(db, t.From)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := ResolveOpenAccount(db, t.To)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from == nil || to == nil {
+		return nil, nil, fmt.Errorf("a transfer needs both a source and a destination account")
+	}
+	if from.ID == to.ID {
+		return nil, nil, fmt.Errorf("cannot transfer from %s to itself", from.Name)
+	}
+	if t.ToAmount == 0 {
+		if from.Currency != to.Currency {
+			return nil, nil, fmt.Errorf("%s is in %s and %s in %s; give the amount that arrived",
+				from.Name, currencyLabel(from.Currency), to.Name, currencyLabel(to.Currency))
+		}
+		t.ToAmount = t.Amount
+	}
+	if t.Description == "" {
+		t.Description = fmt.Sprintf("Transfer %s -> %s", from.Name, to.Name)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	out := &Transaction{Date: t.Date, Description: t.Description, Amount: -t.Amount, Category: TransferCategory, Account: from.Name, Currency: from.Currency}
+	in := &Transaction{Date: t.Date, Description: t.Description, Amount: t.ToAmount, Category: TransferCategory, Account: to.Name, Currency: to.Currency}
+	for _, leg := range []*Transaction{out, in} {
+		if err := insertTransaction(tx, leg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	id, err := linkLegs(tx, out.ID, in.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	out.TransferID, in.TransferID = id, id
+	return out, in, tx.Commit()
+}
+
+// LinkTransfer marks two existing transactions as the legs of one transfer.
+// They must be in different accounts, have opposite signs and not be linked yet.
+func LinkTransfer(db *sql.DB, outID, inID int64) (int64, error) {
+	var legs [2]*Transaction
+	for i, id := range []int64{outID, inID} {
+		t, err := GetTransaction(db, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("transaction %d not found", id)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if t.TransferID != 0 {
+			return 0, fmt.Errorf("transaction %d is already part of a transfer", id)
+		}
+		legs[i] = t
+	}
+	if legs[0].Amount > 0 {
+		legs[0], legs[1] = legs[1], legs[0]
+	}
+	if legs[0].Amount >= 0 || legs[1].Amount <= 0 {
+		return 0, fmt.Errorf("a transfer needs one outgoing and one incoming transaction")
+	}
+	if legs[0].Account == "" || legs[1].Account == "" || legs[0].Account == legs[1].Account {
+		return 0, fmt.Errorf("the legs of a transfer must belong to two different accounts")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := linkLegs(tx, outID, inID)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// linkLegs records a new transfer and attaches both legs to it.
+func linkLegs(tx *sql.Tx, outID, inID int64) (int64, error) {
+	res, err := tx.Exec(`INSERT INTO transfers DEFAULT VALUES`)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`UPDATE transactions SET transfer_id = ? WHERE id IN (?, ?)`, id, outID, inID)
+	return id, err
+}
+
+// linkOtherLeg links a stored transaction with the other leg of its transfer: the closest
+// unlinked transaction of another account with the opposite amount in the same currency,
+// booked within DefaultTransferWindow days. It returns 0 if there is none (yet).
+func linkOtherLeg(tx *sql.Tx, t *Transaction) (int64, error) {
+	if t.Account == "" || t.Amount == 0 || t.TransferID != 0 {
+		return 0, nil
+	}
+
+	var other int64
+	date := t.Date.Format("2006-01-02")
+	err := tx.QueryRow(`
+        SELECT id FROM transactions
+        WHERE amount = ? AND transfer_id IS NULL AND id != ?
+          AND account IS NOT NULL AND account != ?
+          AND COALESCE(currency, '') = ?
+          AND date BETWEEN date(?, '-'||?||' days') AND date(?, '+'||?||' days')
+        ORDER BY abs(julianday(date) - julianday(?)), id
+        LIMIT 1
+    `, -t.Amount, t.ID, t.Account, t.Currency, date, DefaultTransferWindow, date, DefaultTransferWindow, date).Scan(&other)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	outID, inID := t.ID, other
+	if t.Amount > 0 {
+		outID, inID = other, t.ID
+	}
+	if t.TransferID, err = linkLegs(tx, outID, inID); err != nil {
+		return 0, err
+	}
+	return other, nil
+}
+
+// linkTransferLeg links a stored transaction with the other leg of a transfer it, or one of
+// its split lines, makes to the named account (any other account if the name is empty):
+// the closest unlinked transaction there of the opposite amount, or with a Transfer split
+// line of it, booked within DefaultTransferWindow days. amount is what the transfer moved
+// on t's side. It returns 0 if there is none (yet).
+func linkTransferLeg(tx *sql.Tx, t *Transaction, account string, amount Money) (int64, error) {
+	if t.Account == "" || amount == 0 || t.TransferID != 0 {
+		return 0, nil
+	}
+
+	var other int64
+	date := t.Date.Format("2006-01-02")
+	err := tx.QueryRow(`
+        SELECT t.id FROM transactions t
+        WHERE t.transfer_id IS NULL AND t.id != ?
+          AND t.account IS NOT NULL AND t.account != ? AND (? = '' OR t.account = ?)
+          AND COALESCE(t.currency, '') = ?
+          AND t.date BETWEEN date(?, '-'||?||' days') AND date(?, '+'||?||' days')
+          AND (t.amount = ? OR EXISTS (
+                SELECT 1 FROM transaction_splits s JOIN category_paths cp ON cp.id = s.category_id
+                WHERE s.transaction_id = t.id AND s.amount = ? AND cp.path = ?))
+        ORDER BY abs(julianday(t.date) - julianday(?)), t.id
+        LIMIT 1
+    `, t.ID, t.Account, account, account, t.Currency, date, DefaultTransferWindow, date, DefaultTransferWindow,
+		-amount, -amount, TransferCategory, date).Scan(&other)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	outID, inID := t.ID, other
+	if amount > 0 {
+		outID, inID = other, t.ID
+	}
+	if t.TransferID, err = linkLegs(tx, outID, inID); err != nil {
+		return 0, err
+	}
+	return other, nil
+}
+
+// UnlinkTransfer turns both legs of the transfer a transaction belongs to back into
+// ordinary transactions.
+func UnlinkTransfer(db *sql.DB, transactionID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := unlinkTransfer(tx, transactionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func unlinkTransfer(tx *sql.Tx, transactionID int64) error {
+	var transferID sql.NullInt64
+	err := tx.QueryRow(`SELECT transfer_id FROM transactions WHERE id = ?`, transactionID).Scan(&transferID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !transferID.Valid) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE transactions SET transfer_id = NULL WHERE transfer_id = ?`, transferID.Int64); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM transfers WHERE id = ?`, transferID.Int64)
+	return err
+}
+
+// ListTransfers returns the linked transfers, newest first.
+func ListTransfers(db *sql.DB) ([]TransferPair, error) {
+	legs, err := FindTransactions(db, TransactionFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	byTransfer := map[int64]*TransferPair{}
+	var order []int64
+	for _, t := range legs {
+		if t.TransferID == 0 {
+			continue
+		}
+		p, ok := byTransfer[t.TransferID]
+		if !ok {
+			p = &TransferPair{}
+			byTransfer[t.TransferID] = p
+			order = append(order, t.TransferID)
+		}
+		if t.Amount < 0 {
+			p.Out = t
+		} else {
+			p.In = t
+		}
+	}
+
+	pairs := make([]TransferPair, len(order))
+	for i, id := range order {
+		pairs[i] = *byTransfer[id]
+	}
+	return pairs, nil
+}
+
+// DetectTransfers finds unlinked transactions in two different accounts with opposite amounts
+// in the same currency, booked at most window days apart. Each transaction appears in at most
+// one pair; the closest dates win. If onlyIDs is not empty, every pair contains one of them.
+func DetectTransfers(db *sql.DB, window int, onlyIDs []int64) ([]TransferPair, error) {
+	// Dates are stored as YYYY-MM-DD, so comparing them as text keeps the amount index usable
+	query := `
+		SELECT o.id, i.id, ABS(julianday(o.date) - julianday(i.date)) AS days
+		FROM transactions o
+		JOIN transactions i
+		  ON i.amount = -o.amount
+		 AND i.date BETWEEN date(o.date, '-' || ? || ' days') AND date(o.date, '+' || ? || ' days')
+		 AND COALESCE(i.currency, '') = COALESCE(o.currency, '')
+		 AND i.account != o.account
+		WHERE o.amount < 0
+		  AND o.transfer_id IS NULL AND i.transfer_id IS NULL`
+	args := []interface{}{window, window}
+	if len(onlyIDs) > 0 {
+		// A pair without one of the ids is never chosen, so it need not be a candidate either
+		ids, err := json.Marshal(onlyIDs)
+		if err != nil {
+			return nil, err
+		}
+		query += `
+		  AND (o.id IN (SELECT value FROM json_each(?)) OR i.id IN (SELECT value FROM json_each(?)))`
+		args = append(args, string(ids), string(ids))
+	}
+	rows, err := db.Query(query+`
+		ORDER BY days, o.id, i.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	type candidate struct{ out, in int64 }
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		var days float64
+		if err := rows.Scan(&c.out, &c.in, &days); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	only := map[int64]bool{}
+	for _, id := range onlyIDs {
+		only[id] = true
+	}
+
+	used := map[int64]bool{}
+	var pairs []TransferPair
+	for _, c := range candidates {
+		if used[c.out] || used[c.in] {
+			continue
+		}
+		if len(only) > 0 && !only[c.out] && !only[c.in] {
+			continue
+		}
+		used[c.out], used[c.in] = true, true
+
+		out, err := GetTransaction(db, c.out)
+		if err != nil {
+			return nil, err
+		}
+		in, err := GetTransaction(db, c.in)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, TransferPair{Out: *out, In: *in})
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Out.Date.Before(pairs[j].Out.Date) })
+	return pairs, nil
+}
+
+// currencyLabel names an empty currency code for messages.
+func currencyLabel(code string) string {
+	if code == "" {
+		return "the base currency"
+	}
+	return code
+}
@@ -0,0 +1,161 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/importer"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ReviewImport lets the user go through the records of a file before they are stored:
+// accept or reject each of them and change their category. It changes the outcomes in
+// place and reports whether the user chose to import them (true) or cancelled.
+func ReviewImport(outcomes []*importer.Outcome, categories []string, dateLayout string) (bool, error) {
+	app := tview.NewApplication()
+	confirmed := false
+
+	table := tview.NewTable().
+		SetBorders(true).
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	for i, h := range []string{"STATUS", "DATE", "DESCRIPTION", "CATEGORY", "AMOUNT", "REASON"} {
+		table.SetCell(0, i, tview.NewTableCell(h).
+			SetTextColor(tcell.ColorYellow).
+			SetAlign(tview.AlignCenter).
+			SetSelectable(false))
+	}
+	summary := tview.NewTextView().SetTextColor(tcell.ColorGreen)
+
+	showRow := func(i int) {
+		o := outcomes[i]
+		row := i + 1
+
+		color := tcell.ColorWhite
+		switch o.Status {
+		case importer.StatusNew:
+			color = tcell.ColorGreen
+		case importer.StatusInvalid, importer.StatusRejected:
+			color = tcell.ColorRed
+		case importer.StatusDuplicate:
+			color = tcell.ColorGray
+		}
+		table.SetCell(row, 0, tview.NewTableCell(string(o.Status)).SetTextColor(color))
+
+		if t := o.Transaction; t != nil {
+			desc := t.Description
+			if len(desc) > 30 {
+				desc = desc[:27] + "..."
+			}
+			table.SetCell(row, 1, tview.NewTableCell(t.Date.Format(dateLayout)).SetAlign(tview.AlignCenter))
+			table.SetCell(row, 2, tview.NewTableCell(desc))
+			table.SetCell(row, 3, tview.NewTableCell(t.Category))
+			table.SetCell(row, 4, tview.NewTableCell(strings.TrimSpace(t.Amount.String()+" "+t.Currency)).
+				SetAlign(tview.AlignRight))
+		}
+		table.SetCell(row, 5, tview.NewTableCell(o.Reason))
+	}
+	showSummary := func() {
+		counts := map[importer.Status]int{}
+		for _, o := range outcomes {
+			counts[o.Status]++
+		}
+		summary.SetText(fmt.Sprintf("Review import: %d to import, %d duplicates, %d rejected, %d invalid",
+			counts[importer.StatusNew], counts[importer.StatusDuplicate], counts[importer.StatusRejected], counts[importer.StatusInvalid]))
+	}
+	for i := range outcomes {
+		showRow(i)
+	}
+	showSummary()
+
+	pages := tview.NewPages()
+
+	// The category editor is a small form shown over the table
+	editCategory := func(i int) {
+		t := outcomes[i].Transaction
+		field := tview.NewInputField().
+			SetLabel("Category: ").
+			SetText(t.Category).
+			SetFieldWidth(30)
+		field.SetAutocompleteFunc(func(text string) []string {
+			var matches []string
+			for _, c := range categories {
+				if text != "" && strings.HasPrefix(strings.ToLower(c), strings.ToLower(text)) {
+					matches = append(matches, c)
+				}
+			}
+			return matches
+		})
+		field.SetDoneFunc(func(key tcell.Key) {
+			if key == tcell.KeyEnter {
+				t.Category = models.NormalizeCategory(field.GetText())
+				showRow(i)
+			}
+			pages.RemovePage("category")
+			app.SetFocus(table)
+		})
+		field.SetBorder(true).SetTitle(" Recategorize ")
+
+		box := tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(field, 3, 0, true).
+				AddItem(nil, 0, 1, false), 50, 0, true).
+			AddItem(nil, 0, 1, false)
+		pages.AddPage("category", box, true, true)
+		app.SetFocus(field)
+	}
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Rune() == 'w':
+			confirmed = true
+			app.Stop()
+			return nil
+		case event.Rune() == 'q' || event.Key() == tcell.KeyEscape:
+			app.Stop()
+			return nil
+		}
+
+		row, _ := table.GetSelection()
+		if row < 1 || row > len(outcomes) {
+			return event
+		}
+		i := row - 1
+		o := outcomes[i]
+		if o.Status == importer.StatusInvalid {
+			return event // there is nothing to import
+		}
+
+		switch event.Rune() {
+		case 'a':
+			o.Status, o.Reason = importer.StatusNew, ""
+		case 'r':
+			o.Status, o.Reason = importer.StatusRejected, "rejected in review"
+		case 'c':
+			editCategory(i)
+			return nil
+		default:
+			return event
+		}
+		showRow(i)
+		showSummary()
+		return nil
+	})
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(summary, 1, 0, false).
+		AddItem(table, 0, 1, true).
+		AddItem(tview.NewTextView().
+			SetText("'a': accept | 'r': reject | 'c': change the category | 'w': import | 'q'/Esc: cancel without importing").
+			SetTextColor(tcell.ColorGray), 1, 0, false)
+	pages.AddPage("review", layout, true, true)
+
+	if err := app.SetRoot(pages, true).SetFocus(table).Run(); err != nil {
+		return false, err
+	}
+	return confirmed, nil
+}
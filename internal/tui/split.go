@@ -0,0 +1,135 @@
+package tui
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// newSplitEditor builds the screen that splits a transaction across categories.
+// onClose is called with the number of split lines left when the user leaves it.
+func newSplitEditor(app *tview.Application, db *sql.DB, t *models.Transaction, dateLayout string, onClose func(splits int)) (tview.Primitive, error) {
+	lines := tview.NewTable().
+		SetBorders(true).
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	status := tview.NewTextView().SetDynamicColors(true)
+
+	// IDs of the split lines by table row; the remainder row has no ID
+	var rowIDs []int64
+	splits := 0
+
+	refresh := func() error {
+		breakdown, err := models.GetSplitLines(db, t)
+		if err != nil {
+			return err
+		}
+
+		lines.Clear()
+		for i, h := range []string{"AMOUNT", "CATEGORY", "MEMO"} {
+			lines.SetCell(0, i, tview.NewTableCell(h).
+				SetTextColor(tcell.ColorYellow).
+				SetAlign(tview.AlignCenter).
+				SetSelectable(false))
+		}
+
+		rowIDs = []int64{0}
+		splits = 0
+		for i, l := range breakdown {
+			memo := l.Memo
+			if l.Remainder {
+				memo = "(not split)"
+				rowIDs = append(rowIDs, 0)
+			} else {
+				rowIDs = append(rowIDs, l.ID)
+				splits++
+			}
+			lines.SetCell(i+1, 0, tview.NewTableCell(l.Amount.String()).SetAlign(tview.AlignRight))
+			lines.SetCell(i+1, 1, tview.NewTableCell(l.Category))
+			lines.SetCell(i+1, 2, tview.NewTableCell(memo))
+		}
+		return nil
+	}
+	if err := refresh(); err != nil {
+		return nil, err
+	}
+
+	form := tview.NewForm().
+		AddInputField("Amount", "", 12, nil, nil).
+		AddInputField("Category", "", 20, nil, nil).
+		AddInputField("Memo", "", 30, nil, nil)
+
+	form.AddButton("Add", func() {
+		amountField := form.GetFormItemByLabel("Amount").(*tview.InputField)
+		categoryField := form.GetFormItemByLabel("Category").(*tview.InputField)
+		memoField := form.GetFormItemByLabel("Memo").(*tview.InputField)
+
+		amount, err := models.ParseMoney(amountField.GetText())
+		if err != nil {
+			status.SetText("[red]" + err.Error())
+			return
+		}
+		s := &models.Split{TransactionID: t.ID, Amount: amount, Category: categoryField.GetText(), Memo: memoField.GetText()}
+		if err := models.AddSplit(db, s); err != nil {
+			status.SetText("[red]" + err.Error())
+			return
+		}
+		if err := refresh(); err != nil {
+			status.SetText("[red]" + err.Error())
+			return
+		}
+
+		amountField.SetText("")
+		categoryField.SetText("")
+		memoField.SetText("")
+		status.SetText(fmt.Sprintf("[green]Added %s -> %s", s.Amount, s.Category))
+		app.SetFocus(lines)
+	})
+	form.AddButton("Done", func() { onClose(splits) })
+	// Esc in the form goes back to the list of split lines
+	form.SetCancelFunc(func() { app.SetFocus(lines) })
+	form.SetBorder(true).SetTitle(" New split line ")
+
+	lines.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape:
+			onClose(splits)
+			return nil
+		case event.Key() == tcell.KeyTab || event.Rune() == 'a':
+			app.SetFocus(form)
+			return nil
+		case event.Rune() == 'd':
+			row, _ := lines.GetSelection()
+			if row < 1 || row >= len(rowIDs) || rowIDs[row] == 0 {
+				status.SetText("[yellow]Select a split line to remove")
+				return nil
+			}
+			if err := models.DeleteSplit(db, rowIDs[row]); err != nil {
+				status.SetText("[red]" + err.Error())
+				return nil
+			}
+			if err := refresh(); err != nil {
+				status.SetText("[red]" + err.Error())
+				return nil
+			}
+			status.SetText("[green]Split line removed")
+			return nil
+		}
+		return event
+	})
+
+	header := fmt.Sprintf("Split #%d  %s  %s  %s", t.ID, t.Date.Format(dateLayout), t.Description, t.Amount)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewTextView().SetText(header).SetTextColor(tcell.ColorGreen), 1, 0, false).
+		AddItem(lines, 0, 1, true).
+		AddItem(form, 9, 0, false).
+		AddItem(status, 1, 0, false).
+		AddItem(tview.NewTextView().
+			SetText("'a'/Tab: add a line | 'd': remove the selected line | Esc: back").
+			SetTextColor(tcell.ColorGray), 1, 0, false)
+
+	return layout, nil
+}
@@ -3,14 +3,15 @@ package tui
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/SebiGabor/personal-finance-cli/internal/models"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
-// StartTUI launches the interactive terminal interface
-func StartTUI(db *sql.DB) error {
+// StartTUI launches the interactive terminal interface, showing dates with the given layout
+func StartTUI(db *sql.DB, dateLayout string) error {
 	app := tview.NewApplication()
 
 	// 1. Fetch Data
@@ -18,6 +19,10 @@ func StartTUI(db *sql.DB) error {
 	if err != nil {
 		return err
 	}
+	splitCounts, err := models.CountSplits(db)
+	if err != nil {
+		return err
+	}
 
 	// 2. Create Table
 	table := tview.NewTable().
@@ -26,7 +31,7 @@ func StartTUI(db *sql.DB) error {
 		SetFixed(1, 0)              // Fix the header row
 
 	// 3. Set Headers
-	headers := []string{"ID", "DATE", "CATEGORY", "DESCRIPTION", "AMOUNT"}
+	headers := []string{"ID", "DATE", "ACCOUNT", "CATEGORY", "DESCRIPTION", "AMOUNT"}
 	for i, h := range headers {
 		table.SetCell(0, i,
 			tview.NewTableCell(h).
@@ -51,20 +56,23 @@ func StartTUI(db *sql.DB) error {
 		table.SetCell(row, 0, tview.NewTableCell(fmt.Sprintf("%d", t.ID)).SetAlign(tview.AlignCenter))
 
 		// Date
-		table.SetCell(row, 1, tview.NewTableCell(t.Date.Format("2006-01-02")).SetAlign(tview.AlignCenter))
+		table.SetCell(row, 1, tview.NewTableCell(t.Date.Format(dateLayout)).SetAlign(tview.AlignCenter))
+
+		// Account
+		table.SetCell(row, 2, tview.NewTableCell(t.Account).SetAlign(tview.AlignCenter))
 
 		// Category
-		table.SetCell(row, 2, tview.NewTableCell(t.Category).SetAlign(tview.AlignCenter))
+		table.SetCell(row, 3, tview.NewTableCell(categoryLabel(t.Category, splitCounts[t.ID])).SetAlign(tview.AlignCenter))
 
 		// Description (Limit length to keep UI clean)
 		desc := t.Description
 		if len(desc) > 30 {
 			desc = desc[:27] + "..."
 		}
-		table.SetCell(row, 3, tview.NewTableCell(desc))
+		table.SetCell(row, 4, tview.NewTableCell(desc))
 
 		// Amount
-		table.SetCell(row, 4, tview.NewTableCell(fmt.Sprintf("%.2f", t.Amount)).
+		table.SetCell(row, 5, tview.NewTableCell(strings.TrimSpace(t.Amount.String()+" "+t.Currency)).
 			SetTextColor(color).
 			SetAlign(tview.AlignRight))
 	}
@@ -74,19 +82,63 @@ func StartTUI(db *sql.DB) error {
 	frame := tview.NewFrame(table).
 		SetBorders(0, 0, 0, 0, 0, 0).
 		AddText("Personal Finance Manager", true, tview.AlignCenter, tcell.ColorGreen).
-		AddText("Press 'q' or 'Esc' to quit | Use Arrow Keys to navigate", false, tview.AlignCenter, tcell.ColorGray)
+		AddText("Press 'q' or 'Esc' to quit | Use Arrow Keys to navigate | 's' to split the selected transaction | 'u' for upcoming recurring", false, tview.AlignCenter, tcell.ColorGray)
+
+	pages := tview.NewPages().AddPage("transactions", frame, true, true)
+
+	// Open the split editor for the selected transaction, or the upcoming recurring transactions
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'u' {
+			view, err := newUpcomingView(db, dateLayout, func() {
+				pages.RemovePage("upcoming")
+				app.SetFocus(table)
+			})
+			if err != nil {
+				return event
+			}
+			pages.AddAndSwitchToPage("upcoming", view, true)
+			return nil
+		}
 
-	// Quit functionality
+		row, _ := table.GetSelection()
+		if event.Rune() != 's' || row < 1 || row > len(transactions) {
+			return event
+		}
+		t := &transactions[row-1]
+		editor, err := newSplitEditor(app, db, t, dateLayout, func(splits int) {
+			table.GetCell(row, 3).SetText(categoryLabel(t.Category, splits))
+			pages.RemovePage("splits")
+			app.SetFocus(table)
+		})
+		if err != nil {
+			return event
+		}
+		pages.AddAndSwitchToPage("splits", editor, true)
+		return nil
+	})
+
+	// Quit functionality (only from the transaction list, the editor uses the keys itself)
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if name, _ := pages.GetFrontPage(); name != "transactions" {
+			return event
+		}
 		if event.Rune() == 'q' || event.Key() == tcell.KeyEscape {
 			app.Stop()
 		}
 		return event
 	})
 
-	if err := app.SetRoot(frame, true).SetFocus(table).Run(); err != nil {
+	if err := app.SetRoot(pages, true).SetFocus(table).Run(); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// categoryLabel shows the category of a transaction and how many split lines it has.
+func categoryLabel(category string, splits int) string {
+	if splits == 0 {
+		return category
+	}
+	return fmt.Sprintf("%s +%d split", category, splits)
+}
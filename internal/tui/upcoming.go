@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// upcomingDays is how far ahead the upcoming screen looks.
+const upcomingDays = 30
+
+// newUpcomingView builds the screen listing the recurring transactions of the next days
+// that haven't been booked yet. onClose is called when the user leaves it.
+func newUpcomingView(db *sql.DB, dateLayout string, onClose func()) (tview.Primitive, error) {
+	now := time.Now()
+	upcoming, err := models.UpcomingOccurrences(db, now, now.AddDate(0, 0, upcomingDays))
+	if err != nil {
+		return nil, err
+	}
+
+	table := tview.NewTable().
+		SetBorders(true).
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	for i, h := range []string{"DATE", "DESCRIPTION", "CATEGORY", "ACCOUNT", "AMOUNT"} {
+		table.SetCell(0, i, tview.NewTableCell(h).
+			SetTextColor(tcell.ColorYellow).
+			SetAlign(tview.AlignCenter).
+			SetSelectable(false))
+	}
+
+	for i, o := range upcoming {
+		r := o.Recurring
+		color := tcell.ColorGreen
+		if r.Amount < 0 {
+			color = tcell.ColorRed
+		}
+		table.SetCell(i+1, 0, tview.NewTableCell(o.Date.Format(dateLayout)).SetAlign(tview.AlignCenter))
+		table.SetCell(i+1, 1, tview.NewTableCell(r.Description))
+		table.SetCell(i+1, 2, tview.NewTableCell(r.Category))
+		table.SetCell(i+1, 3, tview.NewTableCell(r.Account))
+		table.SetCell(i+1, 4, tview.NewTableCell(strings.TrimSpace(r.Amount.String()+" "+r.Currency)).
+			SetTextColor(color).
+			SetAlign(tview.AlignRight))
+	}
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'u' {
+			onClose()
+			return nil
+		}
+		return event
+	})
+
+	header := fmt.Sprintf("Upcoming recurring transactions, next %d days", upcomingDays)
+	if len(upcoming) == 0 {
+		header = fmt.Sprintf("Nothing scheduled in the next %d days", upcomingDays)
+	}
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewTextView().SetText(header).SetTextColor(tcell.ColorGreen), 1, 0, false).
+		AddItem(table, 0, 1, true).
+		AddItem(tview.NewTextView().
+			SetText("Esc: back | 'finance recurring run' books the due ones").
+			SetTextColor(tcell.ColorGray), 1, 0, false)
+
+	return layout, nil
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestAccountCRUD(t *testing.T) {
+	db := NewTestDB(t)
+
+	a := &models.Account{Name: "Checking"}
+	if err := models.CreateAccount(db, a); err != nil {
+		t.Fatalf("CreateAccount failed: %v", err)
+	}
+	if a.Type != "checking" {
+		t.Errorf("expected default type 'checking', got %q", a.Type)
+	}
+
+	if err := models.CreateAccount(db, &models.Account{Name: "checking"}); err == nil {
+		t.Errorf("expected duplicate (case-insensitive) account name to fail")
+	}
+	if err := models.CreateAccount(db, &models.Account{Name: "Piggy", Type: "piggybank"}); err == nil {
+		t.Errorf("expected unknown account type to fail")
+	}
+
+	tr := &models.Transaction{Date: time.Now(), Description: "Salary", Amount: 1000_00, Category: "Income", Account: "Checking"}
+	if err := models.CreateTransaction(db, tr); err != nil {
+		t.Fatalf("CreateTransaction failed: %v", err)
+	}
+
+	// Rename moves the transactions along
+	if err := models.RenameAccount(db, "checking", "Main"); err != nil {
+		t.Fatalf("RenameAccount failed: %v", err)
+	}
+	loaded, _ := models.GetTransaction(db, tr.ID)
+	if loaded.Account != "Main" {
+		t.Errorf("expected transaction to follow the rename, got account %q", loaded.Account)
+	}
+
+	// Close hides the account from the default list and refuses new transactions
+	if err := models.CloseAccount(db, "Main"); err != nil {
+		t.Fatalf("CloseAccount failed: %v", err)
+	}
+	open, _ := models.ListAccounts(db, false)
+	if len(open) != 0 {
+		t.Errorf("expected no open accounts, got %d", len(open))
+	}
+	if _, err := models.ResolveOpenAccount(db, "Main"); err == nil {
+		t.Errorf("expected closed account to be rejected")
+	}
+}
+
+func TestAccountBalancesAndFilters(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	for _, args := range [][]string{
+		{"account", "create", "Checking"},
+		{"account", "create", "Visa", "--type", "credit"},
+		{"add", "--amount", "2000", "--desc", "Salary", "--category", "Income", "--account", "Checking", "--date", "2024-05-01"},
+		{"add", "--amount=-120", "--desc", "Groceries", "--category", "Food", "--account", "visa", "--date", "2024-05-03"},
+		{"add", "--amount=-30", "--desc", "Cinema", "--category", "Fun", "--account", "Checking", "--date", "2024-05-04"},
+	} {
+		if _, err := RunCLI(t, args...); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+	}
+
+	out, err := RunCLI(t, "account", "list")
+	if err != nil {
+		t.Fatalf("account list failed: %v", err)
+	}
+	if !strings.Contains(out, "1970.00") || !strings.Contains(out, "-120.00") {
+		t.Errorf("expected per-account balances, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "list", "--account", "Visa")
+	if !strings.Contains(out, "Groceries") || strings.Contains(out, "Salary") {
+		t.Errorf("expected list to only show Visa transactions, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "search", "a", "--account", "Checking")
+	if strings.Contains(out, "Groceries") {
+		t.Errorf("expected search to exclude Visa transactions, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "report", "--year", "2024", "--month", "5", "--account", "Checking")
+	if !strings.Contains(out, "Total Income:      2000.00") || strings.Contains(out, "Food") {
+		t.Errorf("expected report limited to Checking, got:\n%s", out)
+	}
+
+	if _, err := RunCLI(t, "add", "--amount", "1", "--desc", "x", "--account", "Nope"); err == nil {
+		t.Errorf("expected add with unknown account to fail")
+	}
+}
@@ -12,7 +12,7 @@ func TestBudgetCRUD(t *testing.T) {
 
 	b := &models.Budget{
 		Category: "Food",
-		Amount:   300,
+		Amount:   300_00,
 		Period:   "monthly",
 	}
 
@@ -26,18 +26,18 @@ func TestBudgetCRUD(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GetBudget failed: %v", err)
 	}
-	if got.Amount != 300 {
-		t.Errorf("expected amount 300, got %v", got.Amount)
+	if got.Amount != 300_00 {
+		t.Errorf("expected amount 300.00, got %s", got.Amount)
 	}
 
 	// Update
-	b.Amount = 350
+	b.Amount = 350_00
 	if err := models.UpdateBudget(db, b); err != nil {
 		t.Fatalf("UpdateBudget failed: %v", err)
 	}
 
 	got, _ = models.GetBudget(db, b.ID)
-	if got.Amount != 350 {
+	if got.Amount != 350_00 {
 		t.Errorf("expected updated amount 350")
 	}
 
@@ -59,11 +59,11 @@ func TestBudgetSpending(t *testing.T) {
 	now := time.Now()
 
 	transactions := []*models.Transaction{
-		{Date: now, Description: "Burger", Amount: -10.00, Category: "Food"},
-		{Date: now, Description: "Pizza", Amount: -20.00, Category: "Food"},
-		{Date: now, Description: "Refund", Amount: 5.00, Category: "Food"},
-		{Date: now, Description: "Gas", Amount: -50.00, Category: "Transport"},
-		{Date: now.AddDate(0, -1, 0), Description: "Old Pizza", Amount: -100.00, Category: "Food"}, // Wrong month
+		{Date: now, Description: "Burger", Amount: -10_00, Category: "Food"},
+		{Date: now, Description: "Pizza", Amount: -20_00, Category: "Food"},
+		{Date: now, Description: "Refund", Amount: 5_00, Category: "Food"},
+		{Date: now, Description: "Gas", Amount: -50_00, Category: "Transport"},
+		{Date: now.AddDate(0, -1, 0), Description: "Old Pizza", Amount: -100_00, Category: "Food"}, // Wrong month
 	}
 
 	for _, tr := range transactions {
@@ -73,19 +73,19 @@ func TestBudgetSpending(t *testing.T) {
 	}
 
 	// 2. Test GetSpendingTotal
-	total, err := models.GetSpendingTotal(db, "Food", now.Month(), now.Year())
+	total, err := models.GetSpendingTotal(db, "Food", now.Month(), now.Year(), nil)
 	if err != nil {
 		t.Fatalf("GetSpendingTotal failed: %v", err)
 	}
 
 	// Expected: 10 + 20 = 30. (5 is income, 50 is transport, 100 is last month)
-	if total != 30.00 {
-		t.Errorf("expected spending 30.00, got %.2f", total)
+	if total != 30_00 {
+		t.Errorf("expected spending 30.00, got %s", total)
 	}
 
 	// 3. Test Previous Month (Old Pizza)
-	totalLast, _ := models.GetSpendingTotal(db, "Food", now.AddDate(0, -1, 0).Month(), now.AddDate(0, -1, 0).Year())
-	if totalLast != 100.00 {
-		t.Errorf("expected last month spending 100.00, got %.2f", totalLast)
+	totalLast, _ := models.GetSpendingTotal(db, "Food", now.AddDate(0, -1, 0).Month(), now.AddDate(0, -1, 0).Year(), nil)
+	if totalLast != 100_00 {
+		t.Errorf("expected last month spending 100.00, got %s", totalLast)
 	}
 }
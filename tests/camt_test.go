@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/importer"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// camt053 is a camt.053.001.02 statement with a card payment, a salary, a batch booking
+// of two transfers and a pending entry.
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG-1</MsgId><CreDtTm>2024-03-01T08:00:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>STMT-2024-02</Id>
+      <Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Ccy>EUR</Ccy></Acct>
+      <Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-02-01</Dt></Dt></Bal>
+      <Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">2000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-02-29</Dt></Dt></Bal>
+      <Ntry>
+        <Amt Ccy="EUR">42.50</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><Dt>2024-02-03</Dt></BookgDt><ValDt><Dt>2024-02-02</Dt></ValDt>
+        <AcctSvcrRef>REF-001</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <RltdPties>
+            <Cdtr><Nm>Supermarkt  GmbH</Nm></Cdtr>
+            <CdtrAcct><Id><IBAN>DE02120300000000202051</IBAN></Id></CdtrAcct>
+          </RltdPties>
+          <RmtInf><Ustrd>Kartenzahlung</Ustrd><Ustrd>Filiale 12</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">2500.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><DtTm>2024-02-27T10:15:00</DtTm></BookgDt><ValDt><Dt>2024-02-27</Dt></ValDt>
+        <AcctSvcrRef>REF-002</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <RltdPties>
+            <Dbtr><Nm>Employer AG</Nm></Dbtr>
+            <DbtrAcct><Id><IBAN>DE12500105170648489890</IBAN></Id></DbtrAcct>
+          </RltdPties>
+          <RmtInf><Ustrd>Gehalt Februar</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">557.50</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><Dt>2024-02-28</Dt></BookgDt>
+        <AcctSvcrRef>REF-003</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls><Refs><EndToEndId>RENT-02</EndToEndId></Refs><Amt Ccy="EUR">500.00</Amt><CdtDbtInd>DBIT</CdtDbtInd>
+            <RltdPties><Cdtr><Nm>Landlord</Nm></Cdtr></RltdPties><RmtInf><Ustrd>Miete</Ustrd></RmtInf></TxDtls>
+          <TxDtls><Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs><Amt Ccy="EUR">57.50</Amt><CdtDbtInd>DBIT</CdtDbtInd>
+            <RltdPties><Cdtr><Nm>Stadtwerke</Nm></Cdtr></RltdPties><RmtInf><Ustrd>Strom</Ustrd></RmtInf></TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">9.99</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>PDNG</Sts>
+        <BookgDt><Dt>2024-02-29</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+// camt052 is an intraday report in the newer version 8 layout.
+const camt052 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.052.001.08">
+  <BkToCstmrAcctRpt>
+    <Rpt>
+      <Id>RPT-1</Id>
+      <Acct><Id><Othr><Id>0532013000</Id></Othr></Id><Ccy>CHF</Ccy></Acct>
+      <Ntry>
+        <NtryRef>N-1</NtryRef>
+        <Amt Ccy="CHF">15.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><RvslInd>true</RvslInd><Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2024-03-04</Dt></BookgDt>
+        <NtryDtls><TxDtls><RltdPties><Dbtr><Pty><Nm>Online Shop</Nm></Pty></Dbtr></RltdPties></TxDtls></NtryDtls>
+        <AddtlNtryInf>Storno Bestellung 77</AddtlNtryInf>
+      </Ntry>
+    </Rpt>
+  </BkToCstmrAcctRpt>
+</Document>
+`
+
+func TestParseCAMT(t *testing.T) {
+	statements, err := importer.ParseCAMT(strings.NewReader(camt053))
+	if err != nil {
+		t.Fatalf("ParseCAMT failed: %v", err)
+	}
+	if len(statements) != 1 {
+		t.Fatalf("expected one statement, got %d", len(statements))
+	}
+	s := statements[0]
+	if s.AccountID != "DE89370400440532013000" || s.Currency != "EUR" {
+		t.Errorf("unexpected statement header %+v", s)
+	}
+	if s.Balance == nil || s.Balance.Amount != 2000_00 || s.Balance.Date.Format("2006-01-02") != "2024-02-29" {
+		t.Errorf("expected the closing booked balance, got %+v", s.Balance)
+	}
+	// The pending entry is left out, the batch booking yields two records
+	if len(s.Records) != 4 {
+		t.Fatalf("expected 4 records, got %+v", s.Records)
+	}
+
+	card := s.Records[0]
+	if card.Amount != -42_50 || card.Description != "Supermarkt GmbH - Kartenzahlung Filiale 12" || card.ExternalID != "REF-001" ||
+		card.Counterparty != "Supermarkt  GmbH" || card.CounterpartyIBAN != "DE02120300000000202051" ||
+		card.Date.Format("2006-01-02") != "2024-02-03" || card.ValueDate.Format("2006-01-02") != "2024-02-02" || card.Currency != "EUR" {
+		t.Errorf("unexpected card payment %+v", card)
+	}
+	salary := s.Records[1]
+	if salary.Amount != 2500_00 || salary.Counterparty != "Employer AG" || salary.CounterpartyIBAN != "DE12500105170648489890" ||
+		salary.Date.Format("2006-01-02") != "2024-02-27" {
+		t.Errorf("unexpected salary %+v", salary)
+	}
+	rent, power := s.Records[2], s.Records[3]
+	if rent.Amount != -500_00 || rent.ExternalID != "RENT-02" || rent.Description != "Landlord - Miete" {
+		t.Errorf("unexpected batch record %+v", rent)
+	}
+	if power.Amount != -57_50 || power.ExternalID != "REF-003/2" {
+		t.Errorf("expected the entry reference with the position when the detail has none, got %+v", power)
+	}
+
+	statements, err = importer.ParseCAMT(strings.NewReader(camt052))
+	if err != nil || len(statements) != 1 || len(statements[0].Records) != 1 {
+		t.Fatalf("unexpected camt.052 result %+v (%v)", statements, err)
+	}
+	refund := statements[0].Records[0]
+	if statements[0].AccountID != "0532013000" || refund.Amount != 15_00 || refund.ExternalID != "N-1" ||
+		refund.Description != "Online Shop - Storno Bestellung 77" || refund.Currency != "CHF" {
+		t.Errorf("unexpected camt.052 record %+v", refund)
+	}
+
+	if _, err := importer.ParseCAMT(strings.NewReader(`<Document><Other/></Document>`)); err == nil {
+		t.Errorf("expected a document without statements to be rejected")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := map[string]string{
+		camt053:                          importer.FormatCAMT,
+		camt052:                          importer.FormatCAMT,
+		sgmlOFX:                          importer.FormatOFX,
+		"<?xml version=\"1.0\"?>\n<OFX>": importer.FormatOFX,
+		sampleQIF:                        importer.FormatQIF,
+		"\n!Type:Bank\nD01/01/2024\n^\n": importer.FormatQIF,
+	}
+	for data, want := range cases {
+		imp, err := importer.Detect("statement.dat", []byte(data))
+		if err != nil || imp.Name() != want {
+			t.Errorf("Detect(%.30q) = %v (%v), want %q", data, imp, err, want)
+		}
+	}
+	if _, err := importer.Detect("statement.dat", []byte("Date,Description,Amount\n")); err == nil {
+		t.Errorf("expected CSV content without a .csv extension to be rejected")
+	}
+}
+
+func TestImportCAMT(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	if err := models.CreateAccount(db, &models.Account{Name: "Giro", Currency: "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "add", "--amount", "100", "--desc", "Opening balance", "--account", "Giro", "--date", "2024-02-01"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The extension doesn't matter; the content tells the format
+	file := filepath.Join(t.TempDir(), "statement.xml")
+	if err := os.WriteFile(file, []byte(camt053), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := RunCLI(t, "import", file, "--account", "Giro")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "CAMT Import complete. 4 imported, 0 duplicates skipped, 0 errors.") ||
+		!strings.Contains(out, "Statement for account DE89370400440532013000") {
+		t.Errorf("unexpected import output:\n%s", out)
+	}
+	if !strings.Contains(out, "Bank balance on 2024-02-29: 2000.00 EUR") || strings.Contains(out, "difference") {
+		t.Errorf("expected the closing balance to match, got:\n%s", out)
+	}
+
+	out, err = RunCLI(t, "import", file, "--account", "Giro", "--force")
+	if err != nil || !strings.Contains(out, "0 imported, 4 duplicates skipped") {
+		t.Errorf("expected the bank references to recognize the re-import, got:\n%s (%v)", out, err)
+	}
+
+	found, _ := models.FindTransactions(db, models.TransactionFilter{Query: "Stadtwerke"})
+	if len(found) != 1 || found[0].ExternalID != "REF-003/2" || found[0].Currency != "EUR" {
+		t.Errorf("unexpected imported transaction %+v", found)
+	}
+
+	other := filepath.Join(t.TempDir(), "notes.xml")
+	if err := os.WriteFile(other, []byte("<notes/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "import", other); err == nil {
+		t.Errorf("expected an unrecognized file to be rejected")
+	}
+}
@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/db"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestCategoryTree(t *testing.T) {
+	database := NewTestDB(t)
+
+	if got := models.NormalizeCategory(" food : GROCERIES "); got != "Food:Groceries" {
+		t.Errorf("expected every level to be normalized, got %q", got)
+	}
+
+	groceries, err := models.EnsureCategory(database, "Food:Groceries")
+	if err != nil {
+		t.Fatalf("EnsureCategory failed: %v", err)
+	}
+	again, _ := models.EnsureCategory(database, "food:groceries")
+	if again.ID != groceries.ID || again.Path != "Food:Groceries" {
+		t.Errorf("expected the existing category, got %+v", again)
+	}
+
+	date := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	for _, tr := range []*models.Transaction{
+		{Date: date, Description: "Market", Amount: -100_00, Category: "Food:Groceries"},
+		{Date: date, Description: "Pizza", Amount: -40_00, Category: "Food:Restaurants"},
+		{Date: date, Description: "Snack", Amount: -5_00, Category: "Food"},
+		{Date: date, Description: "Bus", Amount: -20_00, Category: "Transport"},
+	} {
+		if err := models.CreateTransaction(database, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A budget on the parent covers its children
+	spent, err := models.GetSpendingTotal(database, "Food", time.May, 2024, nil)
+	if err != nil || spent != 145_00 {
+		t.Errorf("expected 145.00 spent on Food and below, got %s (%v)", spent, err)
+	}
+
+	breakdown, _, _, err := models.GetMonthlyReport(database, 2024, 5, models.TransactionFilter{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := models.BuildCategoryTree(breakdown)
+	if len(tree) != 2 || tree[0].Name != "Food" || tree[0].Total != -145_00 || tree[0].Own != -5_00 || len(tree[0].Children) != 2 {
+		t.Fatalf("unexpected tree %+v", tree[0])
+	}
+	if tree[0].Children[0].Name != "Groceries" {
+		t.Errorf("expected the biggest expense first, got %s", tree[0].Children[0].Name)
+	}
+
+	// Renames and moves keep transactions attached
+	if err := models.RenameCategory(database, "Food:Restaurants", "eating out"); err != nil {
+		t.Fatalf("RenameCategory failed: %v", err)
+	}
+	if err := models.MoveCategory(database, "Food", "Food:Groceries"); err == nil {
+		t.Errorf("expected moving a category below itself to fail")
+	}
+	if err := models.MoveCategory(database, "Transport", "Food"); err != nil {
+		t.Fatalf("MoveCategory failed: %v", err)
+	}
+	found, _ := models.SearchTransactions(database, "Bus")
+	if len(found) != 1 || found[0].Category != "Food:Transport" {
+		t.Errorf("expected the bus ride below Food, got %+v", found)
+	}
+	found, _ = models.SearchTransactions(database, "Pizza")
+	if len(found) != 1 || found[0].Category != "Food:Eating Out" {
+		t.Errorf("expected the renamed category, got %+v", found)
+	}
+
+	if err := models.DeleteCategory(database, "Food"); err == nil {
+		t.Errorf("expected a category in use to be kept")
+	}
+}
+
+func TestCategoryStringsAreMigratedToTree(t *testing.T) {
+	database, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+
+	// Categories as free-form strings, the way versions before the tree stored them
+	legacy := []string{
+		`CREATE TABLE transactions (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT NOT NULL, description TEXT,
+			amount INTEGER NOT NULL, category TEXT, account TEXT, currency TEXT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE budgets (id INTEGER PRIMARY KEY AUTOINCREMENT, category TEXT NOT NULL, amount INTEGER NOT NULL, period TEXT NOT NULL)`,
+		`INSERT INTO transactions (date, description, amount, category) VALUES ('2024-02-01', 'Market', -1000, 'Food:Groceries')`,
+		`INSERT INTO transactions (date, description, amount, category) VALUES ('2024-02-02', 'Cafe', -500, 'Food')`,
+		`INSERT INTO budgets (category, amount, period) VALUES ('Food', 20000, 'monthly')`,
+	}
+	for _, stmt := range legacy {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("legacy setup failed: %v", err)
+		}
+	}
+
+	if _, err := db.Migrate(database); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	tr, err := models.GetTransaction(database, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	food, err := models.FindCategory(database, "Food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groceries, _ := models.FindCategory(database, "Food:Groceries")
+	if tr.Category != "Food:Groceries" || groceries.ParentID != food.ID {
+		t.Errorf("expected Groceries below Food, got %q (parent %d)", tr.Category, groceries.ParentID)
+	}
+
+	b, _ := models.GetBudget(database, 1)
+	if b.Category != "Food" {
+		t.Errorf("expected the budget to keep its category, got %q", b.Category)
+	}
+	spent, _ := models.GetSpendingTotal(database, "Food", time.February, 2024, nil)
+	if spent != 15_00 {
+		t.Errorf("expected 15.00 spent on Food and below, got %s", spent)
+	}
+}
+
+func TestCategoryCommands(t *testing.T) {
+	database := NewTestDB(t)
+	cli.SetDatabase(database)
+
+	RunCLI(t, "add", "--amount=-30", "--desc", "Market", "--category", "food:groceries", "--date", "2024-05-02")
+	RunCLI(t, "add", "--amount=-10", "--desc", "Cafe", "--category", "food:coffee", "--date", "2024-05-03")
+	RunCLI(t, "add", "--amount", "500", "--desc", "Salary", "--category", "income", "--date", "2024-05-01")
+
+	out, _ := RunCLI(t, "category", "list")
+	if !strings.Contains(out, "Food\n  Coffee\n  Groceries\n") {
+		t.Errorf("expected an indented tree, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "report", "--year", "2024", "--month", "5")
+	if !strings.Contains(out, "Food ") || !strings.Contains(out, "-40.00") || !strings.Contains(out, "  Groceries") {
+		t.Errorf("expected Food to roll up its children, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "report", "--year", "2024", "--month", "5", "--depth", "1")
+	if strings.Contains(out, "Groceries") || !strings.Contains(out, "Food (+)") {
+		t.Errorf("expected subcategories to be collapsed, got:\n%s", out)
+	}
+
+	// A parent budget sees spending in its children
+	RunCLI(t, "budget", "add", "--category", "Food", "--amount", "42")
+	out, _ = RunCLI(t, "add", "--amount=-1", "--desc", "Gum", "--category", "Food:Groceries", "--date", "2024-05-04")
+	if !strings.Contains(out, "WARNING") || !strings.Contains(out, "'Food'") {
+		t.Errorf("expected a warning for the Food budget, got:\n%s", out)
+	}
+}
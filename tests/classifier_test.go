@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestParseConfidence(t *testing.T) {
+	for in, want := range map[string]float64{"0.9": 0.9, "1": 1, " 0.75 ": 0.75, "off": 0, "OFF": 0} {
+		if got, err := models.ParseConfidence(in); err != nil || got != want {
+			t.Errorf("ParseConfidence(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0", "1.5", "-0.2", "high"} {
+		if _, err := models.ParseConfidence(in); err == nil {
+			t.Errorf("ParseConfidence(%q) should fail", in)
+		}
+	}
+}
+
+func TestClassifierPredict(t *testing.T) {
+	c := models.NewClassifier()
+	for i := 0; i < 5; i++ {
+		c.Learn("STARBUCKS 054", -450, "Food:Coffee")
+		c.Learn("REWE SAGT DANKE 1234", -4200, "Food:Groceries")
+		c.Learn("SALARY ACME GMBH", 320000, "Income")
+	}
+	c.Learn("STARBUCKS RESERVE", -9000, "Food:Groceries")
+
+	if p := c.Predict("STARBUCKS 112", -390); p.Category != "Food:Coffee" || p.Confidence < 0.9 {
+		t.Errorf("expected a confident Food:Coffee, got %+v", p)
+	}
+	if p := c.Predict("Rewe Markt", -3800); p.Category != "Food:Groceries" {
+		t.Errorf("expected Food:Groceries, got %+v", p)
+	}
+	if p := c.Predict("UNKNOWN SHOP", -1000); p.Category != "" {
+		t.Errorf("expected no prediction without a known word, got %+v", p)
+	}
+	one := models.NewClassifier()
+	one.Learn("LIDL 17", -800, "Shopping")
+	if p := one.Predict("Lidl Bakery", -400); p.Category != "" {
+		t.Errorf("expected no prediction from a single category, got %+v", p)
+	}
+	var none *models.Classifier
+	if p := none.Predict("STARBUCKS", -450); p.Category != "" {
+		t.Errorf("expected a nil classifier to predict nothing, got %+v", p)
+	}
+}
+
+func TestImportFallsBackToLearnedCategory(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	for _, args := range [][]string{
+		{"add", "--amount", "-4.50", "--desc", "STARBUCKS 054", "--category", "Food", "--date", "2024-01-02"},
+		{"add", "--amount", "-3.90", "--desc", "Starbucks 112", "--category", "Food", "--date", "2024-01-05"},
+		{"add", "--amount", "-5.10", "--desc", "STARBUCKS 054", "--category", "Food", "--date", "2024-01-09"},
+		{"add", "--amount", "-60.00", "--desc", "SHELL STATION 7", "--category", "Transport", "--date", "2024-01-10"},
+		{"add", "--amount", "-55.00", "--desc", "SHELL STATION 9", "--category", "Transport", "--date", "2024-01-20"},
+		{"rules", "add", "--pattern", "(?i)netflix", "--category", "Entertainment"},
+	} {
+		if _, err := RunCLI(t, args...); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+	}
+
+	csvFile := filepath.Join(t.TempDir(), "feb.csv")
+	content := "Date,Description,Amount,Category\n" +
+		"2024-02-01,STARBUCKS 201,-4.20,\n" +
+		"2024-02-02,NETFLIX.COM,-12.99,\n" +
+		"2024-02-03,Hardware Store,-19.99,\n" +
+		"2024-02-04,SHELL STATION 3,-48.00,Travel\n"
+	if err := os.WriteFile(csvFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := RunCLI(t, "import", csvFile, "--dry-run", "--confidence", "0.6")
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if !strings.Contains(out, "Food (learned,") {
+		t.Errorf("expected the dry run to show the learned category, got:\n%s", out)
+	}
+
+	out, err = RunCLI(t, "import", csvFile, "--confidence", "0.6")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "1 categorized from history") {
+		t.Errorf("expected one learned category, got:\n%s", out)
+	}
+
+	want := map[string]string{
+		"STARBUCKS 201":   "Food",          // learned
+		"NETFLIX.COM":     "Entertainment", // the rule comes first
+		"Hardware Store":  "Uncategorized", // nothing known about it
+		"SHELL STATION 3": "Travel",        // the file's category wins
+	}
+	txs, _ := models.FindTransactions(db, models.TransactionFilter{})
+	for _, tr := range txs {
+		if c, ok := want[tr.Description]; ok && tr.Category != c {
+			t.Errorf("%s: expected %s, got %s", tr.Description, c, tr.Category)
+		}
+	}
+
+	// Learning is off unless asked for, and then a new Starbucks stays uncategorized
+	for _, args := range [][]string{{}, {"--confidence", "off"}} {
+		out, err = RunCLI(t, append([]string{"add", "--amount", "-4.00", "--desc", "STARBUCKS 300"}, args...)...)
+		if err != nil || strings.Contains(out, "Auto-categorized") {
+			t.Errorf("expected no category with %v, got:\n%s (%v)", args, out, err)
+		}
+	}
+	if out, _ := RunCLI(t, "import", csvFile, "--dry-run"); strings.Contains(out, "(learned,") {
+		t.Errorf("expected imports not to learn by default, got:\n%s", out)
+	}
+	out, err = RunCLI(t, "add", "--amount", "-4.00", "--desc", "STARBUCKS 301", "--confidence", "0.6")
+	if err != nil || !strings.Contains(out, "Auto-categorized as: Food (learned,") {
+		t.Errorf("expected the learned category, got:\n%s (%v)", out, err)
+	}
+	if _, err := RunCLI(t, "add", "--amount", "-4.00", "--desc", "x", "--confidence", "2"); err == nil {
+		t.Errorf("expected an invalid confidence to fail")
+	}
+}
+
+func TestCategorizeSuggestAndAccept(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	for _, args := range [][]string{
+		{"add", "--amount", "-4.50", "--desc", "STARBUCKS 054", "--category", "Food"},
+		{"add", "--amount", "-5.10", "--desc", "STARBUCKS 054", "--category", "Food"},
+		{"add", "--amount", "-60.00", "--desc", "SHELL STATION 7", "--category", "Transport"},
+		{"add", "--amount", "-4.20", "--desc", "STARBUCKS 201", "--confidence", "off"},
+		{"add", "--amount", "-58.00", "--desc", "SHELL STATION 3", "--confidence", "off"},
+		{"add", "--amount", "-19.99", "--desc", "Hardware Store", "--confidence", "off"},
+	} {
+		if _, err := RunCLI(t, args...); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+	}
+
+	out, err := RunCLI(t, "categorize", "--suggest")
+	if err != nil {
+		t.Fatalf("categorize --suggest failed: %v", err)
+	}
+	for _, want := range []string{"STARBUCKS 201", "SHELL STATION 3", "2 suggestion(s)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Hardware Store") {
+		t.Errorf("expected no suggestion for an unknown merchant, got:\n%s", out)
+	}
+	if tr, _ := models.GetTransaction(db, 4); tr.Category != "Uncategorized" {
+		t.Errorf("expected --suggest to store nothing, got %+v", tr)
+	}
+
+	if _, err := RunCLI(t, "categorize", "5"); err == nil {
+		t.Errorf("expected IDs without --accept to fail")
+	}
+	out, err = RunCLI(t, "categorize", "--accept", "4")
+	if err != nil || !strings.Contains(out, "Categorized 1 transaction(s).") {
+		t.Fatalf("categorize --accept 4 failed: %v\n%s", err, out)
+	}
+	if tr, _ := models.GetTransaction(db, 4); tr.Category != "Food" {
+		t.Errorf("expected Food, got %+v", tr)
+	}
+	if tr, _ := models.GetTransaction(db, 5); tr.Category != "Uncategorized" {
+		t.Errorf("expected transaction 5 to be left alone, got %+v", tr)
+	}
+
+	if _, err := RunCLI(t, "categorize", "--suggest", "--accept"); err != nil {
+		t.Fatal(err)
+	}
+	if tr, _ := models.GetTransaction(db, 5); tr.Category != "Transport" {
+		t.Errorf("expected Transport, got %+v", tr)
+	}
+	if out, _ := RunCLI(t, "categorize", "--suggest"); !strings.Contains(out, "No category to suggest.") {
+		t.Errorf("expected nothing left to suggest, got:\n%s", out)
+	}
+}
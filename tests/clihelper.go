@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// RunCLI executes the root command with the given arguments and returns its output.
+// Flags of every command are reset before and after the run, so values set by one
+// test do not leak into the next one through cobra's package-level commands.
+func RunCLI(t testing.TB, args ...string) (string, error) {
+	t.Helper()
+
+	resetFlags(cli.RootCmd)
+	defer resetFlags(cli.RootCmd)
+
+	out := new(bytes.Buffer)
+	cli.RootCmd.SetOut(out)
+	cli.RootCmd.SetArgs(args)
+	err := cli.RootCmd.Execute()
+	return out.String(), err
+}
+
+// RunCLIWithInput is RunCLI for commands that read answers from standard input.
+func RunCLIWithInput(t testing.TB, input string, args ...string) (string, error) {
+	t.Helper()
+
+	cli.RootCmd.SetIn(strings.NewReader(input))
+	defer cli.RootCmd.SetIn(nil)
+	return RunCLI(t, args...)
+}
+
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
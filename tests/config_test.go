@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/config"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// isolateConfig points the config file and the environment at a temporary directory.
+func isolateConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	t.Setenv("FINANCE_CONFIG", path)
+	t.Setenv("XDG_DATA_HOME", dir)
+	for _, env := range []string{"FINANCE_DB", "FINANCE_BASE_CURRENCY", "FINANCE_DATE_FORMAT", "FINANCE_ACCOUNT"} {
+		t.Setenv(env, "")
+	}
+	return path
+}
+
+func TestDateLayout(t *testing.T) {
+	cases := map[string]string{
+		"":            "2006-01-02",
+		"YYYY-MM-DD":  "2006-01-02",
+		"DD.MM.YYYY":  "02.01.2006",
+		"MM/DD/YY":    "01/02/06",
+		"02 Jan 2006": "02 Jan 2006",
+	}
+	for format, want := range cases {
+		got, err := config.DateLayout(format)
+		if err != nil || got != want {
+			t.Errorf("DateLayout(%q) = %q, %v; want %q", format, got, err, want)
+		}
+	}
+
+	if _, err := config.DateLayout("DD.MM"); err == nil {
+		t.Errorf("expected a format without a year to be rejected")
+	}
+}
+
+func TestConfigLoadSave(t *testing.T) {
+	path := isolateConfig(t)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load of a missing file failed: %v", err)
+	}
+	if err := cfg.Set("default_account", "Checking"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("colour", "blue"); err == nil {
+		t.Errorf("expected an unknown key to be rejected")
+	}
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.DefaultAccount != "Checking" {
+		t.Errorf("expected default_account to round-trip, got %q", loaded.DefaultAccount)
+	}
+
+	if want := filepath.Join(filepath.Dir(path), "finance", "finance.db"); mustDefaultDBPath(t) != want {
+		t.Errorf("expected default database under XDG_DATA_HOME, got %s", mustDefaultDBPath(t))
+	}
+}
+
+func mustDefaultDBPath(t *testing.T) string {
+	t.Helper()
+	path, err := config.DefaultDBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigDefaultsApplyToCommands(t *testing.T) {
+	isolateConfig(t)
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	if err := models.CreateAccount(db, &models.Account{Name: "Checking", Currency: "RON"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "config", "set", "default_account", "Checking"); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	if _, err := RunCLI(t, "config", "set", "date_format", "DD.MM.YYYY"); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	if _, err := RunCLI(t, "config", "set", "date_format", "banana"); err == nil {
+		t.Errorf("expected an invalid date format to be rejected")
+	}
+
+	// The configured format is accepted on input, and the default account is used
+	if _, err := RunCLI(t, "add", "--amount", "-12.50", "--desc", "Bakery", "--category", "Food", "--date", "23.11.2024"); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	txs, _ := models.ListTransactions(db)
+	if len(txs) != 1 || txs[0].Account != "Checking" || txs[0].Currency != "RON" {
+		t.Fatalf("expected the transaction in the default account, got %+v", txs)
+	}
+
+	out, _ := RunCLI(t, "list")
+	if !strings.Contains(out, "23.11.2024") {
+		t.Errorf("expected dates in the configured format, got:\n%s", out)
+	}
+
+	// Environment beats the config file, and show reports where values come from
+	t.Setenv("FINANCE_DATE_FORMAT", "YYYY/MM/DD")
+	out, _ = RunCLI(t, "config", "show")
+	if !strings.Contains(out, "2006/01/02") || !strings.Contains(out, "env FINANCE_DATE_FORMAT") {
+		t.Errorf("expected the environment to win for date_format, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Checking") || !strings.Contains(out, "config") {
+		t.Errorf("expected default_account from the config file, got:\n%s", out)
+	}
+}
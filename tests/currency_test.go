@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestMoneyMulRounding(t *testing.T) {
+	tests := []struct {
+		amount   models.Money
+		rate     string
+		expected models.Money
+	}{
+		{amount: 100_00, rate: "4.9765", expected: 497_65},
+		{amount: -10_01, rate: "0.5", expected: -5_01}, // -5.005 rounds away from zero
+		{amount: 10_01, rate: "0.5", expected: 5_01},
+		{amount: 1, rate: "0.4", expected: 0},
+	}
+	for _, tt := range tests {
+		rate, err := models.ParseRate(tt.rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := tt.amount.Mul(rate); err != nil || got != tt.expected {
+			t.Errorf("%s * %s = %s, %v; want %s", tt.amount, tt.rate, got, err, tt.expected)
+		}
+	}
+
+	rate, _ := models.ParseRate("1000")
+	if got, err := models.Money(math.MaxInt64 / 10).Mul(rate); err == nil {
+		t.Errorf("expected an overflowing product to fail, got %s", got)
+	}
+}
+
+func TestConverterUsesRateInEffect(t *testing.T) {
+	db := NewTestDB(t)
+
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	for _, r := range []models.ExchangeRate{
+		{Date: day(1), Base: "EUR", Quote: "RON", Rate: "5"},
+		{Date: day(10), Base: "eur", Quote: "ron", Rate: "4"},
+		{Date: day(1), Base: "EUR", Quote: "USD", Rate: "1.25"},
+	} {
+		r := r
+		if err := models.SetRate(db, &r); err != nil {
+			t.Fatalf("SetRate failed: %v", err)
+		}
+	}
+
+	conv, err := models.NewConverter(db, "RON")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Direct rate, picking the latest one on or before the date
+	if got, _ := conv.Convert(10_00, "EUR", day(5)); got != 50_00 {
+		t.Errorf("expected 50.00 RON on May 5th, got %s", got)
+	}
+	if got, _ := conv.Convert(10_00, "EUR", day(12)); got != 40_00 {
+		t.Errorf("expected 40.00 RON on May 12th, got %s", got)
+	}
+	// Cross rate through EUR: 1 USD = 0.8 EUR = 4 RON
+	if got, _ := conv.Convert(10_00, "USD", day(5)); got != 40_00 {
+		t.Errorf("expected 40.00 RON for 10 USD, got %s", got)
+	}
+	// Inverse rate: RON -> EUR on a converter with EUR as base
+	eur, _ := models.NewConverter(db, "")
+	if got, _ := eur.Convert(50_00, "RON", day(5)); got != 10_00 {
+		t.Errorf("expected 10.00 EUR for 50 RON, got %s", got)
+	}
+	// Before any rate was recorded
+	if _, err := conv.Convert(10_00, "EUR", day(1).AddDate(0, 0, -1)); err == nil {
+		t.Errorf("expected an error when no rate is in effect")
+	}
+}
+
+func TestMultiCurrencyReport(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	ecb := `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+  <gesmes:subject>Reference rates</gesmes:subject>
+  <Cube>
+    <Cube time="2024-05-02">
+      <Cube currency="USD" rate="1.25"/>
+      <Cube currency="RON" rate="5"/>
+    </Cube>
+  </Cube>
+</gesmes:Envelope>`
+	tmpfile, err := os.CreateTemp("", "eurofxref-*.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+	tmpfile.WriteString(ecb)
+	tmpfile.Close()
+
+	out, err := RunCLI(t, "rates", "import", tmpfile.Name())
+	if err != nil || !strings.Contains(out, "2 rates stored") {
+		t.Fatalf("rates import failed: %v\n%s", err, out)
+	}
+
+	for _, args := range [][]string{
+		{"account", "create", "Travel Card", "--type", "credit", "--currency", "usd"},
+		{"add", "--amount", "1000", "--desc", "Salary", "--category", "Income", "--currency", "EUR", "--date", "2024-05-03"},
+		{"add", "--amount=-100", "--desc", "Hotel", "--category", "Travel", "--account", "Travel Card", "--date", "2024-05-03"},
+		{"add", "--amount=-250", "--desc", "Groceries", "--category", "Food", "--currency", "RON", "--date", "2024-05-04"},
+	} {
+		if out, err := RunCLI(t, args...); err != nil {
+			t.Fatalf("%v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	// The card's currency is inherited by its transactions
+	out, _ = RunCLI(t, "list", "--account", "Travel Card")
+	if !strings.Contains(out, "-100.00 USD") {
+		t.Errorf("expected transaction in USD, got:\n%s", out)
+	}
+
+	out, err = RunCLI(t, "report", "--year", "2024", "--month", "5", "--base", "RON")
+	if err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	// 1000 EUR = 5000 RON; 100 USD = 80 EUR = 400 RON; 250 RON stays
+	if !strings.Contains(out, "Amounts in RON") || !strings.Contains(out, "Total Income:      5000.00") ||
+		!strings.Contains(out, "Total Expenses:    -650.00") {
+		t.Errorf("expected report converted to RON, got:\n%s", out)
+	}
+
+	// Without rates for the requested base, the report refuses to mix currencies
+	if _, err := RunCLI(t, "report", "--year", "2024", "--month", "5", "--base", "GBP"); err == nil {
+		t.Errorf("expected report to fail without GBP rates")
+	}
+}
@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestDescriptionSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"STARBUCKS 054", "Starbucks - Coffee", 1},
+		{"CARD PAYMENT TESCO STORES 3344", "Tesco Stores", 1},
+		{"CARD PAYMENT TESCO", "CARD PAYMENT SHELL", 0},
+		{"Amazon Marketplace", "Amazon Prime", 0.5},
+		{"12345", "12345", 1},
+		{"12345", "67890", 0},
+	}
+	for _, tt := range tests {
+		if got := models.DescriptionSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("DescriptionSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseFuzzyMatch(t *testing.T) {
+	m, err := models.ParseFuzzyMatch("days=5, amount=0.50")
+	if err != nil {
+		t.Fatalf("ParseFuzzyMatch failed: %v", err)
+	}
+	if m.Days != 5 || m.Tolerance != 50 || m.Similarity != models.DefaultFuzzyMatch.Similarity {
+		t.Errorf("unexpected setting %+v", m)
+	}
+	if m.String() != "days=5,amount=0.50,similarity=0.5" {
+		t.Errorf("unexpected String() %q", m.String())
+	}
+
+	if m, err := models.ParseFuzzyMatch("on"); err != nil || m != models.DefaultFuzzyMatch {
+		t.Errorf("expected on to give the defaults, got %+v (%v)", m, err)
+	}
+	if m, err := models.ParseFuzzyMatch("off"); err != nil || m.Enabled() {
+		t.Errorf("expected off to disable fuzzy matching, got %+v (%v)", m, err)
+	}
+	for _, bad := range []string{"days=-1", "similarity=0", "similarity=2", "amount=x", "weeks=1", "3"} {
+		if _, err := models.ParseFuzzyMatch(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestImportFuzzyDuplicates(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+	if err := models.CreateAccount(db, &models.Account{Name: "Checking"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "export.csv")
+	os.WriteFile(csvFile, []byte(`Date,Description,Amount,Category
+2024-03-01,STARBUCKS 054,-4.50,Coffee
+2024-03-01,CARD PAYMENT TESCO,-20.00,Food
+`), 0o644)
+	qifFile := filepath.Join(dir, "statement.qif")
+	os.WriteFile(qifFile, []byte(`!Type:Bank
+D03/02/2024
+T-4.50
+PStarbucks - Coffee
+^
+D03/03/2024
+T-4.50
+PStarbucks - Coffee
+^
+D03/02/2024
+T-20.00
+PCARD PAYMENT SHELL
+^
+`), 0o644)
+
+	if _, err := RunCLI(t, "import", csvFile, "--account", "Checking"); err != nil {
+		t.Fatalf("CSV import failed: %v", err)
+	}
+
+	// The first coffee is the one of the CSV export; the second one, and the
+	// payment to another shop, are new
+	out, err := RunCLI(t, "import", qifFile, "--account", "Checking", "--fuzzy", "on")
+	if err != nil {
+		t.Fatalf("QIF import failed: %v", err)
+	}
+	if !strings.Contains(out, "2 imported, 1 duplicates skipped") {
+		t.Errorf("expected one likely duplicate to be skipped, got:\n%s", out)
+	}
+	if !strings.Contains(out, `Skipped "Starbucks - Coffee" of 2024-03-02 as likely the same as #1 "STARBUCKS 054"`) {
+		t.Errorf("expected the skipped record to be named, got:\n%s", out)
+	}
+
+	// Records of the same source are only compared exactly
+	if txs, _ := models.ListTransactions(db); len(txs) != 4 {
+		t.Errorf("expected 4 transactions, got %d", len(txs))
+	}
+
+	// Fuzzy matching is off unless turned on, and then the coffee is imported as well
+	for i, args := range [][]string{{}, {"--fuzzy", "off"}} {
+		if _, err := RunCLI(t, "import", "undo", strconv.Itoa(2+i)); err != nil {
+			t.Fatal(err)
+		}
+		out, err = RunCLI(t, append([]string{"import", qifFile, "--account", "Checking", "--force"}, args...)...)
+		if err != nil || !strings.Contains(out, "3 imported, 0 duplicates skipped") {
+			t.Errorf("expected %v to import everything, got:\n%s (%v)", args, out, err)
+		}
+	}
+}
+
+func TestDuplicatesMerge(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "export.csv")
+	os.WriteFile(csvFile, []byte(`Date,Description,Amount,Category
+2024-03-01,STARBUCKS 054,-4.50,
+2024-03-05,Bookshop,-12.00,Books
+`), 0o644)
+	qifFile := filepath.Join(dir, "statement.qif")
+	os.WriteFile(qifFile, []byte(`!Type:Bank
+D03/02/2024
+T-4.50
+PStarbucks - Coffee
+LEating Out
+^
+`), 0o644)
+
+	for _, f := range []string{csvFile, qifFile} {
+		if _, err := RunCLI(t, "import", f, "--fuzzy", "off"); err != nil {
+			t.Fatalf("import of %s failed: %v", f, err)
+		}
+	}
+	// Entered by hand, it is another source than the CSV export
+	if _, err := RunCLI(t, "add", "--amount", "-12.00", "--desc", "The Bookshop", "--category", "Books", "--date", "2024-03-06"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.AddTags(db, 3, "morning"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := RunCLI(t, "duplicates")
+	if err != nil {
+		t.Fatalf("duplicates failed: %v", err)
+	}
+	if !strings.Contains(out, "2 likely duplicate(s)") || !strings.Contains(out, "Starbucks - Coffee") {
+		t.Errorf("expected two pairs, got:\n%s", out)
+	}
+	if out, _ := RunCLI(t, "duplicates", "--fuzzy", "days=0"); !strings.Contains(out, "No likely duplicates found.") {
+		t.Errorf("expected no pairs on the same day, got:\n%s", out)
+	}
+
+	if out, err := RunCLI(t, "duplicates", "--merge"); err != nil || !strings.Contains(out, "Merged 2 duplicate(s).") {
+		t.Fatalf("duplicates --merge failed:\n%s (%v)", out, err)
+	}
+
+	// The older ones are kept and took over the category and tag of the coffee
+	txs, _ := models.ListTransactions(db)
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 transactions after merging, got %+v", txs)
+	}
+	coffee, _ := models.GetTransaction(db, 1)
+	if coffee == nil || coffee.Category != "Eating Out" {
+		t.Errorf("expected the kept coffee to take the category, got %+v", coffee)
+	}
+	if tags, _ := models.GetTags(db, 1); len(tags) != 1 || tags[0] != "morning" {
+		t.Errorf("expected the kept coffee to take the tag, got %v", tags)
+	}
+
+	if _, err := RunCLI(t, "duplicates", "merge", "1", "1"); err == nil {
+		t.Errorf("expected merging a transaction with itself to fail")
+	}
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestImportIsAtomic(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	// Inserting "BOOM" fails, the way a full disk would halfway through a file
+	_, err := db.Exec(`CREATE TRIGGER fail_import BEFORE INSERT ON transactions
+        WHEN NEW.description = 'BOOM' BEGIN SELECT RAISE(ABORT, 'disk full'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "broken.csv")
+	csvData := "Date,Description,Amount,Category\n2024-07-01,Bakery,-3.00,Brand New:Category\n2024-07-02,BOOM,-1.00,\n2024-07-03,Cinema,-12.00,\n"
+	if err := os.WriteFile(csvFile, []byte(csvData), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	qifFile := filepath.Join(dir, "broken.qif")
+	qifData := "!Type:Bank\nD07/01/2024\nT-3.00\nPBakery\n^\nD07/02/2024\nT-1.00\nPBOOM\n^\n"
+	if err := os.WriteFile(qifFile, []byte(qifData), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The streamed (CSV) and the parsed (QIF) paths both roll back completely
+	for _, file := range []string{csvFile, qifFile} {
+		_, err := RunCLI(t, "import", file)
+		if err == nil || !strings.Contains(err.Error(), "nothing was written") || !strings.Contains(err.Error(), "disk full") {
+			t.Errorf("expected %s to fail, got %v", filepath.Base(file), err)
+		}
+	}
+
+	var transactions, batches, categories int
+	db.QueryRow(`SELECT COUNT(*) FROM transactions`).Scan(&transactions)
+	db.QueryRow(`SELECT COUNT(*) FROM import_batches`).Scan(&batches)
+	db.QueryRow(`SELECT COUNT(*) FROM categories WHERE name = 'Brand New'`).Scan(&categories)
+	if transactions != 0 || batches != 0 || categories != 0 {
+		t.Errorf("expected nothing to be left, got %d transactions, %d batches, %d categories", transactions, batches, categories)
+	}
+
+	// Once the cause is gone, the same file imports completely
+	if _, err := db.Exec(`DROP TRIGGER fail_import`); err != nil {
+		t.Fatal(err)
+	}
+	out, err := RunCLI(t, "import", csvFile)
+	if err != nil || !strings.Contains(out, "3 imported") {
+		t.Errorf("expected the retry to import everything, got:\n%s (%v)", out, err)
+	}
+}
+
+func TestImportLargeFile(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	const rows = 20000
+	var b strings.Builder
+	b.WriteString("Date,Description,Amount,Category\n")
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&b, "%s,Payment %d,-%d.%02d,Shop %d\n", start.AddDate(0, 0, i%1500).Format("2006-01-02"), i, i%500, i%100, i%20)
+	}
+	// The last line repeats the first one
+	fmt.Fprintf(&b, "%s,Payment 0,-0.00,Shop 0\n", start.Format("2006-01-02"))
+
+	file := filepath.Join(t.TempDir(), "large.csv")
+	if err := os.WriteFile(file, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := RunCLI(t, "import", file)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, fmt.Sprintf("CSV Import complete. %d imported, 1 duplicates skipped, 0 errors.", rows)) {
+		t.Errorf("unexpected summary:\n%s", out)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE import_batch_id = 1`).Scan(&count); err != nil || count != rows {
+		t.Errorf("expected %d transactions, got %d (%v)", rows, count, err)
+	}
+	found, _ := models.FindTransactions(db, models.TransactionFilter{Query: fmt.Sprintf("Payment %d", rows-1)})
+	if len(found) != 1 || found[0].SourceLine != rows+1 || found[0].Category != "Shop 19" {
+		t.Errorf("unexpected last transaction %+v", found)
+	}
+}
+
+func TestDuplicateCheckUsesIndex(t *testing.T) {
+	db := NewTestDB(t)
+
+	rows, err := db.Query(`EXPLAIN QUERY PLAN
+        SELECT 1 FROM transactions WHERE account IS ? AND date = ? AND amount = ? AND description = ?`,
+		"Checking", "2024-01-01", 100, "Rent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatal(err)
+		}
+		plan = append(plan, detail)
+	}
+	if joined := strings.Join(plan, "\n"); !strings.Contains(joined, "idx_transactions_dedup") {
+		t.Errorf("expected the duplicate check to use idx_transactions_dedup, got:\n%s", joined)
+	}
+}
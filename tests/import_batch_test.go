@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestImportBatches(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	if err := models.CreateAccount(db, &models.Account{Name: "Checking", Currency: "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "may.csv")
+	data := "Date,Description,Amount\n2024-05-01,Rent,-800.00\nbad,Broken,1\n2024-05-03,Groceries,-54.20\n"
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := RunCLI(t, "import", file, "--account", "Checking")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "Recorded as import batch 1") {
+		t.Errorf("expected the batch to be announced, got:\n%s", out)
+	}
+
+	all, _ := models.ListTransactions(db)
+	for _, tr := range all {
+		if tr.BatchID != 1 {
+			t.Errorf("expected %q to belong to batch 1, got %d", tr.Description, tr.BatchID)
+		}
+		if tr.Description == "Groceries" && tr.SourceLine != 4 {
+			t.Errorf("expected Groceries to come from line 4, got %d", tr.SourceLine)
+		}
+	}
+
+	// The same bytes again are refused up front, even under another name
+	copyFile := filepath.Join(dir, "may-copy.csv")
+	if err := os.WriteFile(copyFile, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "import", copyFile, "--account", "Checking"); err == nil || !strings.Contains(err.Error(), "already imported as batch 1") {
+		t.Errorf("expected the identical file to be refused, got %v", err)
+	}
+	if out, err := RunCLI(t, "import", copyFile, "--dry-run"); err != nil || !strings.Contains(out, "already imported as batch 1") {
+		t.Errorf("expected the dry run to warn about the earlier import, got:\n%s (%v)", out, err)
+	}
+
+	// A manual transaction isn't part of any batch
+	if _, err := RunCLI(t, "add", "--amount", "-5", "--desc", "Kiosk", "--account", "Checking"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "import", copyFile, "--account", "Checking", "--force"); err != nil {
+		t.Fatalf("forced import failed: %v", err)
+	}
+
+	out, err = RunCLI(t, "import", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "may.csv") || !strings.Contains(out, "may-copy.csv") || !strings.Contains(out, "NEW") {
+		t.Errorf("unexpected import list:\n%s", out)
+	}
+
+	out, err = RunCLI(t, "import", "show", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Result:   2 imported, 0 duplicates skipped, 1 errors") || !strings.Contains(out, "Groceries") ||
+		strings.Contains(out, "Kiosk") {
+		t.Errorf("unexpected batch details:\n%s", out)
+	}
+	if out, _ := RunCLI(t, "import", "show", "2"); !strings.Contains(out, "0 imported, 2 duplicates skipped") || !strings.Contains(out, "No transactions left") {
+		t.Errorf("expected the forced import to have added nothing, got:\n%s", out)
+	}
+
+	// Tag and split an imported transaction; undo takes them along
+	rent, _ := models.FindTransactions(db, models.TransactionFilter{Query: "Rent"})
+	if len(rent) != 1 {
+		t.Fatalf("expected the rent, got %+v", rent)
+	}
+	if err := models.AddSplit(db, &models.Split{TransactionID: rent[0].ID, Amount: -100_00, Category: "Utilities"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err = RunCLI(t, "import", "undo", "1")
+	if err != nil || !strings.Contains(out, "Import batch 1 undone, 2 transaction(s) deleted.") {
+		t.Fatalf("unexpected undo result:\n%s (%v)", out, err)
+	}
+	all, _ = models.ListTransactions(db)
+	if len(all) != 1 || all[0].Description != "Kiosk" {
+		t.Errorf("expected only the manual transaction to be left, got %+v", all)
+	}
+	var splits int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transaction_splits`).Scan(&splits); err != nil || splits != 0 {
+		t.Errorf("expected the split lines to be deleted, got %d (%v)", splits, err)
+	}
+	if _, err := RunCLI(t, "import", "undo", "1"); err == nil {
+		t.Errorf("expected a second undo to fail")
+	}
+
+	// Once both imports of it are undone, the file can be imported again
+	if _, err := RunCLI(t, "import", file, "--account", "Checking"); err == nil || !strings.Contains(err.Error(), "batch 2") {
+		t.Errorf("expected the forced import to still count, got %v", err)
+	}
+	if _, err := RunCLI(t, "import", "undo", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := RunCLI(t, "import", file, "--account", "Checking"); err != nil || !strings.Contains(out, "2 imported") {
+		t.Errorf("expected the re-import after undo to succeed, got:\n%s (%v)", out, err)
+	}
+}
@@ -44,10 +44,10 @@ func TestImportDuplicates(t *testing.T) {
 		t.Fatalf("Expected 1 transaction after first import, got %d", len(txs))
 	}
 
-	// 4. Second Import (Should Skip)
+	// 4. Second Import (Should Skip); --force gets past the check for an identical file
 	out.Reset()
 	cli.RootCmd.SetOut(out)
-	cli.RootCmd.SetArgs([]string{"import", tmpfile.Name()})
+	cli.RootCmd.SetArgs([]string{"import", tmpfile.Name(), "--force"})
 	if err := cli.RootCmd.Execute(); err != nil {
 		t.Fatalf("Second import failed: %v", err)
 	}
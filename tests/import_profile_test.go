@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/importer"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+const sparkasseCSV = "\ufeff\"Auftragskonto\";\"Buchungstag\";\"Valutadatum\";\"Buchungstext\";\"Verwendungszweck\";\"Beguenstigter/Zahlungspflichtiger\";\"Betrag\";\"Waehrung\"\n" +
+	"\"DE0012345\";\"03.02.24\";\"03.02.24\";\"LASTSCHRIFT\";\"Rechnung 4711\";\"Stadtwerke\";\"-1.234,56\";\"EUR\"\n" +
+	"\"DE0012345\";\"05.02.24\";\"05.02.24\";\"GUTSCHRIFT\";\"Gehalt Februar\";\"Employer GmbH\";\"2.500,00\";\"EUR\"\n"
+
+const ingCSV = `Data,Detalii tranzactie,Debit,Credit
+"01.03.2024","Plata card Kaufland","123,45",""
+"02.03.2024","Incasare salariu","","4.000,00"
+"xx.03.2024","Broken row","1,00",""
+`
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		in, decimal string
+		want        models.Money
+	}{
+		{"1,234.56", ".", 1234_56},
+		{"-1.234,56", ",", -1234_56},
+		{"12,00-", ",", -12_00},
+		{"1 234,5", ",", 1234_50},
+		{"1 000.00", ".", 1000_00},
+		{"1'000.25", ".", 1000_25},
+		{"7", ".", 7_00},
+		{"1,234,567.89", ".", 1234567_89},
+		{"999,999", ".", 999999_00},
+		{"1.234.567,89", ",", 1234567_89},
+		{"15,99", ",", 15_99},
+		{"-0,5", ",", -50},
+		{"15.99", ".", 15_99},
+		{"- 3,00", ",", -3_00},
+	}
+	for _, c := range cases {
+		got, err := importer.ParseAmount(c.in, c.decimal)
+		if err != nil || got != c.want {
+			t.Errorf("ParseAmount(%q, %q) = %d, %v; want %d", c.in, c.decimal, got, err, c.want)
+		}
+	}
+	// A separator that isn't between groups of three digits is a mistake, not 100x the amount
+	for _, c := range []struct{ in, decimal string }{
+		{"abc", "."},
+		{"15,99", "."},
+		{"-12,5", "."},
+		{"1,2,3", "."},
+		{"1,234,56", "."},
+		{",123", "."},
+		{"1234,567.00", "."},
+		{"1,234.5.6", "."},
+		{"1.5", ","},
+		{"-12.5", ","},
+		{"1.2.3", ","},
+		{"1.234.5", ","},
+		{"1,234.56", ","},
+		{"1,2,3", ","},
+	} {
+		if got, err := importer.ParseAmount(c.in, c.decimal); err == nil {
+			t.Errorf("ParseAmount(%q, %q) = %s; want an invalid amount", c.in, c.decimal, got)
+		}
+	}
+}
+
+func TestParseCSVProfiles(t *testing.T) {
+	db := NewTestDB(t)
+
+	sparkasse, _ := models.GetImportProfile(db, "sparkasse")
+	records, err := importer.ParseCSV(strings.NewReader(sparkasseCSV), *sparkasse)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("expected 2 Sparkasse records, got %+v (%v)", records, err)
+	}
+	first := records[0]
+	if first.Err != nil || first.Amount != -1234_56 || first.Description != "Stadtwerke - Rechnung 4711" ||
+		first.Date.Format("2006-01-02") != "2024-02-03" || first.Currency != "EUR" || first.Line != 2 {
+		t.Errorf("unexpected Sparkasse record %+v", first)
+	}
+
+	ing, _ := models.GetImportProfile(db, "ing-ro")
+	records, err = importer.ParseCSV(strings.NewReader(ingCSV), *ing)
+	if err != nil || len(records) != 3 {
+		t.Fatalf("expected 3 ING records, got %+v (%v)", records, err)
+	}
+	if records[0].Amount != -123_45 || records[1].Amount != 4000_00 {
+		t.Errorf("expected debit and credit to become signed amounts, got %d and %d", records[0].Amount, records[1].Amount)
+	}
+	if records[2].Err == nil || records[2].Line != 4 {
+		t.Errorf("expected the broken row to carry an error and its line, got %+v", records[2])
+	}
+
+	// Credit card exports often list purchases as positive amounts
+	card := models.ImportProfile{Name: "card", Delimiter: ",", Header: true, Date: "Date", Description: []string{"Merchant"},
+		Amount: "Charge", DateLayout: "01/02/2006", Decimal: ".", Sign: models.SignInverted}
+	records, err = importer.ParseCSV(strings.NewReader("Date,Merchant,Charge\n03/04/2024,Bookshop,25.00\n03/05/2024,Refund,-5.00\n"), card)
+	if err != nil || len(records) != 2 || records[0].Amount != -25_00 || records[1].Amount != 5_00 {
+		t.Errorf("unexpected inverted amounts %+v (%v)", records, err)
+	}
+}
+
+func TestDetectProfile(t *testing.T) {
+	db := NewTestDB(t)
+	profiles, err := models.ListImportProfiles(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p, ok := importer.DetectProfile([]byte(sparkasseCSV), profiles); !ok || p.Name != "sparkasse" {
+		t.Errorf("expected the Sparkasse header to be recognized, got %+v", p)
+	}
+	if p, ok := importer.DetectProfile([]byte(ingCSV), profiles); !ok || p.Name != "ing-ro" {
+		t.Errorf("expected the ING header to be recognized, got %+v", p)
+	}
+	revolut := "Type,Product,Started Date,Completed Date,Description,Amount,Fee,Currency,State,Balance\n"
+	if p, ok := importer.DetectProfile([]byte(revolut), profiles); !ok || p.Name != "revolut" {
+		t.Errorf("expected the Revolut header to be recognized, got %+v", p)
+	}
+
+	sample, err := os.ReadFile(filepath.Join("..", "test.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := importer.DetectProfile(sample, profiles); ok {
+		t.Errorf("expected no profile for the generic sample, got %s", p.Name)
+	}
+	generic, _ := models.GetImportProfile(db, models.DefaultProfile)
+	records, err := importer.ParseCSV(strings.NewReader(string(sample)), *generic)
+	if err != nil || len(records) != 3 || records[0].Description != "Netflix" || records[0].Category != "Entertainment" {
+		t.Errorf("expected the generic layout to skip the header and read 3 rows, got %+v (%v)", records, err)
+	}
+}
+
+func TestProfileCommands(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	_, err := RunCLI(t, "profile", "add", "MyBank", "--delimiter", "tab", "--skip-rows", "1",
+		"--date-column", "Booked", "--description-column", "Payee", "--description-column", "Reference",
+		"--debit-column", "Out", "--credit-column", "In", "--date-format", "DD/MM/YYYY", "--decimal", ",")
+	if err != nil {
+		t.Fatalf("profile add failed: %v", err)
+	}
+
+	p, err := models.GetImportProfile(db, "mybank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Delimiter != "\t" || p.Sign != models.SignDebitCredit || p.DateLayout != "02/01/2006" || len(p.Description) != 2 {
+		t.Errorf("unexpected saved profile %+v", p)
+	}
+
+	out, _ := RunCLI(t, "profile", "list")
+	if !strings.Contains(out, "mybank") || !strings.Contains(out, "sparkasse") || !strings.Contains(out, "(built-in)") {
+		t.Errorf("expected built-in and own profiles in the list, got:\n%s", out)
+	}
+	out, _ = RunCLI(t, "profile", "show", "mybank")
+	if !strings.Contains(out, "Payee + Reference") || !strings.Contains(out, "tab") {
+		t.Errorf("unexpected profile details:\n%s", out)
+	}
+
+	if _, err := RunCLI(t, "profile", "add", "bad", "--date-column", "1", "--description-column", "2", "--sign", "debit-credit"); err == nil {
+		t.Errorf("expected a debit-credit profile without columns to be rejected")
+	}
+	if _, err := RunCLI(t, "profile", "add", "plus", "--date-column", "Date", "--description-column", "Payee+Memo", "--amount-column", "Amount"); err == nil {
+		t.Errorf("expected a description column with '+' to be rejected")
+	}
+	if _, err := RunCLI(t, "profile", "remove", "chase"); err == nil {
+		t.Errorf("expected built-in profiles to be protected")
+	}
+
+	// Import with the saved profile: a line of account details, then the header
+	file := filepath.Join(t.TempDir(), "mybank.csv")
+	data := "Account DE0012345\nBooked\tPayee\tReference\tOut\tIn\n" +
+		"01/03/2024\tKaufland\tCard 1234\t45,10\t\n" +
+		"02/03/2024\tEmployer\tSalary\t\t3.000,00\n"
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = RunCLI(t, "import", file, "--profile", "mybank")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "2 imported") {
+		t.Errorf("unexpected import summary:\n%s", out)
+	}
+	found, _ := models.FindTransactions(db, models.TransactionFilter{Query: "Kaufland"})
+	if len(found) != 1 || found[0].Amount != -45_10 || found[0].Description != "Kaufland - Card 1234" {
+		t.Errorf("unexpected imported transaction %+v", found)
+	}
+
+	if _, err := RunCLI(t, "profile", "remove", "mybank"); err != nil {
+		t.Errorf("profile remove failed: %v", err)
+	}
+	if _, err := RunCLI(t, "import", file, "--profile", "mybank"); err == nil {
+		t.Errorf("expected an unknown profile to be rejected")
+	}
+}
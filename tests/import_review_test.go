@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/importer"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+const reviewCSV = `Date,Description,Amount
+2024-06-01,Coffee Shop,-3.50
+2024-06-02,Bookstore,-12.00
+2024-06-02,Bookstore,-12.00
+not a date,Broken,-1.00
+2024-06-03,Salary,2000.00
+`
+
+func TestImportDryRun(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	if _, err := RunCLI(t, "add", "--amount", "-3.50", "--desc", "Coffee Shop", "--date", "2024-06-01"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "rules", "add", "--pattern", "(?i)salary", "--category", "Income"); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "june.csv")
+	if err := os.WriteFile(file, []byte(reviewCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := RunCLI(t, "import", file, "--dry-run")
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	for _, want := range []string{
+		"STATUS", "REASON",
+		"same date, description and amount already booked",
+		"repeats an earlier line of the file",
+		"line 5: ",
+		"CSV dry run: 2 would be imported, 2 duplicates, 1 errors. Nothing was written.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the dry run, got:\n%s", want, out)
+		}
+	}
+	var salaryLine string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "Salary") {
+			salaryLine = line
+		}
+	}
+	if !strings.HasPrefix(salaryLine, "import") || !strings.Contains(salaryLine, "Income") {
+		t.Errorf("expected the salary to be imported as Income, got %q", salaryLine)
+	}
+
+	all, _ := models.ListTransactions(db)
+	if len(all) != 1 {
+		t.Errorf("expected the dry run to write nothing, found %d transactions", len(all))
+	}
+
+	// The real import does what the dry run announced
+	out, err = RunCLI(t, "import", file)
+	if err != nil || !strings.Contains(out, "CSV Import complete. 2 imported, 2 duplicates skipped, 1 errors.") {
+		t.Errorf("unexpected import output:\n%s (%v)", out, err)
+	}
+
+	if _, err := RunCLI(t, "import", file, "--dry-run", "--review"); err == nil {
+		t.Errorf("expected --dry-run and --review to be rejected together")
+	}
+}
+
+// TestPipelineReview changes the checked outcomes the way the review screen does.
+func TestPipelineReview(t *testing.T) {
+	db := NewTestDB(t)
+
+	statements, err := importer.CSV{}.Parse(strings.NewReader(reviewCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline := &importer.Pipeline{DB: db}
+	outcomes, err := pipeline.Check(statements[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []importer.Status
+	for _, o := range outcomes {
+		statuses = append(statuses, o.Status)
+	}
+	want := []importer.Status{importer.StatusNew, importer.StatusNew, importer.StatusDuplicate, importer.StatusInvalid, importer.StatusNew}
+	if len(statuses) != len(want) {
+		t.Fatalf("expected statuses %v, got %v", want, statuses)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("expected statuses %v, got %v", want, statuses)
+		}
+	}
+
+	// Reject the coffee, recategorize the salary, accept the repeated bookstore line
+	outcomes[0].Status = importer.StatusRejected
+	outcomes[4].Transaction.Category = models.NormalizeCategory("income")
+	outcomes[2].Status = importer.StatusNew
+	pipeline.Commit(outcomes)
+
+	if outcomes[0].Status != importer.StatusRejected || outcomes[2].Status != importer.StatusImported || outcomes[4].Status != importer.StatusImported {
+		t.Errorf("unexpected statuses after commit: %+v", outcomes)
+	}
+	all, _ := models.ListTransactions(db)
+	if len(all) != 3 {
+		t.Fatalf("expected 3 transactions, got %+v", all)
+	}
+	for _, tr := range all {
+		if tr.Description == "Coffee Shop" {
+			t.Errorf("expected the rejected record to be left out")
+		}
+		if tr.Description == "Salary" && tr.Category != "Income" {
+			t.Errorf("expected the new category to be stored, got %q", tr.Category)
+		}
+	}
+}
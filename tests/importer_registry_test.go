@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/importer"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// pipeImporter reads a made-up format of "date|description|amount" lines after a "#PIPE" marker.
+type pipeImporter struct{}
+
+func (pipeImporter) Name() string            { return "pipe" }
+func (pipeImporter) Extensions() []string    { return []string{".pipe"} }
+func (pipeImporter) Detect(head []byte) bool { return strings.HasPrefix(string(head), "#PIPE") }
+
+func (pipeImporter) Parse(r io.Reader) ([]importer.Statement, error) {
+	var s importer.Statement
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rec := importer.Record{Line: n}
+		parts := strings.Split(line, "|")
+		if len(parts) != 3 {
+			rec.Err = fmt.Errorf("expected 3 fields, got %d", len(parts))
+			s.Records = append(s.Records, rec)
+			continue
+		}
+		rec.Date, rec.Err = time.Parse("2006-01-02", parts[0])
+		rec.Description = parts[1]
+		if rec.Err == nil {
+			rec.Amount, rec.Err = importer.ParseAmount(parts[2], ".")
+		}
+		s.Records = append(s.Records, rec)
+	}
+	return []importer.Statement{s}, scanner.Err()
+}
+
+func init() {
+	importer.Register(pipeImporter{})
+}
+
+func TestImporterRegistry(t *testing.T) {
+	if imp, err := importer.Lookup("MT940"); err != nil || imp.Name() != importer.FormatMT940 {
+		t.Errorf("expected Lookup to ignore case, got %v (%v)", imp, err)
+	}
+	if _, err := importer.Lookup("xls"); err == nil || !strings.Contains(err.Error(), "pipe") {
+		t.Errorf("expected an unknown format to list the registered ones, got %v", err)
+	}
+
+	// Content wins over the extension; CSV is only chosen by extension
+	cases := map[string]string{
+		"statement.csv":  importer.FormatCSV,
+		"statement.TXT":  importer.FormatCSV,
+		"statement.qfx":  importer.FormatOFX,
+		"statement.pipe": "pipe",
+	}
+	for file, want := range cases {
+		if imp, err := importer.Detect(file, []byte("Date,Amount\n")); err != nil || imp.Name() != want {
+			t.Errorf("Detect(%q) = %v (%v), want %q", file, imp, err, want)
+		}
+	}
+	if imp, err := importer.Detect("export.csv", []byte("\ufeff#PIPE\n")); err != nil || imp.Name() != "pipe" {
+		t.Errorf("expected the marker to win over the extension, got %v (%v)", imp, err)
+	}
+	if _, err := importer.Detect("report.pdf", []byte("%PDF-1.7")); err == nil {
+		t.Errorf("expected an unknown file to be rejected")
+	}
+}
+
+func TestImportRegisteredFormat(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	if err := models.CreateAccount(db, &models.Account{Name: "Wallet", Currency: "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "rules", "add", "--pattern", "(?i)bakery", "--category", "Food"); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "wallet.dat")
+	data := "#PIPE\n2024-05-01|Corner Bakery|-4.20\n2024-05-02|Pocket money|20.00\nbroken line\n"
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := RunCLI(t, "import", file, "--account", "Wallet")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "PIPE Import complete. 2 imported, 0 duplicates skipped, 1 errors.") || !strings.Contains(out, "Line 4 skipped") {
+		t.Errorf("unexpected import output:\n%s", out)
+	}
+
+	found, _ := models.FindTransactions(db, models.TransactionFilter{Query: "Bakery"})
+	if len(found) != 1 || found[0].Category != "Food" || found[0].Account != "Wallet" || found[0].Currency != "EUR" {
+		t.Errorf("expected the shared pipeline to categorize and file the record, got %+v", found)
+	}
+
+	out, err = RunCLI(t, "import", file, "--account", "Wallet", "--force")
+	if err != nil || !strings.Contains(out, "0 imported, 2 duplicates skipped") {
+		t.Errorf("expected a re-import to add nothing, got:\n%s (%v)", out, err)
+	}
+
+	// --format overrides detection
+	csvFile := filepath.Join(t.TempDir(), "wallet.dat")
+	if err := os.WriteFile(csvFile, []byte("Date,Description,Amount\n2024-05-03,Kiosk,-2.00\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "import", csvFile, "--account", "Wallet"); err == nil {
+		t.Errorf("expected an unmarked .dat file to be rejected")
+	}
+	out, err = RunCLI(t, "import", csvFile, "--account", "Wallet", "--format", "csv")
+	if err != nil || !strings.Contains(out, "CSV Import complete. 1 imported") {
+		t.Errorf("expected --format csv to read the file, got:\n%s (%v)", out, err)
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/db"
+	_ "modernc.org/sqlite"
+)
+
+func TestMigrationsRunOnce(t *testing.T) {
+	database := NewTestDB(t)
+
+	// NewTestDB already migrated; nothing may run a second time
+	applied, err := db.Migrate(database)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected no pending migrations, got %v", applied)
+	}
+
+	states, err := db.Status(database)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(states) == 0 {
+		t.Fatal("expected known migrations")
+	}
+	for i, s := range states {
+		if !s.Applied {
+			t.Errorf("migration %s not recorded as applied", s.Version)
+		}
+		if i > 0 && states[i-1].Version >= s.Version {
+			t.Errorf("migrations out of order: %s before %s", states[i-1].Version, s.Version)
+		}
+	}
+}
+
+func TestDBStatusAndMigrateCommands(t *testing.T) {
+	database, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	cli.SetDatabase(database)
+
+	out, err := RunCLI(t, "db", "status")
+	if err != nil {
+		t.Fatalf("db status failed: %v", err)
+	}
+	if !strings.Contains(out, "001_init") || !strings.Contains(out, "pending") {
+		t.Errorf("expected pending migrations on an empty database, got:\n%s", out)
+	}
+
+	out, err = RunCLI(t, "db", "migrate")
+	if err != nil {
+		t.Fatalf("db migrate failed: %v", err)
+	}
+	if !strings.Contains(out, "Applied 001_init") {
+		t.Errorf("expected 001_init to be applied, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "db", "migrate")
+	if !strings.Contains(out, "Database is up to date.") {
+		t.Errorf("expected nothing left to apply, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "db", "status")
+	if !strings.Contains(out, "0 pending migration(s).") {
+		t.Errorf("expected no pending migrations, got:\n%s", out)
+	}
+}
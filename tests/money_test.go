@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/db"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected models.Money
+		wantErr  bool
+	}{
+		{input: "15.99", expected: 1599},
+		{input: "-5", expected: -500},
+		{input: "+1200.5", expected: 120050},
+		{input: " 0.07 ", expected: 7},
+		{input: ".5", expected: 50},
+		{input: "-0.10", expected: -10},
+		{input: "3.1400", expected: 314},
+		{input: "3.145", wantErr: true},
+		{input: "12,50", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "-", wantErr: true},
+		{input: "abc", wantErr: true},
+		{input: "92233720368547758.07", expected: math.MaxInt64},
+		{input: "-92233720368547758.07", expected: -math.MaxInt64},
+		{input: "92233720368547758.08", wantErr: true},
+		{input: "999999999999999999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := models.ParseMoney(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) expected error, got %s", tt.input, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("ParseMoney(%q) = %d, %v; want %d", tt.input, got, err, tt.expected)
+		}
+	}
+
+	if s := models.Money(-1599).String(); s != "-15.99" {
+		t.Errorf("expected -15.99, got %s", s)
+	}
+	if s := models.Money(5).String(); s != "0.05" {
+		t.Errorf("expected 0.05, got %s", s)
+	}
+}
+
+func TestMoneySumsDoNotDrift(t *testing.T) {
+	database := NewTestDB(t)
+
+	// 0.1 + 0.2 style sums drift with float64; with minor units they must be exact.
+	for i := 0; i < 1000; i++ {
+		tr := &models.Transaction{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Description: "Coffee", Amount: -10, Category: "Food"}
+		if err := models.CreateTransaction(database, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, _, expense, err := models.GetMonthlyReport(database, 2024, 3, models.TransactionFilter{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expense != -100_00 {
+		t.Errorf("expected exactly -100.00, got %s", expense)
+	}
+}
+
+func TestLegacyRealAmountsAreConverted(t *testing.T) {
+	database, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+
+	// Schema and data as written by versions that stored amounts as floats
+	legacy := []string{
+		`CREATE TABLE transactions (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT NOT NULL, description TEXT,
+			amount REAL NOT NULL, category TEXT, account TEXT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE budgets (id INTEGER PRIMARY KEY AUTOINCREMENT, category TEXT NOT NULL, amount REAL NOT NULL, period TEXT NOT NULL)`,
+		`INSERT INTO transactions (date, description, amount, category) VALUES ('2024-02-01', 'Netflix', -15.99, 'Entertainment')`,
+		`INSERT INTO transactions (date, description, amount, category) VALUES ('2024-02-02', 'Freelance', 500.1, 'Income')`,
+		`INSERT INTO budgets (category, amount, period) VALUES ('Food', 299.99, 'monthly')`,
+	}
+	for _, stmt := range legacy {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("legacy setup failed: %v", err)
+		}
+	}
+
+	if _, err := db.Migrate(database); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	tr, err := models.GetTransaction(database, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Amount != -15_99 {
+		t.Errorf("expected -15.99, got %s", tr.Amount)
+	}
+	tr, _ = models.GetTransaction(database, 2)
+	if tr.Amount != 500_10 {
+		t.Errorf("expected 500.10, got %s", tr.Amount)
+	}
+	b, err := models.GetBudget(database, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Amount != 299_99 {
+		t.Errorf("expected budget 299.99, got %s", b.Amount)
+	}
+
+	var colType string
+	database.QueryRow(`SELECT type FROM pragma_table_info('transactions') WHERE name = 'amount'`).Scan(&colType)
+	if colType != "INTEGER" {
+		t.Errorf("expected amount column to be INTEGER, got %s", colType)
+	}
+}
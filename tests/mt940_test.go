@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/importer"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// sampleMT940 holds two statements. The first has a SWIFT header, a structured German
+// narrative, a booking in the new year with a value date in the old one, a multi-line
+// narrative and a reversed debit. The second doesn't add up.
+const sampleMT940 = `{1:F01BANKDEFFXXXX0000000000}{2:O9400000000000BANKDEFFXXXX00000000000000000000N}{4:
+:20:STARTUMS
+:25:10020030/1234567890
+:28C:1/1
+:60F:C231229EUR1000,00
+:61:2312290102DR42,50NMSCNONREF//BANK-1
+:86:106?00KARTENZAHLUNG?20Supermarkt Filiale 12?21 Rechnung 47?2211?31DE0212030000000
+0202051?32SUPERMARKT GMBH
+:61:240102C2500,00NTRFPAYROLL-1
+:86:Salary January
+Employer AG
+:61:240103RD10,00NMSCNONREF//BANK-3
+:86:Refund of fee
+:62F:C240103EUR3467,50
+-}
+:20:STARTUMS
+:25:10020030/1234567890
+:28C:2/1
+:60F:C240103EUR3467,50
+:61:240104D100,00NDDTNONREF
+Rent
+:62F:C240104EUR3300,00
+:86:Statement 2 of 2
+-
+`
+
+func TestParseMT940(t *testing.T) {
+	statements, err := importer.ParseMT940(strings.NewReader(sampleMT940))
+	if err != nil {
+		t.Fatalf("ParseMT940 failed: %v", err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(statements))
+	}
+
+	first := statements[0]
+	if first.AccountID != "10020030/1234567890" || first.Currency != "EUR" || first.Opening == nil || first.Opening.Amount != 1000_00 ||
+		first.Balance == nil || first.Balance.Amount != 3467_50 || first.Balance.Date.Format("2006-01-02") != "2024-01-03" {
+		t.Errorf("unexpected statement header %+v", first)
+	}
+	if len(first.Records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", first.Records)
+	}
+
+	card := first.Records[0]
+	if card.Amount != -42_50 || card.ExternalID != "BANK-1" || card.Date.Format("2006-01-02") != "2024-01-02" ||
+		card.ValueDate.Format("2006-01-02") != "2023-12-29" {
+		t.Errorf("unexpected card payment %+v", card)
+	}
+	if card.Description != "SUPERMARKT GMBH - Supermarkt Filiale 12 Rechnung 4711" || card.CounterpartyIBAN != "DE02120300000000202051" {
+		t.Errorf("unexpected structured narrative: %q, IBAN %q", card.Description, card.CounterpartyIBAN)
+	}
+	if salary := first.Records[1]; salary.Amount != 2500_00 || salary.ExternalID != "PAYROLL-1" || salary.Description != "Salary January Employer AG" {
+		t.Errorf("unexpected salary %+v", salary)
+	}
+	if refund := first.Records[2]; refund.Amount != 10_00 || refund.Description != "Refund of fee" {
+		t.Errorf("expected the reversed debit to be money in, got %+v", refund)
+	}
+	if diff, ok := first.Difference(); !ok || diff != 0 {
+		t.Errorf("expected the first statement to add up, got %s (%v)", diff, ok)
+	}
+
+	second := statements[1]
+	if len(second.Records) != 1 || second.Records[0].Description != "Rent" || second.Records[0].ExternalID != "" {
+		t.Errorf("expected the supplementary details to describe the rent, got %+v", second.Records)
+	}
+	if diff, ok := second.Difference(); !ok || diff != -67_50 {
+		t.Errorf("expected the second statement to be 67.50 short, got %s (%v)", diff, ok)
+	}
+
+	if _, err := importer.ParseMT940(strings.NewReader(":25:123\n:61:240104D1,00NDDTNONREF\n")); err == nil {
+		t.Errorf("expected fields before :20: to be rejected")
+	}
+	for _, data := range []string{sampleMT940, ":20:X\n:25:1\n"} {
+		if imp, err := importer.Detect("statement.txt", []byte(data)); err != nil || imp.Name() != importer.FormatMT940 {
+			t.Errorf("expected MT940 to be recognized in %.30q, got %v (%v)", data, imp, err)
+		}
+	}
+}
+
+func TestImportMT940(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	if err := models.CreateAccount(db, &models.Account{Name: "Business", Currency: "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "add", "--amount", "1000", "--desc", "Opening balance", "--account", "Business", "--date", "2023-12-28"); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "statement.sta")
+	if err := os.WriteFile(file, []byte(sampleMT940), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := RunCLI(t, "import", file, "--account", "Business")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "MT940 Import complete. 4 imported, 0 duplicates skipped, 0 errors.") {
+		t.Errorf("unexpected import summary:\n%s", out)
+	}
+	// Only the second statement is off, against itself and against the account
+	if strings.Count(out, "doesn't give the closing balance") != 1 || !strings.Contains(out, "(difference -67.50 EUR)") {
+		t.Errorf("expected one reconciliation warning, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Bank balance on 2024-01-03: 3467.50 EUR") || strings.Count(out, "Transactions may be missing") != 1 {
+		t.Errorf("expected the first closing balance to match the account, got:\n%s", out)
+	}
+
+	out, err = RunCLI(t, "import", file, "--account", "Business", "--force")
+	if err != nil || !strings.Contains(out, "0 imported, 4 duplicates skipped") {
+		t.Errorf("expected a re-import to add nothing, got:\n%s (%v)", out, err)
+	}
+}
@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/importer"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// sgmlOFX is an OFX 1.02 export: values have no closing tags, and it holds a bank
+// statement and a credit card statement.
+const sgmlOFX = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20240301120000<LANGUAGE>ENG</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<TRNUID>1
+<STMTRS>
+<CURDEF>EUR
+<BANKACCTFROM>
+<BANKID>12345678
+<ACCTID>DE0012345
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240201
+<DTEND>20240229
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240203120000.000[-5:EST]
+<TRNAMT>-4.50
+<FITID>2024020301
+<NAME>Coffee &amp; Co
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240203
+<TRNAMT>-4.50
+<FITID>2024020302
+<NAME>Coffee &amp; Co
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240205
+<TRNAMT>1500.00
+<FITID>2024020501
+<NAME>Employer Inc
+<MEMO>Salary
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>1491.00
+<DTASOF>20240229
+</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+<CREDITCARDMSGSRSV1>
+<CCSTMTTRNRS>
+<TRNUID>2
+<CCSTMTRS>
+<CURDEF>USD
+<CCACCTFROM>
+<ACCTID>4111XXXX1111
+</CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240210
+<TRNAMT>-25.00
+<FITID>CC-1
+<NAME>Bookshop
+</STMTTRN>
+</BANKTRANLIST>
+</CCSTMTRS>
+</CCSTMTTRNRS>
+</CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	statements, err := importer.ParseOFX(strings.NewReader(sgmlOFX))
+	if err != nil {
+		t.Fatalf("ParseOFX failed: %v", err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("expected a bank and a credit card statement, got %d", len(statements))
+	}
+
+	bank, card := statements[0], statements[1]
+	if bank.AccountID != "DE0012345" || bank.AccountType != "CHECKING" || bank.Currency != "EUR" {
+		t.Errorf("unexpected bank statement header %+v", bank)
+	}
+	if bank.Balance == nil || bank.Balance.Amount != 1491_00 || bank.Balance.Date.Format("2006-01-02") != "2024-02-29" {
+		t.Errorf("unexpected ledger balance %+v", bank.Balance)
+	}
+	if len(bank.Records) != 3 {
+		t.Fatalf("expected 3 bank records, got %d", len(bank.Records))
+	}
+	first := bank.Records[0]
+	if first.Description != "Coffee & Co" || first.Amount != -4_50 || first.ExternalID != "2024020301" || first.Date.Format("2006-01-02") != "2024-02-03" {
+		t.Errorf("unexpected record %+v", first)
+	}
+	if bank.Records[2].Description != "Employer Inc - Salary" {
+		t.Errorf("expected the memo to be appended, got %q", bank.Records[2].Description)
+	}
+
+	if card.AccountType != "CREDITCARD" || card.Currency != "USD" || len(card.Records) != 1 || card.Records[0].ExternalID != "CC-1" {
+		t.Errorf("unexpected credit card statement %+v", card)
+	}
+
+	// The XML variant and the sample file in the repository read the same way
+	xmlOFX := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS><CURDEF>GBP</CURDEF>
+<CCACCTFROM><ACCTID>9999</ACCTID></CCACCTFROM>
+<BANKTRANLIST><STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240301</DTPOSTED><TRNAMT>-9.99</TRNAMT>
+<FITID>X1</FITID><NAME>Streaming</NAME><MEMO></MEMO></STMTTRN></BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`
+	statements, err = importer.ParseOFX(strings.NewReader(xmlOFX))
+	if err != nil || len(statements) != 1 || len(statements[0].Records) != 1 || statements[0].Records[0].Description != "Streaming" {
+		t.Errorf("unexpected XML result %+v (%v)", statements, err)
+	}
+
+	// An empty SGML leaf has neither a value nor a closing tag; it doesn't swallow what follows
+	emptyMemo := `<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>EUR
+<BANKACCTFROM><ACCTID>1
+</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240301
+<TRNAMT>-7.00
+<MEMO>
+<FITID>M1
+<NAME>Bakery
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240302
+<TRNAMT>-3.00
+<FITID>M2
+<NAME>Kiosk
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>90.00<DTASOF>20240302</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+	statements, err = importer.ParseOFX(strings.NewReader(emptyMemo))
+	if err != nil || len(statements) != 1 || len(statements[0].Records) != 2 {
+		t.Fatalf("expected both records after an empty <MEMO>, got %+v (%v)", statements, err)
+	}
+	if rec := statements[0].Records[0]; rec.ExternalID != "M1" || rec.Description != "Bakery" || rec.Amount != -7_00 {
+		t.Errorf("expected the fields after the empty <MEMO> to belong to the record, got %+v", rec)
+	}
+	if b := statements[0].Balance; b == nil || b.Amount != 90_00 {
+		t.Errorf("expected the balance after the list, got %+v", b)
+	}
+
+	sample, err := os.Open(filepath.Join("..", "test.ofx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sample.Close()
+	if statements, err = importer.ParseOFX(sample); err != nil || len(statements[0].Records) != 2 {
+		t.Errorf("expected the sample file to yield 2 records, got %+v (%v)", statements, err)
+	}
+
+	if _, err := importer.ParseOFX(strings.NewReader("Date,Description,Amount\n")); err == nil {
+		t.Errorf("expected a file without statements to be rejected")
+	}
+}
+
+func TestImportOFXWithFITID(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	if err := models.CreateAccount(db, &models.Account{Name: "Giro", Currency: "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+	// A salary entered by hand before the first import
+	if _, err := RunCLI(t, "add", "--amount", "1500", "--desc", "Employer Inc - Salary", "--account", "Giro", "--date", "2024-02-05"); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "statement.ofx")
+	if err := os.WriteFile(file, []byte(sgmlOFX), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := RunCLI(t, "import", file, "--account", "Giro")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	// Both coffees have their own FITID; the salary matches the manual entry
+	if !strings.Contains(out, "3 imported, 1 duplicates skipped") {
+		t.Errorf("unexpected import summary:\n%s", out)
+	}
+	if !strings.Contains(out, "Statement for account DE0012345") || !strings.Contains(out, "Bank balance on 2024-02-29: 1491.00 EUR") {
+		t.Errorf("expected the statement header and balance, got:\n%s", out)
+	}
+	if strings.Contains(out, "difference") {
+		t.Errorf("expected the balance to match, got:\n%s", out)
+	}
+
+	out, err = RunCLI(t, "import", file, "--account", "Giro", "--force")
+	if err != nil {
+		t.Fatalf("second import failed: %v", err)
+	}
+	if !strings.Contains(out, "0 imported, 4 duplicates skipped") {
+		t.Errorf("expected the re-import to be recognized by FITID, got:\n%s", out)
+	}
+
+	found, _ := models.FindTransactions(db, models.TransactionFilter{Query: "Bookshop"})
+	if len(found) != 1 || found[0].ExternalID != "CC-1" || found[0].Currency != "USD" {
+		t.Errorf("expected the card transaction with its FITID and currency, got %+v", found)
+	}
+}
+
+func TestFITIDsAreUniquePerBankAccount(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	// Another bank numbers its transactions the same way
+	other := strings.NewReplacer("12345678", "87654321", "DE0012345", "DE0099999", "4111XXXX1111", "5500XXXX0004",
+		"Coffee &amp; Co", "Bakery", "Employer Inc", "Landlord", "Bookshop", "Cinema").Replace(sgmlOFX)
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.ofx"), filepath.Join(dir, "second.ofx")
+	if err := os.WriteFile(first, []byte(sgmlOFX), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte(other), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without --account both files book into the same (no) account
+	if out, err := RunCLI(t, "import", first); err != nil || !strings.Contains(out, "4 imported") {
+		t.Fatalf("first import failed: %v\n%s", err, out)
+	}
+	out, err := RunCLI(t, "import", second)
+	if err != nil || !strings.Contains(out, "4 imported, 0 duplicates skipped") {
+		t.Errorf("expected the other bank's FITIDs not to match, got:\n%s (%v)", out, err)
+	}
+	out, err = RunCLI(t, "import", second, "--force")
+	if err != nil || !strings.Contains(out, "0 imported, 4 duplicates skipped") {
+		t.Errorf("expected the re-import to be recognized by FITID, got:\n%s (%v)", out, err)
+	}
+
+	found, _ := models.FindTransactions(db, models.TransactionFilter{Query: "Cinema"})
+	if len(found) != 1 || found[0].ExternalID != "CC-1" || found[0].ExternalAccount != "5500XXXX0004" {
+		t.Errorf("expected the FITID with the card's account, got %+v", found)
+	}
+}
+
+func TestBalanceCheckOnlyComparesOwnStatements(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+	if err := models.CreateAccount(db, &models.Account{Name: "Giro", Currency: "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two EUR accounts with a closing balance each
+	twoAccounts := strings.NewReplacer("<CURDEF>USD", "<CURDEF>EUR",
+		"</BANKTRANLIST>\n</CCSTMTRS>", "</BANKTRANLIST>\n<LEDGERBAL>\n<BALAMT>-25.00\n<DTASOF>20240229\n</LEDGERBAL>\n</CCSTMTRS>").Replace(sgmlOFX)
+	file := filepath.Join(t.TempDir(), "two.ofx")
+	if err := os.WriteFile(file, []byte(twoAccounts), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both went into Giro, whose balance is neither bank's
+	out, err := RunCLI(t, "import", file, "--account", "Giro")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if strings.Count(out, "Not compared with Giro") != 2 || strings.Contains(out, "Transactions may be missing") {
+		t.Errorf("expected neither balance to be compared with Giro, got:\n%s", out)
+	}
+
+	// With an account of the card's name, each statement has its own account
+	if err := models.CreateAccount(db, &models.Account{Name: "4111XXXX1111", Type: "credit", Currency: "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "import", "undo", "1"); err != nil {
+		t.Fatal(err)
+	}
+	out, err = RunCLI(t, "import", file, "--account", "Giro")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if strings.Contains(out, "Not compared") || strings.Contains(out, "Transactions may be missing") {
+		t.Errorf("expected both balances to match their accounts, got:\n%s", out)
+	}
+	if found, _ := models.FindTransactions(db, models.TransactionFilter{Query: "Bookshop"}); len(found) != 1 || found[0].Account != "4111XXXX1111" {
+		t.Errorf("expected the card payment in the card's account, got %+v", found)
+	}
+}
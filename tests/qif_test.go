@@ -0,0 +1,332 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/importer"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// sampleQIF has a bank account with a split purchase and a transfer, a credit card
+// section and an investment section that is skipped.
+const sampleQIF = `!Option:AutoSwitch
+!Account
+NChecking
+TBank
+^
+!Clear:AutoSwitch
+!Account
+NChecking
+TBank
+^
+!Type:Bank
+D01/15/2024
+T-1,234.56
+PLandlord
+MJanuary rent
+LHousing:Rent/Home
+^
+D1/20'24
+T-100.00
+PSupermarket
+L--Split--
+SFood:Groceries
+$-70.00
+SHousehold
+EDetergent
+$-30.00
+^
+D01/25/24
+T-500.00
+PCard payment
+L[Visa]
+^
+D13/45/2024
+T-1.00
+PBroken
+^
+!Account
+NVisa
+TCCard
+^
+!Type:CCard
+D15.02.2024
+T-25.00
+PBookshop
+!Type:Invst
+D02/01/2024
+NBuy
+YACME
+^
+`
+
+func TestParseQIF(t *testing.T) {
+	statements, err := importer.ParseQIF(strings.NewReader(sampleQIF))
+	if err != nil {
+		t.Fatalf("ParseQIF failed: %v", err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("expected a bank and a credit card statement, got %d", len(statements))
+	}
+
+	bank, card := statements[0], statements[1]
+	if bank.AccountID != "Checking" || bank.AccountType != "BANK" || len(bank.Records) != 4 {
+		t.Fatalf("unexpected bank statement %+v", bank)
+	}
+	rent := bank.Records[0]
+	if rent.Amount != -1234_56 || rent.Description != "Landlord - January rent" || rent.Category != "Housing:Rent" ||
+		rent.Date.Format("2006-01-02") != "2024-01-15" {
+		t.Errorf("unexpected record %+v", rent)
+	}
+
+	shop := bank.Records[1]
+	if shop.Date.Format("2006-01-02") != "2024-01-20" || shop.Category != "" || len(shop.Splits) != 2 {
+		t.Fatalf("unexpected split record %+v", shop)
+	}
+	if shop.Splits[1].Category != "Household" || shop.Splits[1].Amount != -30_00 || shop.Splits[1].Memo != "Detergent" {
+		t.Errorf("unexpected split line %+v", shop.Splits[1])
+	}
+
+	if transfer := bank.Records[2]; transfer.Transfer != "Visa" || transfer.Category != "" || transfer.Date.Year() != 2024 {
+		t.Errorf("expected a transfer to Visa, got %+v", transfer)
+	}
+	if broken := bank.Records[3]; broken.Err == nil || broken.Line == 0 {
+		t.Errorf("expected the invalid date to be reported with its line, got %+v", broken)
+	}
+
+	// The last entry lacks its "^"; the investment section is skipped
+	if card.AccountID != "Visa" || card.AccountType != "CREDITCARD" || len(card.Records) != 1 ||
+		card.Records[0].Date.Format("2006-01-02") != "2024-02-15" {
+		t.Errorf("unexpected credit card statement %+v", card)
+	}
+
+	if _, err := importer.ParseQIF(strings.NewReader("Date,Description,Amount\n")); err == nil {
+		t.Errorf("expected a file without QIF sections to be rejected")
+	}
+}
+
+func TestImportExportQIF(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	if err := models.CreateAccount(db, &models.Account{Name: "Checking", Currency: "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "checking.qif")
+	if err := os.WriteFile(file, []byte(sampleQIF), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := RunCLI(t, "import", file, "--account", "Checking")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "QIF Import complete. 4 imported, 0 duplicates skipped, 1 errors.") || !strings.Contains(out, "Line ") {
+		t.Errorf("unexpected import summary:\n%s", out)
+	}
+
+	found, _ := models.FindTransactions(db, models.TransactionFilter{Query: "Supermarket"})
+	if len(found) != 1 {
+		t.Fatalf("expected the split purchase, got %+v", found)
+	}
+	splits, _ := models.ListSplits(db, found[0].ID)
+	if len(splits) != 2 || splits[0].Category != "Food:Groceries" || splits[1].Memo != "Detergent" {
+		t.Errorf("expected both split lines, got %+v", splits)
+	}
+	if found, _ := models.FindTransactions(db, models.TransactionFilter{Query: "Landlord"}); len(found) != 1 || found[0].Category != "Housing:Rent" {
+		t.Errorf("expected the QIF category to be kept, got %+v", found)
+	}
+
+	if out, _ := RunCLI(t, "import", file, "--account", "Checking", "--force"); !strings.Contains(out, "0 imported, 4 duplicates skipped") {
+		t.Errorf("expected a re-import to add nothing, got:\n%s", out)
+	}
+
+	// Export and read the file back
+	exported, err := RunCLI(t, "export", "--format", "qif", "--account", "Checking")
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	for _, want := range []string{"!Account\nNChecking\nTBank\n^\n!Type:Bank\n", "D01/15/2024\nT-1234.56\nPLandlord - January rent\nLHousing:Rent\n^",
+		"SFood:Groceries\n$-70.00\nSHousehold\nEDetergent\n$-30.00\n"} {
+		if !strings.Contains(exported, want) {
+			t.Errorf("expected %q in the export:\n%s", want, exported)
+		}
+	}
+
+	statements, err := importer.ParseQIF(bytes.NewBufferString(exported))
+	if err != nil || len(statements) != 1 || len(statements[0].Records) != 4 {
+		t.Fatalf("expected the export to read back, got %+v (%v)", statements, err)
+	}
+	for _, rec := range statements[0].Records {
+		if rec.Description == "Supermarket" && len(rec.Splits) != 2 {
+			t.Errorf("expected the split lines to survive the round trip, got %+v", rec.Splits)
+		}
+	}
+
+	csvOut, err := RunCLI(t, "export")
+	if err != nil || !strings.HasPrefix(csvOut, "Date,Description,Amount,Category,Currency,Account,Transfer\n") || !strings.Contains(csvOut, "2024-01-15,Landlord - January rent,-1234.56,Housing:Rent,EUR,Checking,\n") {
+		t.Errorf("unexpected CSV export (%v):\n%s", err, csvOut)
+	}
+	if _, err := RunCLI(t, "export", "--format", "xls"); err == nil {
+		t.Errorf("expected an unknown format to be rejected")
+	}
+}
+
+func TestExportQIFTransfers(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	for _, a := range []models.Account{{Name: "Checking", Currency: "EUR"}, {Name: "Visa", Type: "credit", Currency: "EUR"}} {
+		a := a
+		if err := models.CreateAccount(db, &a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := RunCLI(t, "transfer", "--from", "Checking", "--to", "Visa", "--amount", "200", "--date", "2024-03-01"); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "all.qif")
+	if _, err := RunCLI(t, "export", "-f", "qif", "-o", file); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	data, _ := os.ReadFile(file)
+	if !strings.Contains(string(data), "T-200.00\nPTransfer") || !strings.Contains(string(data), "L[Visa]") ||
+		!strings.Contains(string(data), "!Type:CCard") || !strings.Contains(string(data), "L[Checking]") {
+		t.Errorf("expected both transfer legs with their other account:\n%s", data)
+	}
+}
+
+// exportLedger describes every transaction of a database, with its split lines and the
+// account of the other leg if it is a transfer, in an order independent of the IDs.
+func exportLedger(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	txs, err := models.FindTransactions(db, models.TransactionFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherLeg := map[int64]string{}
+	pairs, _ := models.ListTransfers(db)
+	for _, p := range pairs {
+		otherLeg[p.Out.ID], otherLeg[p.In.ID] = p.In.Account, p.Out.Account
+	}
+
+	var ledger []string
+	for _, tr := range txs {
+		line := fmt.Sprintf("%s %s %q %s %s transfer:%s", tr.Account, tr.Date.Format("2006-01-02"), tr.Description, tr.Amount, tr.Category, otherLeg[tr.ID])
+		splits, _ := models.ListSplits(db, tr.ID)
+		for _, s := range splits {
+			line += fmt.Sprintf(" split:%s=%s", s.Category, s.Amount)
+		}
+		ledger = append(ledger, line)
+	}
+	sort.Strings(ledger)
+	return ledger
+}
+
+func TestQIFRoundTripKeepsTransfers(t *testing.T) {
+	accounts := []models.Account{{Name: "Checking"}, {Name: "Savings", Type: "savings"}}
+	source := NewTestDB(t)
+	cli.SetDatabase(source)
+	for _, a := range accounts {
+		a := a
+		if err := models.CreateAccount(source, &a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"transfer", "--from", "Checking", "--to", "Savings", "--amount", "200", "--date", "2024-03-01"},
+		{"add", "--account", "Checking", "--amount", "-100", "--desc", "Payday split", "--category", "Food:Groceries", "--date", "2024-03-05"},
+		{"split", "add", "3", "--amount", "40", "--category", models.TransferCategory},
+		{"add", "--account", "Savings", "--amount", "40", "--desc", "From checking", "--category", models.TransferCategory, "--date", "2024-03-05"},
+		{"transfer", "link", "3", "4"},
+		{"add", "--account", "Checking", "--amount", "-12.50", "--desc", "Bakery", "--category", "Food", "--date", "2024-03-06"},
+	} {
+		if out, err := RunCLI(t, args...); err != nil {
+			t.Fatalf("%v failed: %v\n%s", args, err, out)
+		}
+	}
+	want := exportLedger(t, source)
+
+	file := filepath.Join(t.TempDir(), "ledger.qif")
+	if _, err := RunCLI(t, "export", "-f", "qif", "-o", file); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	data, _ := os.ReadFile(file)
+	if !strings.Contains(string(data), "S[Savings]\n$-40.00\n") {
+		t.Errorf("expected the split transfer as a transfer split line:\n%s", data)
+	}
+
+	// An empty database with the same accounts; each statement goes to its own account
+	target := NewTestDB(t)
+	cli.SetDatabase(target)
+	for _, a := range accounts {
+		a := a
+		if err := models.CreateAccount(target, &a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := RunCLI(t, "import", file); err != nil {
+		t.Fatalf("import failed: %v\n%s", err, out)
+	}
+	got := exportLedger(t, target)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("the ledger changed on the round trip\nwant:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestCSVRoundTripKeepsAccountsAndTransfers(t *testing.T) {
+	accounts := []models.Account{{Name: "Checking"}, {Name: "Savings", Type: "savings"}}
+	source := NewTestDB(t)
+	cli.SetDatabase(source)
+	for _, a := range accounts {
+		a := a
+		if err := models.CreateAccount(source, &a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"transfer", "--from", "Checking", "--to", "Savings", "--amount", "200", "--date", "2024-03-01"},
+		{"add", "--account", "Savings", "--amount", "1.25", "--desc", "Interest", "--category", "Income", "--date", "2024-03-31"},
+		{"add", "--account", "Checking", "--amount", "-12.50", "--desc", "Bakery", "--category", "Food", "--date", "2024-03-06"},
+	} {
+		if out, err := RunCLI(t, args...); err != nil {
+			t.Fatalf("%v failed: %v\n%s", args, err, out)
+		}
+	}
+	want := exportLedger(t, source)
+
+	file := filepath.Join(t.TempDir(), "ledger.csv")
+	if _, err := RunCLI(t, "export", "-o", file); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	data, _ := os.ReadFile(file)
+	if !strings.Contains(string(data), "2024-03-01,Transfer Checking -> Savings,-200.00,Transfer,,Checking,Savings\n") {
+		t.Errorf("expected the account and the other leg's account in the export:\n%s", data)
+	}
+
+	// The generic profile reads the export back into the accounts it names
+	target := NewTestDB(t)
+	cli.SetDatabase(target)
+	for _, a := range accounts {
+		a := a
+		if err := models.CreateAccount(target, &a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := RunCLI(t, "import", file); err != nil || !strings.Contains(out, "4 imported") {
+		t.Fatalf("import failed: %v\n%s", err, out)
+	}
+	got := exportLedger(t, target)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("the ledger changed on the round trip\nwant:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func ymd(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func formatDates(dates []time.Time) string {
+	var s []string
+	for _, d := range dates {
+		s = append(s, d.Format("2006-01-02"))
+	}
+	return strings.Join(s, ",")
+}
+
+func TestRecurringSchedules(t *testing.T) {
+	cases := []struct {
+		name string
+		r    models.Recurring
+		want string
+	}{
+		{"monthly on day 31 clamps to short months",
+			models.Recurring{Frequency: models.Monthly, Interval: 1, Day: 31, Start: ymd(2024, 1, 10)},
+			"2024-01-31,2024-02-29,2024-03-31,2024-04-30"},
+		{"monthly day before the start day begins next month",
+			models.Recurring{Frequency: models.Monthly, Interval: 1, Day: 5, Start: ymd(2024, 1, 10)},
+			"2024-02-05,2024-03-05,2024-04-05"},
+		{"every two weeks",
+			models.Recurring{Frequency: models.Weekly, Interval: 2, Start: ymd(2024, 1, 1)},
+			"2024-01-01,2024-01-15,2024-01-29,2024-02-12,2024-02-26,2024-03-11,2024-03-25,2024-04-08,2024-04-22"},
+		{"every 45 days with an end date",
+			models.Recurring{Frequency: models.Daily, Interval: 45, Start: ymd(2024, 1, 1), End: ymd(2024, 3, 1)},
+			"2024-01-01,2024-02-15"},
+		{"yearly on a leap day",
+			models.Recurring{Frequency: models.Yearly, Interval: 1, Start: ymd(2020, 2, 29)},
+			"2021-02-28,2022-02-28,2023-02-28,2024-02-29"},
+	}
+	for _, c := range cases {
+		from, to := ymd(2021, 1, 1), ymd(2024, 4, 30)
+		if got := formatDates(c.r.Occurrences(from, to)); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+
+	if err := (models.Recurring{Description: "x", Frequency: "hourly", Interval: 1, Start: ymd(2024, 1, 1)}).Validate(); err == nil {
+		t.Errorf("expected an unknown frequency to be rejected")
+	}
+	if err := (models.Recurring{Description: "x", Frequency: models.Weekly, Interval: 1, Day: 3, Start: ymd(2024, 1, 1)}).Validate(); err == nil {
+		t.Errorf("expected a day of the month on a weekly schedule to be rejected")
+	}
+}
+
+func TestRunRecurring(t *testing.T) {
+	db := NewTestDB(t)
+
+	rent := &models.Recurring{Description: "Rent", Amount: -950_00, Category: "Housing", Frequency: models.Monthly, Day: 1, Start: ymd(2024, 1, 1)}
+	if err := models.CreateRecurring(db, rent); err != nil {
+		t.Fatalf("CreateRecurring failed: %v", err)
+	}
+
+	// February's rent was already entered by hand
+	manual := rent.Transaction(ymd(2024, 2, 1))
+	if err := models.CreateTransaction(db, manual); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := models.RunRecurring(db, ymd(2024, 3, 15))
+	if err != nil {
+		t.Fatalf("RunRecurring failed: %v", err)
+	}
+	if len(run.Created) != 2 || run.Skipped != 1 {
+		t.Errorf("expected 2 created and 1 skipped, got %d and %d", len(run.Created), run.Skipped)
+	}
+	if all, _ := models.ListTransactions(db); len(all) != 3 {
+		t.Errorf("expected 3 rent transactions, got %d", len(all))
+	}
+
+	// Running again books nothing new, and deleted occurrences stay deleted
+	if err := models.DeleteTransaction(db, run.Created[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if run, _ = models.RunRecurring(db, ymd(2024, 3, 20)); len(run.Created) != 0 || run.Skipped != 0 {
+		t.Errorf("expected nothing to do on a second run, got %+v", run)
+	}
+
+	upcoming, err := models.UpcomingOccurrences(db, ymd(2024, 3, 1), ymd(2024, 5, 31))
+	if err != nil {
+		t.Fatalf("UpcomingOccurrences failed: %v", err)
+	}
+	if len(upcoming) != 2 || !upcoming[0].Date.Equal(ymd(2024, 4, 1)) {
+		t.Errorf("expected April and May to be upcoming, got %+v", upcoming)
+	}
+}
+
+func TestRecurringCommands(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	start := time.Now().AddDate(0, 0, -20).Format("2006-01-02")
+	out, err := RunCLI(t, "recurring", "add", "--desc", "Gym", "--amount=-30", "--category", "Health",
+		"--frequency", "weekly", "--start", start)
+	if err != nil {
+		t.Fatalf("recurring add failed: %v", err)
+	}
+	if !strings.Contains(out, "weekly") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if _, err := RunCLI(t, "recurring", "add", "--desc", "Bad", "--amount", "1", "--frequency", "hourly"); err == nil {
+		t.Errorf("expected an unknown frequency to fail")
+	}
+
+	out, _ = RunCLI(t, "recurring", "list")
+	if !strings.Contains(out, "Gym") || !strings.Contains(out, "Health") {
+		t.Errorf("expected the template in the list, got:\n%s", out)
+	}
+
+	out, err = RunCLI(t, "recurring", "run")
+	if err != nil {
+		t.Fatalf("recurring run failed: %v", err)
+	}
+	if !strings.Contains(out, "3 recurring transaction(s) added") {
+		t.Errorf("expected three weekly occurrences to be booked, got:\n%s", out)
+	}
+	out, _ = RunCLI(t, "recurring", "run")
+	if !strings.Contains(out, "0 recurring transaction(s) added") {
+		t.Errorf("expected a second run to add nothing, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "recurring", "upcoming", "--days", "14")
+	if strings.Count(out, "Gym") != 2 {
+		t.Errorf("expected two upcoming occurrences, got:\n%s", out)
+	}
+
+	now := time.Now()
+	out, _ = RunCLI(t, "report", "--upcoming", "--year", now.Format("2006"), "--month", now.Format("1"))
+	if !strings.Contains(out, "Upcoming Recurring") {
+		t.Errorf("expected the upcoming section in the report, got:\n%s", out)
+	}
+
+	if _, err := RunCLI(t, "recurring", "remove", "1"); err != nil {
+		t.Fatalf("recurring remove failed: %v", err)
+	}
+	if _, err := RunCLI(t, "recurring", "remove", "1"); err == nil {
+		t.Errorf("expected removing a missing template to fail")
+	}
+}
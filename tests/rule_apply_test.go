@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestRulesRemoveAndTest(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	for _, args := range [][]string{
+		{"rules", "add", "--pattern", "(?i)lidl", "--category", "Food", "--payee", "Lidl"},
+		{"rules", "add", "--pattern", "(?i)lidl", "--sign", "expense", "--tag", "groceries"},
+	} {
+		if _, err := RunCLI(t, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := RunCLI(t, "rules", "test", "LIDL 0042", "--amount", "-12.00")
+	if err != nil {
+		t.Fatalf("rules test failed: %v", err)
+	}
+	for _, want := range []string{"Rule 1 matches", "Rule 2 matches", "Uncategorized -> Food", "+#groceries"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	// Without an amount the expense rule doesn't fire
+	if out, _ := RunCLI(t, "rules", "test", "LIDL 0042"); strings.Contains(out, "Rule 2") {
+		t.Errorf("expected only rule 1 to match, got:\n%s", out)
+	}
+	if out, _ := RunCLI(t, "rules", "test", "Bakery"); !strings.Contains(out, "No rule matches.") {
+		t.Errorf("expected no match, got:\n%s", out)
+	}
+
+	if _, err := RunCLI(t, "rules", "remove", "1"); err != nil {
+		t.Fatalf("rules remove failed: %v", err)
+	}
+	if _, err := RunCLI(t, "rules", "remove", "1"); err == nil {
+		t.Errorf("expected removing a missing rule to fail")
+	}
+	if rules, _ := models.ListRules(db); len(rules) != 1 || rules[0].ID != 2 {
+		t.Errorf("expected only rule 2 to be left, got %+v", rules)
+	}
+}
+
+func TestInvalidStoredPattern(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	// A rule stored before patterns were checked
+	if _, err := db.Exec(`INSERT INTO category_rules (pattern, priority) VALUES ('(unclosed', 1)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "add", "--amount", "-1", "--desc", "Coffee"); err == nil || !strings.Contains(err.Error(), "rule 1 has an invalid pattern") {
+		t.Errorf("expected the invalid rule to be reported, got %v", err)
+	}
+	if _, err := models.MatchCategory([]models.CategoryRule{{ID: 1, Pattern: "(", Category: "X"}}, "x"); err == nil {
+		t.Errorf("expected MatchCategory to report the invalid pattern")
+	}
+
+	// Disabling it is enough to go on
+	if _, err := RunCLI(t, "rules", "disable", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "add", "--amount", "-1", "--desc", "Coffee"); err != nil {
+		t.Errorf("expected a disabled invalid rule to be ignored, got %v", err)
+	}
+}
+
+func TestRulesApply(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	for _, args := range [][]string{
+		{"add", "--amount", "-23.50", "--desc", "CARD PAYMENT LIDL 0042", "--date", "2024-01-15"},
+		{"add", "--amount", "-8.00", "--desc", "LIDL 17", "--date", "2024-03-01", "--category", "Shopping"},
+		{"add", "--amount", "-4.00", "--desc", "Lidl Bakery", "--date", "2024-03-02"},
+		{"add", "--amount", "-3.00", "--desc", "Coffee", "--date", "2024-03-03"},
+		{"rules", "add", "--pattern", "(?i)lidl", "--category", "Food", "--tag", "groceries"},
+	} {
+		if _, err := RunCLI(t, args...); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+	}
+
+	out, err := RunCLI(t, "rules", "apply", "--dry-run")
+	if err != nil {
+		t.Fatalf("rules apply --dry-run failed: %v", err)
+	}
+	if !strings.Contains(out, "3 transaction(s) would change") || !strings.Contains(out, "Shopping -> Food") {
+		t.Errorf("unexpected preview:\n%s", out)
+	}
+	if txs, _ := models.FindTransactions(db, models.TransactionFilter{Query: "Food"}); len(txs) != 0 {
+		t.Errorf("expected the dry run to store nothing, got %+v", txs)
+	}
+
+	out, err = RunCLI(t, "rules", "apply", "--since", "2024-03-01", "--only-uncategorized")
+	if err != nil {
+		t.Fatalf("rules apply failed: %v", err)
+	}
+	if !strings.Contains(out, `#3 2024-03-02 "Lidl Bakery"`) || !strings.Contains(out, "Updated 1 transaction(s).") {
+		t.Errorf("expected only the uncategorized March row to change, got:\n%s", out)
+	}
+	tr, _ := models.GetTransaction(db, 3)
+	if tr.Category != "Food" {
+		t.Errorf("expected Food, got %+v", tr)
+	}
+	if tags, _ := models.GetTags(db, 3); len(tags) != 1 || tags[0] != "groceries" {
+		t.Errorf("expected the rule's tag, got %v", tags)
+	}
+
+	// Everything else, overriding the Shopping category
+	if out, err := RunCLI(t, "rules", "apply"); err != nil || !strings.Contains(out, "Updated 2 transaction(s).") {
+		t.Errorf("expected two more changes, got:\n%s (%v)", out, err)
+	}
+	if tr, _ := models.GetTransaction(db, 2); tr.Category != "Food" {
+		t.Errorf("expected the Shopping row to move to Food, got %+v", tr)
+	}
+	if out, _ := RunCLI(t, "rules", "apply"); !strings.Contains(out, "No transaction would change.") {
+		t.Errorf("expected applying again to change nothing, got:\n%s", out)
+	}
+}
@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestApplyRules(t *testing.T) {
+	date := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	rules := []models.CategoryRule{
+		{ID: 1, Pattern: `(?i)^card payment (\w+)`, Description: "$1", Sign: -1, Tags: []string{"card"}},
+		{ID: 2, Pattern: `(?i)lidl`, Category: "Food:Groceries", Payee: "Lidl"},
+		{ID: 3, Pattern: `(?i)lidl`, Category: "Shopping", Payee: "Someone else", Tags: []string{"card", "weekly"}},
+		{ID: 4, IfCategory: "Food", MinAmount: 10000, Category: "Food:Party"},
+		{ID: 5, Account: "Savings", Category: "Interest", Sign: 1},
+		{ID: 6, To: date.AddDate(0, 0, -1), Category: "Old"},
+		{ID: 7, Pattern: "(?i)lidl", Category: "Never", Disabled: true},
+	}
+	set, err := models.CompileRules(rules)
+	if err != nil {
+		t.Fatalf("CompileRules failed: %v", err)
+	}
+
+	// The first rule to set a field wins; tags add up; conditions see the original
+	tr := &models.Transaction{Date: date, Description: "CARD PAYMENT LIDL 0042", Amount: -2350}
+	result := set.Apply(tr)
+	if tr.Description != "LIDL" || tr.Category != "Food:Groceries" || tr.Payee != "Lidl" {
+		t.Errorf("unexpected result %+v", tr)
+	}
+	if strings.Join(result.Tags, ",") != "card,weekly" || len(result.Rules) != 3 || result.Transfer {
+		t.Errorf("unexpected rule result %+v", result)
+	}
+
+	// A category given by the file is only replaced by a rule made for it
+	tr = &models.Transaction{Date: date, Description: "Lidl", Amount: -12000, Category: "Food"}
+	set.Apply(tr)
+	if tr.Category != "Food:Party" || tr.Payee != "Lidl" {
+		t.Errorf("expected the big food expense to move to Food:Party, got %+v", tr)
+	}
+	tr = &models.Transaction{Date: date, Description: "Lidl", Amount: -500, Category: "Food"}
+	set.Apply(tr)
+	if tr.Category != "Food" {
+		t.Errorf("expected a small food expense to keep its category, got %+v", tr)
+	}
+
+	// Account, sign and date conditions
+	tr = &models.Transaction{Date: date, Description: "Interest", Amount: 150, Account: "savings"}
+	set.Apply(tr)
+	if tr.Category != "Interest" {
+		t.Errorf("expected the account rule to apply, got %+v", tr)
+	}
+	tr = &models.Transaction{Date: date, Description: "Fee", Amount: -150, Account: "Savings"}
+	set.Apply(tr)
+	if tr.Category != "" {
+		t.Errorf("expected no rule to apply to an expense, got %+v", tr)
+	}
+	tr = &models.Transaction{Date: date.AddDate(0, 0, -1), Description: "Fee", Amount: -150}
+	set.Apply(tr)
+	if tr.Category != "Old" {
+		t.Errorf("expected the date rule to apply, got %+v", tr)
+	}
+
+	if got, err := models.MatchCategory(rules, "lidl"); err != nil || got != "Food:Groceries" {
+		t.Errorf("MatchCategory = %q (%v), want Food:Groceries", got, err)
+	}
+}
+
+func TestRulesWorkflow(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	for _, args := range [][]string{
+		{"rules", "add", "--pattern", "(?i)netflix", "--category", "Entertainment"},
+		{"rules", "add", "--pattern", "(?i)spotify", "--category", "Music", "--tag", "#Streaming"},
+		{"rules", "add", "--sign", "income", "--min-amount", "1000", "--payee", "Employer"},
+	} {
+		if out, err := RunCLI(t, args...); err != nil {
+			t.Fatalf("%v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	for _, bad := range [][]string{
+		{"rules", "add", "--pattern", "(unclosed", "--category", "X"},
+		{"rules", "add", "--pattern", "netflix"},
+		{"rules", "add", "--min-amount", "50", "--max-amount", "10", "--category", "X"},
+		{"rules", "add", "--sign", "sideways", "--category", "X"},
+	} {
+		if _, err := RunCLI(t, bad...); err == nil {
+			t.Errorf("expected %v to be rejected", bad)
+		}
+	}
+
+	if _, err := RunCLI(t, "rules", "move", "3", "1"); err != nil {
+		t.Fatalf("rules move failed: %v", err)
+	}
+	if _, err := RunCLI(t, "rules", "disable", "1"); err != nil {
+		t.Fatalf("rules disable failed: %v", err)
+	}
+	if _, err := RunCLI(t, "rules", "edit", "2", "--category", "Entertainment:Music", "--max-amount", "20"); err != nil {
+		t.Fatalf("rules edit failed: %v", err)
+	}
+
+	rules, err := models.ListRules(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []int64
+	for _, r := range rules {
+		order = append(order, r.ID)
+	}
+	if len(order) != 3 || order[0] != 3 || order[1] != 1 || order[2] != 2 {
+		t.Fatalf("expected rules in order 3, 1, 2, got %v", order)
+	}
+	if !rules[1].Disabled {
+		t.Errorf("expected rule 1 to be disabled")
+	}
+	if r := rules[2]; r.Pattern != "(?i)spotify" || r.Category != "Entertainment:Music" || r.MaxAmount != 2000 || len(r.Tags) != 1 || r.Tags[0] != "streaming" {
+		t.Errorf("expected the edit to keep the other fields, got %+v", r)
+	}
+
+	out, err := RunCLI(t, "rules", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "disabled") || !strings.Contains(out, "amount <= 20.00") || !strings.Contains(out, "income") {
+		t.Errorf("unexpected rules list:\n%s", out)
+	}
+
+	// The disabled rule no longer categorizes
+	out, _ = RunCLI(t, "add", "--amount", "-9.99", "--desc", "NETFLIX.COM")
+	if strings.Contains(out, "Auto-categorized") {
+		t.Errorf("expected the disabled rule to be skipped, got:\n%s", out)
+	}
+	out, _ = RunCLI(t, "add", "--amount", "-9.99", "--desc", "Spotify")
+	if !strings.Contains(out, "Auto-categorized as: Entertainment:Music") {
+		t.Errorf("expected the edited rule to apply, got:\n%s", out)
+	}
+	if tags, _ := models.GetTags(db, 2); len(tags) != 1 || tags[0] != "streaming" {
+		t.Errorf("expected the rule's tag, got %v", tags)
+	}
+
+	// A category used by a rule's condition can't be deleted
+	if _, err := RunCLI(t, "rules", "edit", "1", "--if-category", "Bills"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.DeleteCategory(db, "Bills"); err == nil {
+		t.Errorf("expected a category used by a rule condition to stay")
+	}
+}
+
+func TestImportAppliesRules(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+	for _, name := range []string{"Checking", "Savings"} {
+		if err := models.CreateAccount(db, &models.Account{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := RunCLI(t, "add", "--amount", "200", "--desc", "From checking", "--account", "Savings", "--date", "2024-03-02"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"rules", "add", "--pattern", `(?i)^card payment (\w+)`, "--set-description", "$1", "--payee", "Card"},
+		{"rules", "add", "--pattern", "(?i)lidl", "--category", "Food", "--payee", "Lidl", "--tag", "groceries"},
+		{"rules", "add", "--pattern", "(?i)savings", "--account", "Checking", "--transfer"},
+	} {
+		if out, err := RunCLI(t, args...); err != nil {
+			t.Fatalf("%v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	csvFile := filepath.Join(t.TempDir(), "march.csv")
+	os.WriteFile(csvFile, []byte(`Date,Description,Amount,Category
+2024-03-01,CARD PAYMENT LIDL 0042,-23.50,
+2024-03-01,To savings,-200.00,
+`), 0o644)
+	if out, err := RunCLI(t, "import", csvFile, "--account", "Checking"); err != nil {
+		t.Fatalf("import failed: %v\n%s", err, out)
+	}
+
+	txs, err := models.FindTransactions(db, models.TransactionFilter{Query: "lidl"})
+	if err != nil || len(txs) != 1 {
+		t.Fatalf("expected to find the payment by its payee, got %+v (%v)", txs, err)
+	}
+	lidl := txs[0]
+	if lidl.Description != "LIDL" || lidl.Payee != "Card" || lidl.Category != "Food" {
+		t.Errorf("unexpected imported transaction %+v", lidl)
+	}
+	if tags, _ := models.GetTags(db, lidl.ID); len(tags) != 1 || tags[0] != "groceries" {
+		t.Errorf("expected the rule's tag, got %v", tags)
+	}
+
+	txs, _ = models.FindTransactions(db, models.TransactionFilter{Query: "to savings"})
+	if len(txs) != 1 || txs[0].Category != models.TransferCategory || txs[0].TransferID == 0 {
+		t.Fatalf("expected the transfer to be linked, got %+v", txs)
+	}
+	other, _ := models.GetTransaction(db, 1)
+	if other.TransferID != txs[0].TransferID {
+		t.Errorf("expected the savings leg to be linked too, got %+v", other)
+	}
+
+	if out, _ := RunCLI(t, "list", "--account", "Checking"); !strings.Contains(out, "PAYEE") || !strings.Contains(out, "Card") {
+		t.Errorf("expected the payee to be listed, got:\n%s", out)
+	}
+}
+
+func TestRenameAccountKeepsRulesAndBatches(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	if err := models.CreateAccount(db, &models.Account{Name: "Checking", Currency: "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "rules", "add", "--pattern", "(?i)coffee", "--account", "Checking", "--category", "Food"); err != nil {
+		t.Fatal(err)
+	}
+	csvFile := filepath.Join(t.TempDir(), "checking.csv")
+	if err := os.WriteFile(csvFile, []byte("Date,Description,Amount,Category\n2024-03-01,Rent,-800.00,Housing\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "import", csvFile, "--account", "Checking"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RunCLI(t, "account", "rename", "Checking", "Giro"); err != nil {
+		t.Fatalf("account rename failed: %v", err)
+	}
+	if rules, _ := models.ListRules(db); len(rules) != 1 || rules[0].Account != "Giro" {
+		t.Errorf("expected the rule to follow the account, got %+v", rules)
+	}
+	if batches, _ := models.ListImportBatches(db); len(batches) != 1 || batches[0].Account != "Giro" {
+		t.Errorf("expected the import batch to follow the account, got %+v", batches)
+	}
+
+	out, err := RunCLI(t, "add", "--amount", "-3.00", "--desc", "Coffee", "--account", "Giro")
+	if err != nil || !strings.Contains(out, "Auto-categorized as: Food") {
+		t.Errorf("expected the rule to still apply after the rename, got:\n%s (%v)", out, err)
+	}
+}
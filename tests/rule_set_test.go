@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestRuleSetMatchesLikeRegexp(t *testing.T) {
+	patterns := []string{
+		"Netflix", "(?i)netflix", "(?i)lidl|aldi", "Lidl|(?i)aldi", "lidl|lime", "^Lidl", "Lidl$",
+		`(?i)card payment \d+`, "(?i)straße", "(?i)k", "(?i)s", "ÅNGSTRÖM", "(netflix)", "a.c", "",
+	}
+	descriptions := []string{
+		"NETFLIX.COM", "Netflix", "netflix monthly", "LIDL 0042", "Lidl", "ALDI SUED", "lime scooter",
+		"CARD PAYMENT 1234", "STRASSE", "Straße 5", "STRAẞE", "K", "ſ", "ångström", "abc", "",
+	}
+
+	var rules []models.CategoryRule
+	for i, p := range patterns {
+		rules = append(rules, models.CategoryRule{ID: int64(i + 1), Pattern: p, Tags: []string{"t"}})
+	}
+	set, err := models.CompileRules(rules)
+	if err != nil {
+		t.Fatalf("CompileRules failed: %v", err)
+	}
+
+	for _, d := range descriptions {
+		var want []int64
+		for _, r := range rules {
+			if regexp.MustCompile(r.Pattern).MatchString(d) {
+				want = append(want, r.ID)
+			}
+		}
+		got := set.Apply(&models.Transaction{Description: d}).Rules
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%q: rules %v match, regexp says %v", d, got, want)
+		}
+	}
+}
+
+func TestCompileRulesReportsInvalidPatterns(t *testing.T) {
+	_, err := models.CompileRules([]models.CategoryRule{
+		{ID: 1, Pattern: "(", Category: "X"},
+		{ID: 2, Pattern: "ok", Category: "X"},
+		{ID: 3, Pattern: "[", Category: "X"},
+		{ID: 4, Pattern: "*", Category: "X", Disabled: true},
+	})
+	if err == nil || !strings.Contains(err.Error(), "rule 1 ") || !strings.Contains(err.Error(), "rule 3 ") {
+		t.Errorf("expected rules 1 and 3 to be reported, got %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "rule 4 ") {
+		t.Errorf("expected the disabled rule to be ignored, got %v", err)
+	}
+}
+
+// benchmarkRules returns 300 rules, mostly merchant names as users write them and some
+// real regexes, and descriptions of card payments at those merchants.
+func benchmarkRules(n int) ([]models.CategoryRule, []string) {
+	var rules []models.CategoryRule
+	for i := 0; i < 300; i++ {
+		r := models.CategoryRule{ID: int64(i + 1), Category: fmt.Sprintf("Shops:Shop %d", i)}
+		switch {
+		case i%10 == 9:
+			r.Pattern = fmt.Sprintf(`(?i)^card payment \d+ outlet%03d\b`, i)
+		case i%2 == 0:
+			r.Pattern = fmt.Sprintf("(?i)merchant%03d", i)
+		default:
+			r.Pattern = fmt.Sprintf("MERCHANT%03d|Shop%03d", i, i)
+		}
+		rules = append(rules, r)
+	}
+
+	descriptions := make([]string, n)
+	for i := range descriptions {
+		switch k := i * 7 % 400; {
+		case k >= 300:
+			descriptions[i] = fmt.Sprintf("SEPA TRANSFER REF %d", i)
+		case k%10 == 9:
+			descriptions[i] = fmt.Sprintf("CARD PAYMENT %d OUTLET%03d", i, k)
+		default:
+			descriptions[i] = fmt.Sprintf("CARD PAYMENT MERCHANT%03d %d", k, i)
+		}
+	}
+	return rules, descriptions
+}
+
+// BenchmarkMatchRegexpPerTransaction is how rules used to be matched: every pattern
+// compiled again for every transaction.
+func BenchmarkMatchRegexpPerTransaction(b *testing.B) {
+	rules, descriptions := benchmarkRules(20000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, d := range descriptions {
+			for _, r := range rules {
+				if ok, _ := regexp.MatchString(r.Pattern, d); ok {
+					break
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkRuleSet(b *testing.B) {
+	rules, descriptions := benchmarkRules(20000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		set, err := models.CompileRules(rules)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, d := range descriptions {
+			set.Apply(&models.Transaction{Description: d})
+		}
+	}
+}
+
+func BenchmarkImportWithRules(b *testing.B) {
+	rules, descriptions := benchmarkRules(20000)
+	var sb strings.Builder
+	sb.WriteString("Date,Description,Amount,Category\n")
+	for i, d := range descriptions {
+		fmt.Fprintf(&sb, "2024-%02d-%02d,%s,-%d.%02d,\n", i%12+1, i%28+1, d, i%90+1, i%100)
+	}
+	csvFile := filepath.Join(b.TempDir(), "large.csv")
+	if err := os.WriteFile(csvFile, []byte(sb.String()), 0o644); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		db := NewTestDB(b)
+		cli.SetDatabase(db)
+		for i := range rules {
+			if err := models.CreateRule(db, &rules[i]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StartTimer()
+
+		if _, err := RunCLI(b, "import", csvFile, "--fuzzy", "off"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+// addUncategorized stores transactions no rule or history categorizes yet.
+func addUncategorized(t *testing.T, descriptions ...string) {
+	t.Helper()
+	for _, d := range descriptions {
+		if _, err := RunCLI(t, "add", "--amount", "-4.50", "--desc", d, "--confidence", "off"); err != nil {
+			t.Fatalf("add %q failed: %v", d, err)
+		}
+	}
+}
+
+func TestSuggestRules(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	addUncategorized(t, "STARBUCKS 054", "Starbucks 112", "CARD PAYMENT STARBUCKS 7",
+		"REWE SAGT DANKE 1234", "Rewe 55 sagt danke", "Netflix.com", "NETFLIX.COM", "Bakery")
+	if _, err := RunCLI(t, "rules", "add", "--pattern", "(?i)netflix", "--category", "Entertainment"); err != nil {
+		t.Fatal(err)
+	}
+
+	suggestions, err := models.SuggestRules(db, nil, nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 3 {
+		t.Fatalf("expected starbucks, rewe and netflix without rules, got %+v", suggestions)
+	}
+	if s := suggestions[0]; s.Merchant != "starbucks" || s.Pattern != "(?i)starbucks" || s.Count != 3 {
+		t.Errorf("expected starbucks first with 3 rows, got %+v", s)
+	}
+	for _, s := range suggestions {
+		if s.Merchant == "rewe sagt danke" && s.Pattern != "(?i)rewe.*sagt.*danke" {
+			t.Errorf("expected the words in order with anything between, got %q", s.Pattern)
+		}
+	}
+
+	// The netflix rows are caught by the rule already
+	rules, err := models.LoadRules(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suggestions, _ := models.SuggestRules(db, rules, nil, 2); len(suggestions) != 2 {
+		t.Errorf("expected netflix to be left out, got %+v", suggestions)
+	}
+	if suggestions, _ := models.SuggestRules(db, rules, nil, 3); len(suggestions) != 1 {
+		t.Errorf("expected only starbucks with --min-count 3, got %+v", suggestions)
+	}
+}
+
+func TestRulesSuggestCommand(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	for _, args := range [][]string{
+		{"add", "--amount", "-4.50", "--desc", "STARBUCKS 1", "--category", "Food:Coffee"},
+		{"add", "--amount", "-60.00", "--desc", "SHELL 9", "--category", "Transport"},
+	} {
+		if _, err := RunCLI(t, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addUncategorized(t, "STARBUCKS 054", "Starbucks 112", "STARBUCKS 7", "REWE SAGT DANKE 1234", "Rewe sagt danke", "Kiosk 1", "KIOSK 2")
+
+	out, err := RunCLI(t, "rules", "suggest", "--list")
+	if err != nil {
+		t.Fatalf("rules suggest --list failed: %v", err)
+	}
+	for _, want := range []string{"/(?i)starbucks/", "Food:Coffee", "/(?i)rewe sagt danke/", "3 suggestion(s)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	// Take the learned category for the first, type one for the second, skip the third
+	out, err = RunCLIWithInput(t, "\nFood:Groceries\ns\n", "rules", "suggest")
+	if err != nil {
+		t.Fatalf("rules suggest failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "Added 2 rule(s)") {
+		t.Errorf("expected two rules to be added, got:\n%s", out)
+	}
+	rules, _ := models.ListRules(db)
+	if len(rules) != 2 {
+		t.Fatalf("expected two rules, got %+v", rules)
+	}
+	got := map[string]string{}
+	for _, r := range rules {
+		got[r.Pattern] = r.Category
+	}
+	if got["(?i)starbucks"] != "Food:Coffee" || got["(?i)kiosk"] != "Food:Groceries" {
+		t.Errorf("unexpected rules %+v", got)
+	}
+
+	// Only rewe is left; quitting adds nothing
+	out, err = RunCLIWithInput(t, "q\n", "rules", "suggest")
+	if err != nil || !strings.Contains(out, "/(?i)rewe sagt danke/") || !strings.Contains(out, "No rule added.") {
+		t.Errorf("expected rewe to be offered and nothing added, got:\n%s (%v)", out, err)
+	}
+}
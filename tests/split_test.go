@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestSplitTransactions(t *testing.T) {
+	db := NewTestDB(t)
+	date := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	receipt := &models.Transaction{Date: date, Description: "Supermarket", Amount: -100_00, Category: "Groceries"}
+	if err := models.CreateTransaction(db, receipt); err != nil {
+		t.Fatal(err)
+	}
+
+	// Positive amounts take the sign of the expense
+	household := &models.Split{TransactionID: receipt.ID, Amount: 30_00, Category: "household", Memo: "detergent"}
+	if err := models.AddSplit(db, household); err != nil {
+		t.Fatalf("AddSplit failed: %v", err)
+	}
+	if household.Amount != -30_00 || household.Category != "Household" {
+		t.Errorf("expected a normalized -30.00 Household split, got %s %s", household.Amount, household.Category)
+	}
+	if err := models.AddSplit(db, &models.Split{TransactionID: receipt.ID, Amount: -20_00, Category: "Gifts"}); err != nil {
+		t.Fatalf("AddSplit failed: %v", err)
+	}
+	if err := models.AddSplit(db, &models.Split{TransactionID: receipt.ID, Amount: -60_00, Category: "Gifts"}); err == nil {
+		t.Errorf("expected splits exceeding the transaction to be rejected")
+	}
+
+	lines, err := models.GetSplitLines(db, receipt)
+	if err != nil {
+		t.Fatalf("GetSplitLines failed: %v", err)
+	}
+	if len(lines) != 3 || !lines[2].Remainder || lines[2].Amount != -50_00 || lines[2].Category != "Groceries" {
+		t.Errorf("expected two splits and a -50.00 Groceries remainder, got %+v", lines)
+	}
+
+	// Reports and budgets see the split lines, not the whole receipt
+	breakdown, _, expense, err := models.GetMonthlyReport(db, 2024, 5, models.TransactionFilter{}, nil)
+	if err != nil {
+		t.Fatalf("GetMonthlyReport failed: %v", err)
+	}
+	got := map[string]models.Money{}
+	for _, c := range breakdown {
+		got[c.Category] = c.Amount
+	}
+	if got["Groceries"] != -50_00 || got["Household"] != -30_00 || got["Gifts"] != -20_00 || expense != -100_00 {
+		t.Errorf("unexpected breakdown %v (expense %s)", got, expense)
+	}
+
+	spent, err := models.GetSpendingTotal(db, "Household", time.May, 2024, nil)
+	if err != nil || spent != 30_00 {
+		t.Errorf("expected 30.00 spent on Household, got %s (%v)", spent, err)
+	}
+
+	// The amount may change only as far as the split lines still fit
+	for _, amount := range []models.Money{-40_00, 100_00} {
+		changed := *receipt
+		changed.Amount = amount
+		if err := models.UpdateTransaction(db, &changed); err == nil {
+			t.Errorf("expected an amount of %s to be rejected with 50.00 split off", amount)
+		}
+	}
+	if tr, _ := models.GetTransaction(db, receipt.ID); tr.Amount != -100_00 {
+		t.Errorf("expected the rejected updates to store nothing, got %s", tr.Amount)
+	}
+	receipt.Amount = -80_00
+	if err := models.UpdateTransaction(db, receipt); err != nil {
+		t.Fatalf("expected a smaller remainder to be fine, got %v", err)
+	}
+	if lines, _ := models.GetSplitLines(db, receipt); len(lines) != 3 || lines[2].Amount != -30_00 {
+		t.Errorf("expected a -30.00 remainder, got %+v", lines)
+	}
+
+	// Deleting the transaction takes its splits along
+	if err := models.DeleteTransaction(db, receipt.ID); err != nil {
+		t.Fatal(err)
+	}
+	if splits, _ := models.ListSplits(db, receipt.ID); len(splits) != 0 {
+		t.Errorf("expected splits to be deleted with the transaction, got %d", len(splits))
+	}
+}
+
+func TestSplitCommands(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	tr := &models.Transaction{Date: time.Now(), Description: "Supermarket", Amount: -45_00, Category: "Groceries"}
+	if err := models.CreateTransaction(db, tr); err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.FormatInt(tr.ID, 10)
+
+	out, err := RunCLI(t, "split", "add", id, "--amount", "15", "--category", "Household", "--memo", "soap")
+	if err != nil {
+		t.Fatalf("split add failed: %v", err)
+	}
+	if !strings.Contains(out, "-15.00 -> Household") || !strings.Contains(out, "-30.00") {
+		t.Errorf("expected the split and the remainder, got:\n%s", out)
+	}
+
+	splits, _ := models.ListSplits(db, tr.ID)
+	if len(splits) != 1 {
+		t.Fatalf("expected one split, got %d", len(splits))
+	}
+	if _, err := RunCLI(t, "split", "remove", strconv.FormatInt(splits[0].ID, 10)); err != nil {
+		t.Fatalf("split remove failed: %v", err)
+	}
+	out, _ = RunCLI(t, "split", "list", id)
+	if !strings.Contains(out, "-45.00  Groceries  (not split)") {
+		t.Errorf("expected the whole amount back in Groceries, got:\n%s", out)
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestTags(t *testing.T) {
+	db := NewTestDB(t)
+
+	hotel := &models.Transaction{Date: time.Now(), Description: "Hotel", Amount: -400_00, Category: "Travel"}
+	dinner := &models.Transaction{Date: time.Now(), Description: "Dinner", Amount: -60_00, Category: "Food"}
+	for _, tr := range []*models.Transaction{hotel, dinner} {
+		if err := models.CreateTransaction(db, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := models.AddTags(db, hotel.ID, "#Vacation-2026"); err != nil {
+		t.Fatalf("AddTags failed: %v", err)
+	}
+	if err := models.AddTags(db, dinner.ID, "vacation-2026", "family"); err != nil {
+		t.Fatalf("AddTags failed: %v", err)
+	}
+	if err := models.AddTags(db, dinner.ID, "two words"); err == nil {
+		t.Errorf("expected a tag with spaces to be rejected")
+	}
+
+	tags, _ := models.GetTags(db, dinner.ID)
+	if strings.Join(tags, ",") != "family,vacation-2026" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	found, _ := models.FindTransactions(db, models.TransactionFilter{Tag: "vacation-2026"})
+	if len(found) != 2 {
+		t.Errorf("expected 2 transactions tagged vacation-2026, got %d", len(found))
+	}
+
+	// A tag budget covers every tagged transaction, whatever its category
+	now := time.Now()
+	spent, err := models.GetBudgetSpending(db, models.Budget{Tag: "vacation-2026"}, now.Month(), now.Year(), nil)
+	if err != nil || spent != 460_00 {
+		t.Errorf("expected 460.00 spent on #vacation-2026, got %s (%v)", spent, err)
+	}
+
+	// Unused tags disappear
+	if err := models.RemoveTags(db, dinner.ID, "family"); err != nil {
+		t.Fatalf("RemoveTags failed: %v", err)
+	}
+	all, _ := models.ListTags(db)
+	if len(all) != 1 || all[0].Name != "vacation-2026" || all[0].Count != 2 {
+		t.Errorf("unexpected tag list %+v", all)
+	}
+}
+
+func TestTagCommands(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	if _, err := RunCLI(t, "budget", "add", "--tag", "renovation", "--amount", "100"); err != nil {
+		t.Fatalf("budget add --tag failed: %v", err)
+	}
+	if _, err := RunCLI(t, "budget", "add", "--amount", "100"); err == nil {
+		t.Errorf("expected a budget without category or tag to be rejected")
+	}
+
+	out, err := RunCLI(t, "add", "--amount=-95", "--desc", "Paint", "--category", "Home", "--tag", "renovation")
+	if err != nil {
+		t.Fatalf("add --tag failed: %v", err)
+	}
+	if !strings.Contains(out, "WARNING") || !strings.Contains(out, "#renovation") {
+		t.Errorf("expected a warning for the tag budget, got:\n%s", out)
+	}
+
+	tr := &models.Transaction{Date: time.Now(), Description: "Tiles", Amount: -20_00, Category: "Home"}
+	if err := models.CreateTransaction(db, tr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunCLI(t, "tag", "add", strconv.FormatInt(tr.ID, 10), "renovation"); err != nil {
+		t.Fatalf("tag add failed: %v", err)
+	}
+
+	out, _ = RunCLI(t, "budget", "list")
+	if !strings.Contains(out, "#renovation") || !strings.Contains(out, "OVER BUDGET") {
+		t.Errorf("expected the tag budget to be over, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "report", "--tag", "renovation")
+	if !strings.Contains(out, "Tag: #renovation") || !strings.Contains(out, "-115.00") {
+		t.Errorf("expected a report limited to the tag, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "list", "--tag", "#renovation")
+	if !strings.Contains(out, "Paint") || !strings.Contains(out, "Tiles") {
+		t.Errorf("expected both tagged transactions, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "tag", "list")
+	if !strings.Contains(out, "#renovation  2") {
+		t.Errorf("expected the tag with its count, got:\n%s", out)
+	}
+}
@@ -14,7 +14,7 @@ func TestTransactionCRUD(t *testing.T) {
 	tr := &models.Transaction{
 		Date:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 		Description: "Test Income",
-		Amount:      200.50,
+		Amount:      200_50,
 		Category:    "Salary",
 	}
 
@@ -31,18 +31,18 @@ func TestTransactionCRUD(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GetTransaction failed: %v", err)
 	}
-	if loaded.Amount != 200.50 {
-		t.Errorf("expected amount 200.50, got %v", loaded.Amount)
+	if loaded.Amount != 200_50 {
+		t.Errorf("expected amount 200.50, got %s", loaded.Amount)
 	}
 
 	// 3. Update
-	tr.Amount = 220.00
+	tr.Amount = 220_00
 	if err := models.UpdateTransaction(db, tr); err != nil {
 		t.Fatalf("UpdateTransaction failed: %v", err)
 	}
 
 	updated, _ := models.GetTransaction(db, tr.ID)
-	if updated.Amount != 220.00 {
+	if updated.Amount != 220_00 {
 		t.Errorf("expected updated amount 220.00")
 	}
 
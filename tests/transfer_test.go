@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebiGabor/personal-finance-cli/internal/cli"
+	"github.com/SebiGabor/personal-finance-cli/internal/models"
+)
+
+func TestTransfers(t *testing.T) {
+	db := NewTestDB(t)
+	date := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	for _, a := range []*models.Account{{Name: "Checking"}, {Name: "Visa", Type: "credit"}, {Name: "Dollars", Currency: "USD"}} {
+		if err := models.CreateAccount(db, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	salary := &models.Transaction{Date: date, Description: "Salary", Amount: 1000_00, Category: "Income", Account: "Checking"}
+	if err := models.CreateTransaction(db, salary); err != nil {
+		t.Fatal(err)
+	}
+
+	out, in, err := models.CreateTransfer(db, models.Transfer{Date: date, From: "Checking", To: "Visa", Amount: 300_00})
+	if err != nil {
+		t.Fatalf("CreateTransfer failed: %v", err)
+	}
+	if out.Amount != -300_00 || in.Amount != 300_00 || out.TransferID == 0 || out.TransferID != in.TransferID {
+		t.Errorf("expected two linked legs of 300.00, got %+v and %+v", out, in)
+	}
+	if _, _, err := models.CreateTransfer(db, models.Transfer{Date: date, From: "Checking", To: "Checking", Amount: 1_00}); err == nil {
+		t.Errorf("expected a transfer to the same account to be rejected")
+	}
+	if _, _, err := models.CreateTransfer(db, models.Transfer{Date: date, From: "Checking", To: "Dollars", Amount: 1_00}); err == nil {
+		t.Errorf("expected a cross-currency transfer without the arriving amount to be rejected")
+	}
+
+	// Balances move, income and expense don't
+	balances, _ := models.GetAccountBalances(db, false, nil)
+	got := map[string]models.Money{}
+	for _, b := range balances {
+		got[b.Account.Name] = b.Balance
+	}
+	if got["Checking"] != 700_00 || got["Visa"] != 300_00 {
+		t.Errorf("unexpected balances %v", got)
+	}
+
+	_, income, expense, err := models.GetMonthlyReport(db, 2024, 5, models.TransactionFilter{}, nil)
+	if err != nil {
+		t.Fatalf("GetMonthlyReport failed: %v", err)
+	}
+	if income != 1000_00 || expense != 0 {
+		t.Errorf("expected transfers to stay out of the report, got income %s expense %s", income, expense)
+	}
+	spent, err := models.GetSpendingTotal(db, models.TransferCategory, time.May, 2024, nil)
+	if err != nil || spent != 0 {
+		t.Errorf("expected nothing spent on transfers, got %s (%v)", spent, err)
+	}
+
+	// Unlinking turns both legs into ordinary transactions again
+	if err := models.UnlinkTransfer(db, in.ID); err != nil {
+		t.Fatalf("UnlinkTransfer failed: %v", err)
+	}
+	if legs, _ := models.ListTransfers(db); len(legs) != 0 {
+		t.Errorf("expected no transfers after unlinking, got %d", len(legs))
+	}
+	breakdown, _, _, _ := models.GetMonthlyReport(db, 2024, 5, models.TransactionFilter{}, nil)
+	if len(breakdown) != 2 {
+		t.Errorf("expected the unlinked legs back in the report, got %+v", breakdown)
+	}
+
+	// Detection pairs the closest opposite amounts and ignores the same account
+	payment := &models.Transaction{Date: date.AddDate(0, 0, 2), Description: "Card payment", Amount: -50_00, Account: "Checking"}
+	received := &models.Transaction{Date: date.AddDate(0, 0, 3), Description: "Payment thank you", Amount: 50_00, Account: "Visa"}
+	tooLate := &models.Transaction{Date: date.AddDate(0, 0, 20), Description: "Refund", Amount: 50_00, Account: "Visa"}
+	for _, tr := range []*models.Transaction{payment, received, tooLate} {
+		if err := models.CreateTransaction(db, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pairs, err := models.DetectTransfers(db, models.DefaultTransferWindow, []int64{payment.ID})
+	if err != nil {
+		t.Fatalf("DetectTransfers failed: %v", err)
+	}
+	if len(pairs) != 1 || pairs[0].Out.ID != payment.ID || pairs[0].In.ID != received.ID {
+		t.Fatalf("expected the card payment to pair with its receipt, got %+v", pairs)
+	}
+
+	if _, err := models.LinkTransfer(db, received.ID, payment.ID); err != nil {
+		t.Fatalf("LinkTransfer failed: %v", err)
+	}
+	if _, err := models.LinkTransfer(db, payment.ID, tooLate.ID); err == nil {
+		t.Errorf("expected linking an already linked transaction to fail")
+	}
+
+	// Deleting one leg releases the other
+	if err := models.DeleteTransaction(db, payment.ID); err != nil {
+		t.Fatal(err)
+	}
+	loaded, _ := models.GetTransaction(db, received.ID)
+	if loaded.TransferID != 0 {
+		t.Errorf("expected the remaining leg to be unlinked, got transfer %d", loaded.TransferID)
+	}
+}
+
+func TestTransferCommands(t *testing.T) {
+	db := NewTestDB(t)
+	cli.SetDatabase(db)
+
+	for _, args := range [][]string{
+		{"account", "create", "Checking"},
+		{"account", "create", "Savings", "--type", "savings"},
+	} {
+		if _, err := RunCLI(t, args...); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+	}
+
+	out, err := RunCLI(t, "transfer", "--from", "Checking", "--to", "Savings", "--amount", "250", "--date", "2024-05-02")
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if !strings.Contains(out, "Transferred 250.00 from Checking to Savings") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "transfer", "list")
+	if !strings.Contains(out, "Checking") || !strings.Contains(out, "Savings") {
+		t.Errorf("expected the transfer in the list, got:\n%s", out)
+	}
+
+	// An imported statement that mirrors an existing transaction gets a hint
+	if _, err := RunCLI(t, "add", "--amount=-80", "--desc", "To savings", "--account", "Checking", "--date", "2024-05-10"); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "savings.csv")
+	if err := os.WriteFile(file, []byte("Date,Description,Amount\n2024-05-11,From checking,80.00\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = RunCLI(t, "import", file, "--account", "Savings")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "1 possible transfer(s) found") {
+		t.Errorf("expected a transfer hint after import, got:\n%s", out)
+	}
+
+	out, err = RunCLI(t, "transfer", "detect", "--link")
+	if err != nil {
+		t.Fatalf("transfer detect failed: %v", err)
+	}
+	if !strings.Contains(out, "Linked 1 transfer(s)") {
+		t.Errorf("expected one transfer to be linked, got:\n%s", out)
+	}
+
+	out, _ = RunCLI(t, "report", "--year", "2024", "--month", "5")
+	if !strings.Contains(out, "0.00") || strings.Contains(out, "Transfer") {
+		t.Errorf("expected transfers to stay out of the report, got:\n%s", out)
+	}
+
+	pairs, _ := models.ListTransfers(db)
+	if len(pairs) != 2 {
+		t.Fatalf("expected 2 transfers, got %d", len(pairs))
+	}
+	if _, err := RunCLI(t, "transfer", "unlink", strconv.FormatInt(pairs[0].In.ID, 10)); err != nil {
+		t.Fatalf("transfer unlink failed: %v", err)
+	}
+	if _, err := RunCLI(t, "transfer", "link", strconv.FormatInt(pairs[0].Out.ID, 10), strconv.FormatInt(pairs[0].In.ID, 10)); err != nil {
+		t.Fatalf("transfer link failed: %v", err)
+	}
+}